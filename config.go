@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/mark3labs/mcp-filesystem-server/filesystemserver"
+	"github.com/mark3labs/mcp-filesystem-server/filesystemserver/handler"
+)
+
+// settings holds everything that can be set in the config file or on the
+// command line. Flags override the config file.
+type settings struct {
+	AllowedDirectories []string       `json:"allowed_directories"`
+	ReadOnly           bool           `json:"read_only"`
+	LogLevel           string         `json:"log_level"`
+	Limits             handler.Limits `json:"limits"`
+
+	TrashDir       string   `json:"trash_dir"`
+	TrashRetention duration `json:"trash_retention"`
+	JournalDir     string   `json:"journal_dir"`
+	JournalEntries int      `json:"journal_entries"`
+	CheckpointDir  string   `json:"checkpoint_dir"`
+	OverlayDir     string   `json:"overlay_dir"`
+	ClientRoots    string   `json:"client_roots"`
+	DirectoryAdmin bool     `json:"directory_admin"`
+	Confirm        string   `json:"confirm"`
+
+	Transport       string   `json:"transport"`
+	Listen          string   `json:"listen"`
+	BasePath        string   `json:"base_path"`
+	SocketMode      fileMode `json:"socket_mode"`
+	SocketOwner     string   `json:"socket_owner"`
+	ShutdownTimeout duration `json:"shutdown_timeout"`
+
+	AuthTokens  []string `json:"auth_tokens"`
+	AuthFile    string   `json:"auth_file"`
+	TLSCert     string   `json:"tls_cert"`
+	TLSKey      string   `json:"tls_key"`
+	TLSClientCA string   `json:"tls_client_ca"`
+
+	configFile string
+	version    bool
+}
+
+func defaultSettings() *settings {
+	return &settings{
+		LogLevel:        "info",
+		ClientRoots:     filesystemserver.ClientRootsOff,
+		Confirm:         filesystemserver.ConfirmOff,
+		Limits:          handler.DefaultLimits(),
+		TrashRetention:  duration(handler.DEFAULT_TRASH_RETENTION),
+		JournalEntries:  handler.DEFAULT_JOURNAL_ENTRIES,
+		Transport:       filesystemserver.TransportStdio,
+		Listen:          filesystemserver.DefaultListenAddr,
+		SocketMode:      fileMode(filesystemserver.DefaultSocketMode),
+		ShutdownTimeout: duration(filesystemserver.DefaultShutdownTimeout),
+	}
+}
+
+// parseSettings reads the config file named by --config, if any, and then
+// applies the flags in args on top of it. It returns the positional arguments.
+func parseSettings(command string, args []string, output io.Writer) (*settings, []string, error) {
+	s := defaultSettings()
+	flags := s.flagSet(command, output)
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+	if s.configFile == "" {
+		return s, flags.Args(), nil
+	}
+
+	// Parse again on top of the config file so that flags take precedence
+	path := s.configFile
+	s = defaultSettings()
+	if err := s.load(path); err != nil {
+		return nil, nil, err
+	}
+	flags = s.flagSet(command, output)
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+	return s, flags.Args(), nil
+}
+
+func (s *settings) flagSet(command string, output io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.SetOutput(output)
+	flags.Usage = func() {
+		fmt.Fprintf(output, "Usage: %s [command] [flags] [allowed-directory...]\n\n", os.Args[0])
+		fmt.Fprintf(output, "Commands:\n")
+		fmt.Fprintf(output, "  serve         Serve the allowed directories (default)\n")
+		fmt.Fprintf(output, "  check-config  Validate the configuration and exit\n")
+		fmt.Fprintf(output, "  list-tools    List the tools the configuration registers\n\n")
+		fmt.Fprintf(output, "Flags:\n")
+		flags.PrintDefaults()
+	}
+
+	flags.StringVar(&s.configFile, "config", "", "Read settings from this JSON file; flags override it")
+	flags.BoolVar(&s.version, "version", false, "Print the version and exit")
+	flags.BoolVar(&s.ReadOnly, "read-only", s.ReadOnly, "Only register tools that do not change files")
+	flags.StringVar(&s.LogLevel, "log-level", s.LogLevel, "Log level: debug, info, warn or error")
+	flags.Int64Var(&s.Limits.MaxInlineSize, "max-inline-size", s.Limits.MaxInlineSize,
+		"Largest file in bytes whose content is returned inline")
+	flags.Int64Var(&s.Limits.MaxBase64Size, "max-base64-size", s.Limits.MaxBase64Size,
+		"Largest binary file in bytes returned base64 encoded")
+	flags.IntVar(&s.Limits.MaxSearchResults, "max-search-results", s.Limits.MaxSearchResults,
+		"Default number of matches returned by search_within_files")
+	flags.Int64Var(&s.Limits.MaxSearchableSize, "max-searchable-size", s.Limits.MaxSearchableSize,
+		"Largest file in bytes searched by search_within_files")
+	flags.IntVar(&s.Limits.MaxBatchOperations, "max-batch-operations", s.Limits.MaxBatchOperations,
+		"Largest number of operations in a batch")
+	flags.Int64Var(&s.Limits.MaxExtractSize, "max-extract-size", s.Limits.MaxExtractSize,
+		"Largest total size in bytes extract_archive unpacks")
+	flags.IntVar(&s.Limits.MaxExtractEntries, "max-extract-entries", s.Limits.MaxExtractEntries,
+		"Largest number of archive entries extract_archive unpacks")
+
+	flags.StringVar(&s.TrashDir, "trash-dir", s.TrashDir,
+		"Move deleted items into this directory instead of removing them")
+	flags.Var(&s.TrashRetention, "trash-retention",
+		"How long to keep items in the trash (0 keeps them until emptied)")
+	flags.StringVar(&s.JournalDir, "journal-dir", s.JournalDir,
+		"Record an undo journal of all changes in this directory")
+	flags.IntVar(&s.JournalEntries, "journal-entries", s.JournalEntries,
+		"Number of operations to keep in the undo journal (0 keeps all)")
+	flags.StringVar(&s.CheckpointDir, "checkpoint-dir", s.CheckpointDir,
+		"Store workspace checkpoints in this directory")
+	flags.StringVar(&s.OverlayDir, "overlay-dir", s.OverlayDir,
+		"Keep all changes in this directory until they are committed or discarded with the overlay tools")
+	flags.StringVar(&s.ClientRoots, "client-roots", s.ClientRoots,
+		"Use the roots of the client: off, intersect (with the allowed directories) or replace")
+	flags.StringVar(&s.Confirm, "confirm", s.Confirm,
+		"Ask the user before dangerous changes: off, destructive (calls that would overwrite or remove data) or all")
+	flags.BoolVar(&s.DirectoryAdmin, "directory-admin", s.DirectoryAdmin,
+		"Register tools that let clients add and remove allowed directories")
+
+	flags.StringVar(&s.Transport, "transport", s.Transport,
+		"Transport to serve on: stdio, sse or http (streamable HTTP)")
+	flags.StringVar(&s.Listen, "listen", s.Listen,
+		"Address to listen on for the sse and http transports, or unix:<path> for a Unix domain socket")
+	flags.StringVar(&s.BasePath, "base-path", s.BasePath,
+		"URL path to serve the sse and http transports under (http default: /mcp)")
+	flags.Var(&s.SocketMode, "socket-mode", "File mode of the Unix domain socket, in octal")
+	flags.StringVar(&s.SocketOwner, "socket-owner", s.SocketOwner,
+		"Owner of the Unix domain socket as user[:group]")
+	flags.Var(&s.ShutdownTimeout, "shutdown-timeout", "How long to wait for open sessions when shutting down")
+
+	flags.Func("auth-token", "Require this bearer token on the sse and http transports (repeatable)",
+		func(token string) error {
+			s.AuthTokens = append(s.AuthTokens, token)
+			return nil
+		})
+	flags.StringVar(&s.AuthFile, "auth-file", s.AuthFile,
+		"JSON file of principals with tokens, client certificate names, roots and access levels")
+	flags.StringVar(&s.TLSCert, "tls-cert", s.TLSCert,
+		"Serve the sse and http transports over HTTPS with this certificate")
+	flags.StringVar(&s.TLSKey, "tls-key", s.TLSKey, "Private key for -tls-cert")
+	flags.StringVar(&s.TLSClientCA, "tls-client-ca", s.TLSClientCA,
+		"Authenticate clients with certificates signed by the CAs in this file (mTLS)")
+	return flags
+}
+
+// load reads a JSON config file. Relative paths in it are resolved against
+// the directory of the file.
+func (s *settings) load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(s); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	base := filepath.Dir(path)
+	resolve := func(p *string) {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(base, *p)
+		}
+	}
+	for i := range s.AllowedDirectories {
+		resolve(&s.AllowedDirectories[i])
+	}
+	for _, p := range []*string{&s.TrashDir, &s.JournalDir, &s.CheckpointDir, &s.OverlayDir, &s.AuthFile, &s.TLSCert, &s.TLSKey, &s.TLSClientCA} {
+		resolve(p)
+	}
+	return nil
+}
+
+// config returns the server configuration
+func (s *settings) config() filesystemserver.Config {
+	return filesystemserver.Config{
+		TrashDir:       s.TrashDir,
+		TrashRetention: s.TrashRetention.duration(),
+		JournalDir:     s.JournalDir,
+		JournalEntries: s.JournalEntries,
+		CheckpointDir:  s.CheckpointDir,
+		OverlayDir:     s.OverlayDir,
+		ClientRoots:    s.ClientRoots,
+		DirectoryAdmin: s.DirectoryAdmin,
+		Confirm:        s.Confirm,
+		ReadOnly:       s.ReadOnly,
+		Limits:         s.Limits,
+	}
+}
+
+// duration is a time.Duration written as a string such as "90s" or "168h"
+type duration time.Duration
+
+func (d duration) duration() time.Duration {
+	return time.Duration(d)
+}
+
+func (d *duration) String() string {
+	return time.Duration(*d).String()
+}
+
+func (d *duration) Set(value string) error {
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = duration(parsed)
+	return nil
+}
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("duration must be a string such as \"90s\": %w", err)
+	}
+	return d.Set(value)
+}
+
+// fileMode is an os.FileMode written in octal such as "0660"
+type fileMode os.FileMode
+
+func (m *fileMode) String() string {
+	return fmt.Sprintf("%04o", uint32(*m))
+}
+
+func (m *fileMode) Set(value string) error {
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode > 0777 {
+		return fmt.Errorf("invalid mode %q", value)
+	}
+	*m = fileMode(mode)
+	return nil
+}
+
+func (m *fileMode) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("mode must be an octal string such as \"0660\": %w", err)
+	}
+	return m.Set(value)
+}
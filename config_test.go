@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSettings(t *testing.T) {
+	configDir := t.TempDir()
+	configFile := filepath.Join(configDir, "config.json")
+	require.NoError(t, os.WriteFile(configFile, []byte(`{
+		"allowed_directories": ["data"],
+		"read_only": true,
+		"transport": "sse",
+		"trash_retention": "1h",
+		"limits": {"max_inline_size": 1024}
+	}`), 0644))
+
+	t.Run("defaults", func(t *testing.T) {
+		s, dirs, err := parseSettings("serve", []string{"/srv"}, io.Discard)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"/srv"}, dirs)
+		assert.False(t, s.ReadOnly)
+		assert.Equal(t, int64(5*1024*1024), s.Limits.MaxInlineSize)
+		assert.Equal(t, 1000, s.Limits.MaxSearchResults)
+	})
+
+	t.Run("config file", func(t *testing.T) {
+		s, _, err := parseSettings("serve", []string{"--config", configFile}, io.Discard)
+		require.NoError(t, err)
+		assert.Equal(t, []string{filepath.Join(configDir, "data")}, s.AllowedDirectories)
+		assert.True(t, s.ReadOnly)
+		assert.Equal(t, "sse", s.Transport)
+		assert.Equal(t, time.Hour, s.TrashRetention.duration())
+		assert.Equal(t, int64(1024), s.Limits.MaxInlineSize)
+		// Limits missing from the file keep their defaults
+		assert.Equal(t, 1000, s.Limits.MaxSearchResults)
+	})
+
+	t.Run("flags override the config file", func(t *testing.T) {
+		s, _, err := parseSettings("serve", []string{
+			"--transport", "http", "--max-inline-size", "2048", "--config", configFile,
+		}, io.Discard)
+		require.NoError(t, err)
+		assert.Equal(t, "http", s.Transport)
+		assert.Equal(t, int64(2048), s.Limits.MaxInlineSize)
+		assert.True(t, s.ReadOnly)
+	})
+
+	t.Run("unknown settings are rejected", func(t *testing.T) {
+		badFile := filepath.Join(configDir, "bad.json")
+		require.NoError(t, os.WriteFile(badFile, []byte(`{"read_onyl": true}`), 0644))
+		_, _, err := parseSettings("serve", []string{"--config", badFile}, io.Discard)
+		assert.Error(t, err)
+	})
+}
+
+func TestRunCommands(t *testing.T) {
+	dir := t.TempDir()
+
+	var stdout bytes.Buffer
+	require.NoError(t, run([]string{"list-tools", "--read-only", dir}, &stdout, io.Discard))
+	assert.Contains(t, stdout.String(), "read_file")
+	assert.NotContains(t, stdout.String(), "write_file")
+
+	stdout.Reset()
+	require.NoError(t, run([]string{"check-config", dir}, &stdout, io.Discard))
+	assert.Contains(t, stdout.String(), "Configuration OK")
+
+	err := run([]string{"check-config", filepath.Join(dir, "missing")}, &stdout, io.Discard)
+	assert.Error(t, err)
+
+	err = run([]string{"check-config", "--log-level", "loud", dir}, &stdout, io.Discard)
+	assert.Error(t, err)
+
+	stdout.Reset()
+	require.NoError(t, run([]string{"check-config", "--client-roots", "replace"}, &stdout, io.Discard))
+	err = run([]string{"check-config", "--client-roots", "replace", "--transport", "http"}, &stdout, io.Discard)
+	assert.ErrorContains(t, err, "only supported on the stdio transport")
+}
@@ -0,0 +1,299 @@
+package filesystemserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Access levels that can be granted to a principal
+const (
+	// AccessRead only registers the tools that do not change files
+	AccessRead = "read"
+	// AccessWrite registers all tools
+	AccessWrite = "write"
+)
+
+var principalNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Principal is a client identity accepted by the HTTP transports. A client
+// authenticates as the principal by sending Token as a bearer token or by
+// presenting a verified TLS client certificate whose common name is ClientCN.
+type Principal struct {
+	Name     string `json:"name"`
+	Token    string `json:"token,omitempty"`
+	ClientCN string `json:"client_cn,omitempty"`
+	// Roots limits the principal to these directories, which must lie within
+	// the server's allowed directories. Empty allows all of them.
+	Roots []string `json:"roots,omitempty"`
+	// Access is AccessRead or AccessWrite (the default).
+	Access string `json:"access,omitempty"`
+}
+
+// LoadPrincipals reads principals from a JSON file of the form
+// {"principals": [{"name": "ci", "token": "...", "roots": [...], "access": "read"}]}
+func LoadPrincipals(path string) ([]Principal, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read auth file: %w", err)
+	}
+
+	var file struct {
+		Principals []Principal `json:"principals"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse auth file %s: %w", path, err)
+	}
+	return file.Principals, nil
+}
+
+// LoadTLSConfig loads a server certificate and key. When clientCAFile is set,
+// client certificates signed by its CAs are verified and accepted for
+// authentication.
+func LoadTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if clientCAFile != "" {
+		pem, err := os.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in client CA file %s", clientCAFile)
+		}
+		cfg.ClientCAs = pool
+	}
+	return cfg, nil
+}
+
+// Auth authenticates requests to the HTTP transports and serves each
+// principal from a server limited to its roots and access level.
+type Auth struct {
+	principals []*authPrincipal
+}
+
+type authPrincipal struct {
+	Principal
+	// server is nil for principals with full access, who share the default server
+	server *server.MCPServer
+}
+
+// NewAuth prepares principals for serving. Principals with restricted roots
+// or read access get their own server built with opts, with separate trash,
+// journal, checkpoint and overlay directories so that they cannot see or undo
+// the changes of others.
+func NewAuth(allowedDirs []string, principals []Principal, opts ...Option) (*Auth, error) {
+	if len(principals) == 0 {
+		return nil, errors.New("no principals configured")
+	}
+
+	names := make(map[string]bool)
+	tokens := make(map[string]bool)
+	a := &Auth{}
+	for _, p := range principals {
+		if !principalNamePattern.MatchString(p.Name) {
+			return nil, fmt.Errorf("invalid principal name %q (use letters, digits, '.', '_' and '-')", p.Name)
+		}
+		if names[p.Name] {
+			return nil, fmt.Errorf("duplicate principal name %q", p.Name)
+		}
+		names[p.Name] = true
+
+		if p.Token == "" && p.ClientCN == "" {
+			return nil, fmt.Errorf("principal %s needs a token or a client_cn", p.Name)
+		}
+		if p.Token != "" {
+			if tokens[p.Token] {
+				return nil, fmt.Errorf("principal %s reuses the token of another principal", p.Name)
+			}
+			tokens[p.Token] = true
+		}
+
+		switch p.Access {
+		case "":
+			p.Access = AccessWrite
+		case AccessRead, AccessWrite:
+		default:
+			return nil, fmt.Errorf("principal %s: unknown access level %q (expected %s or %s)",
+				p.Name, p.Access, AccessRead, AccessWrite)
+		}
+
+		ap := &authPrincipal{Principal: p}
+		if len(p.Roots) > 0 || p.Access == AccessRead {
+			s, err := newPrincipalServer(allowedDirs, newOptions(opts), p)
+			if err != nil {
+				return nil, fmt.Errorf("principal %s: %w", p.Name, err)
+			}
+			ap.server = s
+		}
+		a.principals = append(a.principals, ap)
+	}
+	return a, nil
+}
+
+// newPrincipalServer builds a server restricted to the roots and access level of p
+func newPrincipalServer(allowedDirs []string, o *options, p Principal) (*server.MCPServer, error) {
+	roots := allowedDirs
+	if len(p.Roots) > 0 {
+		for _, root := range p.Roots {
+			if !isWithinDirs(root, allowedDirs) {
+				return nil, fmt.Errorf("root %s is outside the allowed directories", root)
+			}
+		}
+		roots = p.Roots
+	}
+
+	if p.Access == AccessRead {
+		o.config.ReadOnly = true
+	}
+	// The client of a principal must not widen its roots
+	if o.config.ClientRoots == ClientRootsReplace {
+		o.config.ClientRoots = ClientRootsIntersect
+	}
+	o.config.DirectoryAdmin = false
+	for _, dir := range []*string{&o.config.TrashDir, &o.config.JournalDir, &o.config.CheckpointDir, &o.config.OverlayDir} {
+		if *dir != "" {
+			*dir = filepath.Join(*dir, "principals", p.Name)
+		}
+	}
+	return newServer(roots, o)
+}
+
+// isWithinDirs reports whether path resolves to one of dirs or a path below them
+func isWithinDirs(path string, dirs []string) bool {
+	resolve := func(p string) (string, error) {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return "", err
+		}
+		resolved, err := filepath.EvalSymlinks(abs)
+		if os.IsNotExist(err) {
+			// The directories may only exist in another backend
+			return abs, nil
+		}
+		return resolved, err
+	}
+
+	resolved, err := resolve(path)
+	if err != nil {
+		return false
+	}
+	for _, dir := range dirs {
+		resolvedDir, err := resolve(dir)
+		if err != nil {
+			continue
+		}
+		if resolved == resolvedDir || strings.HasPrefix(resolved, resolvedDir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reasons authenticate refuses a request
+var (
+	errUnauthenticated  = errors.New("no valid token or client certificate")
+	errUnknownPrincipal = errors.New("client certificate matches no principal")
+)
+
+// authenticate returns the principal that sent r. A verified client
+// certificate whose common name matches no principal is refused with
+// errUnknownPrincipal.
+func (a *Auth) authenticate(r *http.Request) (*authPrincipal, error) {
+	if token, ok := bearerToken(r); ok {
+		for _, p := range a.principals {
+			if p.Token != "" && subtle.ConstantTimeCompare([]byte(p.Token), []byte(token)) == 1 {
+				return p, nil
+			}
+		}
+		return nil, errUnauthenticated
+	}
+
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		cn := r.TLS.VerifiedChains[0][0].Subject.CommonName
+		for _, p := range a.principals {
+			if p.ClientCN != "" && p.ClientCN == cn {
+				return p, nil
+			}
+		}
+		return nil, errUnknownPrincipal
+	}
+	return nil, errUnauthenticated
+}
+
+// bearerToken returns the token from the request's Authorization header
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// wrap returns a handler that authenticates each request and passes it to
+// the transport serving the principal's server
+func (a *Auth) wrap(
+	handler http.Handler,
+	shutdown func(context.Context) error,
+	opts ServeOptions,
+) (http.Handler, func(context.Context) error, error) {
+	handlers := make(map[*authPrincipal]http.Handler)
+	shutdowns := []func(context.Context) error{shutdown}
+	for _, p := range a.principals {
+		if p.server == nil {
+			continue
+		}
+		h, sd, err := newTransportHandler(p.server, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		handlers[p] = h
+		shutdowns = append(shutdowns, sd)
+	}
+
+	authenticated := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, err := a.authenticate(r)
+		if err == errUnknownPrincipal {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		} else if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="mcp-filesystem-server"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if h, ok := handlers[p]; ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+
+	shutdownAll := func(ctx context.Context) error {
+		var errs []error
+		for _, sd := range shutdowns {
+			errs = append(errs, sd(ctx))
+		}
+		return errors.Join(errs...)
+	}
+	return authenticated, shutdownAll, nil
+}
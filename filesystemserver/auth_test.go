@@ -0,0 +1,252 @@
+package filesystemserver_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-filesystem-server/filesystemserver"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveAuth starts the streamable HTTP transport with opts and returns its base URL
+func serveAuth(t *testing.T, dirs []string, opts filesystemserver.ServeOptions) string {
+	t.Helper()
+	fss, err := filesystemserver.NewFilesystemServer(dirs)
+	require.NoError(t, err)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	opts.Transport = filesystemserver.TransportHTTP
+	opts.ShutdownTimeout = filesystemserver.DefaultShutdownTimeout
+	go func() {
+		done <- filesystemserver.ServeListener(ctx, fss, ln, opts)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, <-done)
+	})
+	// Cleanups run in reverse, so connections are closed before the shutdown
+	t.Cleanup(http.DefaultClient.CloseIdleConnections)
+
+	scheme := "http"
+	if opts.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + ln.Addr().String() + filesystemserver.DefaultHTTPBasePath
+}
+
+// connect initializes an MCP client against url
+func connect(t *testing.T, url string, options ...transport.StreamableHTTPCOption) *client.Client {
+	t.Helper()
+	mcpClient, err := client.NewStreamableHttpClient(url, options...)
+	require.NoError(t, err)
+	t.Cleanup(func() { mcpClient.Close() })
+
+	initRequest := mcp.InitializeRequest{}
+	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initRequest.Params.ClientInfo = mcp.Implementation{
+		Name:    "test-client",
+		Version: "1.0.0",
+	}
+	_, err = mcpClient.Initialize(context.Background(), initRequest)
+	require.NoError(t, err)
+	return mcpClient
+}
+
+// hasTool reports whether the server lists the tool
+func hasTool(t *testing.T, mcpClient client.MCPClient, toolName string) bool {
+	t.Helper()
+	result, err := mcpClient.ListTools(context.Background(), mcp.ListToolsRequest{})
+	require.NoError(t, err)
+	for _, tool := range result.Tools {
+		if tool.Name == toolName {
+			return true
+		}
+	}
+	return false
+}
+
+func TestBearerTokenAuth(t *testing.T) {
+	allowed := t.TempDir()
+	shared := filepath.Join(allowed, "shared")
+	require.NoError(t, os.Mkdir(shared, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(allowed, "secret.txt"), []byte("secret"), 0644))
+
+	auth, err := filesystemserver.NewAuth([]string{allowed}, []filesystemserver.Principal{
+		{Name: "admin", Token: "admin-token"},
+		{Name: "reader", Token: "reader-token", Roots: []string{shared}, Access: filesystemserver.AccessRead},
+	})
+	require.NoError(t, err)
+
+	url := serveAuth(t, []string{allowed}, filesystemserver.ServeOptions{Auth: auth})
+
+	t.Run("requests without a valid token are rejected", func(t *testing.T) {
+		for _, header := range []string{"", "Bearer wrong-token", "Basic YWRtaW46YWRtaW4="} {
+			req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(`{}`))
+			require.NoError(t, err)
+			if header != "" {
+				req.Header.Set("Authorization", header)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, header)
+			assert.Contains(t, resp.Header.Get("WWW-Authenticate"), "Bearer")
+		}
+	})
+
+	t.Run("full access token", func(t *testing.T) {
+		mcpClient := connect(t, url, transport.WithHTTPHeaders(map[string]string{
+			"Authorization": "Bearer admin-token",
+		}))
+		assert.NotNil(t, getTool(t, mcpClient, "write_file"))
+	})
+
+	t.Run("restricted token", func(t *testing.T) {
+		mcpClient := connect(t, url, transport.WithHTTPHeaders(map[string]string{
+			"Authorization": "Bearer reader-token",
+		}))
+		assert.NotNil(t, getTool(t, mcpClient, "read_file"))
+		assert.False(t, hasTool(t, mcpClient, "write_file"))
+
+		request := mcp.CallToolRequest{}
+		request.Params.Name = "read_file"
+		request.Params.Arguments = map[string]any{"path": filepath.Join(allowed, "secret.txt")}
+		result, err := mcpClient.CallTool(context.Background(), request)
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+	})
+}
+
+func TestNewAuthValidation(t *testing.T) {
+	allowed := t.TempDir()
+
+	tests := []struct {
+		name      string
+		principal filesystemserver.Principal
+	}{
+		{"missing credentials", filesystemserver.Principal{Name: "ci"}},
+		{"invalid name", filesystemserver.Principal{Name: "../ci", Token: "t"}},
+		{"unknown access", filesystemserver.Principal{Name: "ci", Token: "t", Access: "admin"}},
+		{"root outside allowed directories", filesystemserver.Principal{Name: "ci", Token: "t", Roots: []string{t.TempDir()}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := filesystemserver.NewAuth([]string{allowed}, []filesystemserver.Principal{tt.principal})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestClientCertificateAuth(t *testing.T) {
+	allowed := t.TempDir()
+	certDir := t.TempDir()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	// issue returns a certificate signed by the test CA
+	issue := func(cn string, usage x509.ExtKeyUsage) tls.Certificate {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(time.Now().UnixNano()),
+			Subject:      pkix.Name{CommonName: cn},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+		require.NoError(t, err)
+		return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	}
+
+	writePEM := func(name, blockType string, der []byte) string {
+		path := filepath.Join(certDir, name)
+		require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600))
+		return path
+	}
+	serverCert := issue("127.0.0.1", x509.ExtKeyUsageServerAuth)
+	serverKeyDER, err := x509.MarshalECPrivateKey(serverCert.PrivateKey.(*ecdsa.PrivateKey))
+	require.NoError(t, err)
+
+	tlsConfig, err := filesystemserver.LoadTLSConfig(
+		writePEM("server.crt", "CERTIFICATE", serverCert.Certificate[0]),
+		writePEM("server.key", "EC PRIVATE KEY", serverKeyDER),
+		writePEM("ca.crt", "CERTIFICATE", caDER),
+	)
+	require.NoError(t, err)
+
+	auth, err := filesystemserver.NewAuth([]string{allowed}, []filesystemserver.Principal{
+		{Name: "alice", ClientCN: "alice", Access: filesystemserver.AccessRead},
+	})
+	require.NoError(t, err)
+
+	url := serveAuth(t, []string{allowed}, filesystemserver.ServeOptions{TLS: tlsConfig, Auth: auth})
+
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+	httpClient := func(certs ...tls.Certificate) *http.Client {
+		c := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs},
+		}}
+		t.Cleanup(c.CloseIdleConnections)
+		return c
+	}
+
+	t.Run("without a certificate or token", func(t *testing.T) {
+		resp, err := httpClient().Post(url, "application/json", strings.NewReader(`{}`))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("certificate of a principal", func(t *testing.T) {
+		mcpClient := connect(t, url, transport.WithHTTPBasicClient(httpClient(issue("alice", x509.ExtKeyUsageClientAuth))))
+		assert.NotNil(t, getTool(t, mcpClient, "read_file"))
+		assert.False(t, hasTool(t, mcpClient, "write_file"))
+	})
+
+	t.Run("certificate without a principal is refused", func(t *testing.T) {
+		resp, err := httpClient(issue("bob", x509.ExtKeyUsageClientAuth)).Post(url, "application/json", strings.NewReader(`{}`))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+}
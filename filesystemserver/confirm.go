@@ -0,0 +1,108 @@
+package filesystemserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/mark3labs/mcp-filesystem-server/filesystemserver/handler"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Confirmation modes for Config.Confirm
+const (
+	// ConfirmOff runs every tool call without asking
+	ConfirmOff = "off"
+	// ConfirmDestructive asks before the calls of destructive tools that
+	// would overwrite or remove data, such as recursive deletes of
+	// directories, writes to existing files and undoing changes
+	ConfirmDestructive = "destructive"
+	// ConfirmAll asks before every call of a tool that changes files
+	ConfirmAll = "all"
+)
+
+// maxConfirmArguments bounds the length of the arguments shown when asking
+// about a call that is not destructive
+const maxConfirmArguments = 500
+
+func validConfirmMode(mode string) error {
+	switch mode {
+	case "", ConfirmOff, ConfirmDestructive, ConfirmAll:
+		return nil
+	}
+	return fmt.Errorf("invalid confirmation mode %q (expected off, destructive or all)", mode)
+}
+
+// confirmMiddleware asks the user of the client through an elicitation
+// before running the calls that mode deems dangerous. The call is aborted if
+// the user declines or the client cannot ask.
+func confirmMiddleware(h *handler.FilesystemHandler, mode string) server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			tool := request.Params.Name
+			summary := h.DestructiveSummary(tool, request.GetArguments())
+			if summary == "" && mode == ConfirmAll && slices.Contains(mutatingTools, tool) {
+				arguments, _ := json.Marshal(request.GetArguments())
+				if len(arguments) > maxConfirmArguments {
+					arguments = append(arguments[:maxConfirmArguments], "..."...)
+				}
+				summary = fmt.Sprintf("Run %s with %s", tool, arguments)
+			}
+			if summary == "" {
+				return next(ctx, request)
+			}
+
+			if err := confirm(ctx, summary); err != nil {
+				return &mcp.CallToolResult{
+					Content: []mcp.Content{
+						mcp.TextContent{
+							Type: "text",
+							Text: fmt.Sprintf("Error: %v. Nothing was changed.", err),
+						},
+					},
+					IsError: true,
+				}, nil
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+// confirm asks the user whether to go ahead with what summary describes
+func confirm(ctx context.Context, summary string) error {
+	s := server.ServerFromContext(ctx)
+	session, ok := server.ClientSessionFromContext(ctx).(server.SessionWithClientInfo)
+	if s == nil || !ok || session.GetClientCapabilities().Elicitation == nil {
+		return fmt.Errorf("this operation needs confirmation but the client cannot ask the user (no elicitation support)")
+	}
+
+	result, err := s.RequestElicitation(ctx, mcp.ElicitationRequest{
+		Params: mcp.ElicitationParams{
+			Message: summary + "\n\nDo you want to continue?",
+			RequestedSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"confirm": map[string]any{
+						"type":        "boolean",
+						"title":       "Continue",
+						"description": "Go ahead with the operation",
+					},
+				},
+				"required": []string{"confirm"},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to ask the user for confirmation: %w", err)
+	}
+	if result.Action != mcp.ElicitationResponseActionAccept {
+		return fmt.Errorf("the user did not confirm the operation (%s)", result.Action)
+	}
+	content, _ := result.Content.(map[string]any)
+	if confirmed, _ := content["confirm"].(bool); !confirmed {
+		return fmt.Errorf("the user did not confirm the operation")
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package filesystemserver_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-filesystem-server/filesystemserver"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testUser answers confirmation requests and remembers what it was asked
+type testUser struct {
+	action   mcp.ElicitationResponseAction
+	messages []string
+}
+
+func (u *testUser) Elicit(ctx context.Context, request mcp.ElicitationRequest) (*mcp.ElicitationResult, error) {
+	u.messages = append(u.messages, request.Params.Message)
+	result := &mcp.ElicitationResult{}
+	result.Action = u.action
+	if u.action == mcp.ElicitationResponseActionAccept {
+		result.Content = map[string]any{"confirm": true}
+	}
+	return result, nil
+}
+
+func TestConfirm(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	build := filepath.Join(dir, "build")
+	require.NoError(t, os.MkdirAll(filepath.Join(build, "out"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(build, "out", "app"), []byte("binary"), 0644))
+	notes := filepath.Join(dir, "notes.txt")
+	require.NoError(t, os.WriteFile(notes, []byte("keep me"), 0644))
+
+	fss, err := filesystemserver.NewFilesystemServer([]string{dir},
+		filesystemserver.WithConfig(filesystemserver.Config{Confirm: filesystemserver.ConfirmDestructive}))
+	require.NoError(t, err)
+
+	user := &testUser{}
+	mcpClient := client.NewClient(
+		transport.NewInProcessTransportWithOptions(fss, transport.WithElicitationHandler(user)),
+		client.WithElicitationHandler(user),
+	)
+	t.Cleanup(func() { mcpClient.Close() })
+	require.NoError(t, mcpClient.Start(context.Background()))
+	initRequest := mcp.InitializeRequest{}
+	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	_, err = mcpClient.Initialize(context.Background(), initRequest)
+	require.NoError(t, err)
+
+	call := func(c client.MCPClient, name string, arguments map[string]any) *mcp.CallToolResult {
+		request := mcp.CallToolRequest{}
+		request.Params.Name = name
+		request.Params.Arguments = arguments
+		result, err := c.CallTool(context.Background(), request)
+		require.NoError(t, err)
+		return result
+	}
+
+	t.Run("declined overwrite", func(t *testing.T) {
+		user.action = mcp.ElicitationResponseActionDecline
+		result := call(mcpClient, "write_file", map[string]any{"path": notes, "content": "gone"})
+		assert.True(t, result.IsError)
+		assert.Contains(t, result.Content[0].(mcp.TextContent).Text, "did not confirm")
+		data, err := os.ReadFile(notes)
+		require.NoError(t, err)
+		assert.Equal(t, "keep me", string(data))
+		require.Len(t, user.messages, 1)
+		assert.Contains(t, user.messages[0], "Overwrite the existing file "+notes)
+	})
+
+	t.Run("safe calls are not asked about", func(t *testing.T) {
+		user.messages = nil
+		result := call(mcpClient, "write_file", map[string]any{"path": filepath.Join(dir, "new.txt"), "content": "new"})
+		assert.False(t, result.IsError)
+		result = call(mcpClient, "read_file", map[string]any{"path": notes})
+		assert.False(t, result.IsError)
+		assert.Empty(t, user.messages)
+	})
+
+	t.Run("confirmed recursive delete", func(t *testing.T) {
+		user.action = mcp.ElicitationResponseActionAccept
+		result := call(mcpClient, "delete_file", map[string]any{"path": build, "recursive": true})
+		require.False(t, result.IsError, result.Content)
+		_, err := os.Stat(build)
+		assert.True(t, os.IsNotExist(err))
+		require.Len(t, user.messages, 1)
+		assert.Contains(t, user.messages[0], "1 file(s) and 1 directory(ies)")
+		assert.Contains(t, user.messages[0], filepath.Join("out", "app"))
+	})
+
+	t.Run("clients without elicitation are refused", func(t *testing.T) {
+		result := call(startTestClient(t, fss), "write_file", map[string]any{"path": notes, "content": "gone"})
+		assert.True(t, result.IsError)
+		assert.Contains(t, result.Content[0].(mcp.TextContent).Text, "no elicitation support")
+	})
+
+	t.Run("invalid mode", func(t *testing.T) {
+		_, err := filesystemserver.NewFilesystemServer([]string{dir},
+			filesystemserver.WithConfig(filesystemserver.Config{Confirm: "sometimes"}))
+		assert.Error(t, err)
+	})
+}
+
+func TestConfirmEveryDestructiveTool(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	notes := filepath.Join(dir, "notes.txt")
+	build := filepath.Join(dir, "build")
+	added := filepath.Join(dir, "added.txt")
+
+	// Arguments for each destructive tool that make it overwrite or remove
+	// something once the setup below has run
+	arguments := map[string]map[string]any{
+		"write_file":         {"path": notes, "content": "gone"},
+		"modify_file":        {"path": notes, "find": "keep", "replace": "lose"},
+		"edit_lines":         {"path": notes, "operation": "delete", "start": 1},
+		"copy_file":          {"source": notes, "destination": build},
+		"move_file":          {"source": added, "destination": notes},
+		"delete_file":        {"path": build, "recursive": true},
+		"batch":              {"operations": []any{map[string]any{"op": "write", "path": notes, "content": "gone"}}},
+		"empty_trash":        {},
+		"undo_last":          {},
+		"undo_to":            {"checkpoint": 0},
+		"checkpoint_restore": {"name": "before"},
+		"overlay_commit":     {},
+		"overlay_discard":    {},
+	}
+
+	for _, cfg := range []filesystemserver.Config{
+		{TrashDir: t.TempDir(), JournalDir: t.TempDir(), CheckpointDir: t.TempDir()},
+		{OverlayDir: t.TempDir()},
+	} {
+		require.NoError(t, os.RemoveAll(build))
+		require.NoError(t, os.RemoveAll(added))
+		require.NoError(t, os.MkdirAll(filepath.Join(build, "out"), 0755))
+		require.NoError(t, os.WriteFile(notes, []byte("keep me"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "old.txt"), []byte("old"), 0644))
+
+		cfg.Confirm = filesystemserver.ConfirmDestructive
+		fss, err := filesystemserver.NewFilesystemServer([]string{dir}, filesystemserver.WithConfig(cfg))
+		require.NoError(t, err)
+		user := &testUser{action: mcp.ElicitationResponseActionDecline}
+		mcpClient := client.NewClient(
+			transport.NewInProcessTransportWithOptions(fss, transport.WithElicitationHandler(user)),
+			client.WithElicitationHandler(user),
+		)
+		t.Cleanup(func() { mcpClient.Close() })
+		require.NoError(t, mcpClient.Start(context.Background()))
+		initRequest := mcp.InitializeRequest{}
+		initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+		_, err = mcpClient.Initialize(context.Background(), initRequest)
+		require.NoError(t, err)
+
+		call := func(name string, arguments map[string]any) *mcp.CallToolResult {
+			request := mcp.CallToolRequest{}
+			request.Params.Name = name
+			request.Params.Arguments = arguments
+			result, err := mcpClient.CallTool(context.Background(), request)
+			require.NoError(t, err)
+			return result
+		}
+
+		// Give the trash, journal, checkpoint and overlay something to lose
+		if cfg.CheckpointDir != "" {
+			require.False(t, call("checkpoint_create", map[string]any{"name": "before", "path": dir}).IsError)
+		}
+		require.False(t, call("write_file", map[string]any{"path": added, "content": "new"}).IsError)
+		require.False(t, call("delete_file", map[string]any{"path": filepath.Join(dir, "old.txt")}).IsError)
+		require.Empty(t, user.messages)
+
+		tools, err := mcpClient.ListTools(context.Background(), mcp.ListToolsRequest{})
+		require.NoError(t, err)
+		for _, tool := range tools.Tools {
+			if hint := tool.Annotations.DestructiveHint; hint == nil || !*hint {
+				continue
+			}
+			args, ok := arguments[tool.Name]
+			if !assert.True(t, ok, "no arguments for destructive tool %s", tool.Name) {
+				continue
+			}
+			user.messages = nil
+			result := call(tool.Name, args)
+			assert.True(t, result.IsError, tool.Name)
+			assert.Len(t, user.messages, 1, "%s was not confirmed", tool.Name)
+		}
+		data, err := os.ReadFile(notes)
+		require.NoError(t, err)
+		assert.Equal(t, "keep me", string(data))
+	}
+}
@@ -0,0 +1,248 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Origins of allowed directories
+const (
+	// DirOriginConfig directories were given when the handler was created
+	DirOriginConfig = "config"
+	// DirOriginClient directories are roots of the MCP client
+	DirOriginClient = "client"
+	// DirOriginAdmin directories were added with add_allowed_directory
+	DirOriginAdmin = "admin"
+)
+
+// Access levels of allowed directories
+const (
+	// DirAccessRead directories can only be read
+	DirAccessRead = "read"
+	// DirAccessWrite directories can be read and changed
+	DirAccessWrite = "write"
+	// DirAccessOverlay directories can be changed through the overlay only
+	DirAccessOverlay = "overlay"
+)
+
+func dirOrigins(dirs []string, origin string) map[string]string {
+	origins := make(map[string]string, len(dirs))
+	for _, dir := range dirs {
+		origins[dir] = origin
+	}
+	return origins
+}
+
+// AllowedDirectories returns the directories the handler gives access to
+func (fs *FilesystemHandler) AllowedDirectories() []string {
+	fs.dirsMu.RLock()
+	defer fs.dirsMu.RUnlock()
+	dirs := make([]string, len(fs.allowedDirs))
+	for i, dir := range fs.allowedDirs {
+		dirs[i] = strings.TrimSuffix(dir, string(filepath.Separator))
+	}
+	return dirs
+}
+
+// AllowedDirectoryInfo describes the directories the handler gives access to
+func (fs *FilesystemHandler) AllowedDirectoryInfo() []AllowedDirectory {
+	access := DirAccessWrite
+	switch {
+	case fs.readOnly:
+		access = DirAccessRead
+	case fs.overlay != nil:
+		access = DirAccessOverlay
+	default:
+		if _, ok := fs.backend.(*FSBackend); ok {
+			access = DirAccessRead
+		}
+	}
+
+	fs.dirsMu.RLock()
+	defer fs.dirsMu.RUnlock()
+	dirs := make([]AllowedDirectory, len(fs.allowedDirs))
+	for i, dir := range fs.allowedDirs {
+		dirs[i] = AllowedDirectory{
+			Path:   strings.TrimSuffix(dir, string(filepath.Separator)),
+			Access: access,
+			Origin: fs.dirOrigins[dir],
+		}
+	}
+	return dirs
+}
+
+// SetAllowedDirectories replaces the directories the handler gives access
+// to. The current directories are kept if any of dirs is invalid or contains
+// the data of the trash, journal, checkpoints or overlay.
+func (fs *FilesystemHandler) SetAllowedDirectories(dirs []string, origin string) error {
+	normalized, err := normalizeDirs(fs.backend, dirs)
+	if err != nil {
+		return err
+	}
+	for i, dir := range normalized {
+		if err := fs.checkInternalOverlap(dir); err != nil {
+			return fmt.Errorf("directory %s %w", dirs[i], err)
+		}
+	}
+	fs.dirsMu.Lock()
+	defer fs.dirsMu.Unlock()
+	fs.allowedDirs = normalized
+	fs.dirOrigins = dirOrigins(normalized, origin)
+	return nil
+}
+
+// AddAllowedDirectory gives access to dir as well. Directories that contain
+// the data of the trash, journal, checkpoints or overlay are refused.
+func (fs *FilesystemHandler) AddAllowedDirectory(dir string) (string, error) {
+	normalized, err := normalizeDirs(fs.backend, []string{dir})
+	if err != nil {
+		return "", err
+	}
+	added := normalized[0]
+	if err := fs.checkInternalOverlap(added); err != nil {
+		return "", fmt.Errorf("directory %s %w", dir, err)
+	}
+
+	fs.dirsMu.Lock()
+	defer fs.dirsMu.Unlock()
+	if slices.Contains(fs.allowedDirs, added) {
+		return "", fmt.Errorf("directory is already allowed: %s", dir)
+	}
+	// Copy so that readers of the previous slice are not affected
+	fs.allowedDirs = append(slices.Clone(fs.allowedDirs), added)
+	fs.dirOrigins[added] = DirOriginAdmin
+	return strings.TrimSuffix(added, string(filepath.Separator)), nil
+}
+
+// RemoveAllowedDirectory stops giving access to dir
+func (fs *FilesystemHandler) RemoveAllowedDirectory(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve path %s: %w", dir, err)
+	}
+	removed := filepath.Clean(abs) + string(filepath.Separator)
+
+	fs.dirsMu.Lock()
+	defer fs.dirsMu.Unlock()
+	i := slices.Index(fs.allowedDirs, removed)
+	if i < 0 {
+		return "", fmt.Errorf("not an allowed directory: %s", dir)
+	}
+	fs.allowedDirs = slices.Delete(slices.Clone(fs.allowedDirs), i, i+1)
+	delete(fs.dirOrigins, removed)
+	return strings.TrimSuffix(removed, string(filepath.Separator)), nil
+}
+
+// dirs returns the allowed directories with trailing separators
+func (fs *FilesystemHandler) dirs() []string {
+	fs.dirsMu.RLock()
+	defer fs.dirsMu.RUnlock()
+	return fs.allowedDirs
+}
+
+// internalDirs returns the directories where the handler keeps its own data
+func (fs *FilesystemHandler) internalDirs() []string {
+	var dirs []string
+	if fs.trash != nil {
+		dirs = append(dirs, fs.trash.dir)
+	}
+	if fs.journal != nil {
+		dirs = append(dirs, fs.journal.dir)
+	}
+	if fs.checkpoints != nil {
+		dirs = append(dirs, fs.checkpoints.dir)
+	}
+	if fs.overlay != nil {
+		dirs = append(dirs, filepath.Dir(fs.overlay.upper))
+	}
+	return dirs
+}
+
+// checkInternalOverlap returns an error if the normalized directory dir
+// contains or lies within the server's own data
+func (fs *FilesystemHandler) checkInternalOverlap(dir string) error {
+	for _, internal := range fs.internalDirs() {
+		internal += string(filepath.Separator)
+		if strings.HasPrefix(internal, dir) || strings.HasPrefix(dir, internal) {
+			return fmt.Errorf("overlaps the server's own data in %s", internal)
+		}
+	}
+	return nil
+}
+
+// checkAllowedOverlap returns an error if the normalized directory dir
+// contains or lies within an allowed directory, where tools could change
+// the server's own data
+func (fs *FilesystemHandler) checkAllowedOverlap(dir string) error {
+	dir += string(filepath.Separator)
+	for _, allowed := range fs.dirs() {
+		if strings.HasPrefix(allowed, dir) || strings.HasPrefix(dir, allowed) {
+			return fmt.Errorf("overlaps the allowed directory %s", allowed)
+		}
+	}
+	return nil
+}
+
+// EnableDirectoryAdmin lets clients change the allowed directories with
+// add_allowed_directory and remove_allowed_directory
+func (fs *FilesystemHandler) EnableDirectoryAdmin() {
+	fs.directoryAdmin = true
+}
+
+// DirectoryAdminEnabled reports whether clients can change the allowed
+// directories
+func (fs *FilesystemHandler) DirectoryAdminEnabled() bool {
+	return fs.directoryAdmin
+}
+
+// SetReadOnly records that only the tools that do not change files are
+// served, which list_allowed_directories reports as the access level
+func (fs *FilesystemHandler) SetReadOnly(readOnly bool) {
+	fs.readOnly = readOnly
+}
+
+// ReadOnly reports whether only the tools that do not change files are served
+func (fs *FilesystemHandler) ReadOnly() bool {
+	return fs.readOnly
+}
+
+// SetToolSelection records which tools are served: only the enabled ones if
+// any are named, and never the disabled ones
+func (fs *FilesystemHandler) SetToolSelection(enabled, disabled []string) {
+	fs.enabledTools = enabled
+	fs.disabledTools = disabled
+}
+
+// ToolSelection returns the tools recorded by SetToolSelection
+func (fs *FilesystemHandler) ToolSelection() (enabled, disabled []string) {
+	return fs.enabledTools, fs.disabledTools
+}
+
+func directoryAdminDisabledResult() *mcp.CallToolResult {
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: "Error: Changing the allowed directories is not enabled on this server",
+			},
+		},
+		IsError: true,
+	}
+}
+
+// notifyAllowedDirectoriesChanged tells the clients that the resources and
+// the tool descriptions that mention the allowed directories changed
+func notifyAllowedDirectoriesChanged(ctx context.Context) {
+	s := server.ServerFromContext(ctx)
+	if s == nil {
+		return
+	}
+	s.SendNotificationToAllClients(mcp.MethodNotificationResourcesListChanged, nil)
+	s.SendNotificationToAllClients(mcp.MethodNotificationToolsListChanged, nil)
+}
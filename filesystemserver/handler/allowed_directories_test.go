@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirectoryAdmin(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	extra, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(extra, "notes.txt"), []byte("hello"), 0644))
+
+	fsHandler, err := NewFilesystemHandler([]string{dir})
+	require.NoError(t, err)
+	text := func(result *mcp.CallToolResult) string {
+		return result.Content[0].(mcp.TextContent).Text
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		result := callTool(t, fsHandler.HandleAddAllowedDirectory, map[string]any{"path": extra})
+		assert.True(t, result.IsError)
+		assert.Contains(t, text(result), "not enabled")
+	})
+
+	fsHandler.EnableDirectoryAdmin()
+
+	t.Run("add and remove", func(t *testing.T) {
+		result := callTool(t, fsHandler.HandleReadFile, map[string]any{"path": filepath.Join(extra, "notes.txt")})
+		assert.True(t, result.IsError)
+
+		result = callTool(t, fsHandler.HandleAddAllowedDirectory, map[string]any{"path": extra})
+		require.False(t, result.IsError, text(result))
+		result = callTool(t, fsHandler.HandleReadFile, map[string]any{"path": filepath.Join(extra, "notes.txt")})
+		require.False(t, result.IsError, text(result))
+		assert.Equal(t, "hello", text(result))
+
+		result = callTool(t, fsHandler.HandleListAllowedDirectories, map[string]any{})
+		assert.Contains(t, text(result), dir+" ("+pathToResourceURI(dir)+") [access: write, origin: config]")
+		assert.Contains(t, text(result), extra+" ("+pathToResourceURI(extra)+") [access: write, origin: admin]")
+
+		result = callTool(t, fsHandler.HandleAddAllowedDirectory, map[string]any{"path": extra})
+		assert.True(t, result.IsError)
+
+		result = callTool(t, fsHandler.HandleRemoveAllowedDirectory, map[string]any{"path": extra})
+		require.False(t, result.IsError, text(result))
+		result = callTool(t, fsHandler.HandleReadFile, map[string]any{"path": filepath.Join(extra, "notes.txt")})
+		assert.True(t, result.IsError)
+		assert.Equal(t, []string{dir}, fsHandler.AllowedDirectories())
+
+		result = callTool(t, fsHandler.HandleRemoveAllowedDirectory, map[string]any{"path": extra})
+		assert.True(t, result.IsError)
+	})
+
+	t.Run("server data cannot be added", func(t *testing.T) {
+		trashDir := filepath.Join(extra, ".trash")
+		require.NoError(t, fsHandler.EnableTrash(trashDir, 0))
+
+		result := callTool(t, fsHandler.HandleAddAllowedDirectory, map[string]any{"path": extra})
+		assert.True(t, result.IsError)
+		assert.Contains(t, text(result), "overlaps")
+		result = callTool(t, fsHandler.HandleAddAllowedDirectory, map[string]any{"path": trashDir})
+		assert.True(t, result.IsError)
+
+		// Nor can it be set, as client roots do
+		err := fsHandler.SetAllowedDirectories([]string{dir, extra}, DirOriginClient)
+		assert.ErrorContains(t, err, "overlaps")
+		assert.Equal(t, []string{dir}, fsHandler.AllowedDirectories())
+	})
+
+	t.Run("read-only access", func(t *testing.T) {
+		fsHandler.SetReadOnly(true)
+		result := callTool(t, fsHandler.HandleListAllowedDirectories, map[string]any{})
+		assert.Contains(t, text(result), "[access: read, origin: config]")
+	})
+}
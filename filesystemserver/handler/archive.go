@@ -0,0 +1,355 @@
+package handler
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gobwas/glob"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Supported archive formats
+const (
+	ArchiveZip    = "zip"
+	ArchiveTar    = "tar"
+	ArchiveTarGz  = "tar.gz"
+	ArchiveTarZst = "tar.zst"
+)
+
+// archiveSuffixes maps file name suffixes to the format they imply
+var archiveSuffixes = []struct {
+	suffix, format string
+}{
+	{".zip", ArchiveZip},
+	{".jar", ArchiveZip},
+	{".war", ArchiveZip},
+	{".tar", ArchiveTar},
+	{".tar.gz", ArchiveTarGz},
+	{".tgz", ArchiveTarGz},
+	{".tar.zst", ArchiveTarZst},
+	{".tzst", ArchiveTarZst},
+}
+
+// archiveFormat returns the format of the archive at name as implied by its
+// suffix, or "" if it has none of the known ones
+func archiveFormat(name string) string {
+	lower := strings.ToLower(name)
+	for _, s := range archiveSuffixes {
+		if strings.HasSuffix(lower, s.suffix) {
+			return s.format
+		}
+	}
+	return ""
+}
+
+// archiveWriter adds entries to an archive. Names use forward slashes and
+// directories end with one.
+type archiveWriter interface {
+	// add writes an entry described by info. Regular files take their
+	// content from r and symlinks point to link.
+	add(name string, info os.FileInfo, link string, r io.Reader) error
+	Close() error
+}
+
+// newArchiveWriter creates a writer for format on top of w
+func newArchiveWriter(w io.Writer, format string) (archiveWriter, error) {
+	switch format {
+	case ArchiveZip:
+		return &zipWriter{zip.NewWriter(w)}, nil
+	case ArchiveTar:
+		return &tarWriter{Writer: tar.NewWriter(w)}, nil
+	case ArchiveTarGz:
+		gz := gzip.NewWriter(w)
+		return &tarWriter{Writer: tar.NewWriter(gz), compressor: gz}, nil
+	case ArchiveTarZst:
+		zw, err := zstd.NewWriter(w)
+		if err != nil {
+			return nil, err
+		}
+		return &tarWriter{Writer: tar.NewWriter(zw), compressor: zw}, nil
+	}
+	return nil, fmt.Errorf("unsupported archive format %q (expected zip, tar, tar.gz or tar.zst)", format)
+}
+
+type zipWriter struct {
+	*zip.Writer
+}
+
+func (z *zipWriter) add(name string, info os.FileInfo, link string, r io.Reader) error {
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if info.Mode().IsRegular() {
+		header.Method = zip.Deflate
+	}
+	w, err := z.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		// Like Info-ZIP, symlinks store their target as content
+		_, err = io.WriteString(w, link)
+	case info.Mode().IsRegular():
+		_, err = io.Copy(w, r)
+	}
+	return err
+}
+
+type tarWriter struct {
+	*tar.Writer
+	compressor io.WriteCloser
+}
+
+func (t *tarWriter) add(name string, info os.FileInfo, link string, r io.Reader) error {
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	// PAX keeps modification times below a second
+	header.Format = tar.FormatPAX
+	if err := t.WriteHeader(header); err != nil {
+		return err
+	}
+	if info.Mode().IsRegular() {
+		_, err = io.Copy(t, r)
+	}
+	return err
+}
+
+func (t *tarWriter) Close() error {
+	err := t.Writer.Close()
+	if t.compressor != nil {
+		if cerr := t.compressor.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// archiveEntry is an entry read from an archive. Types other than regular
+// files, directories and symlinks, such as hard links and devices, have
+// os.ModeIrregular set.
+type archiveEntry struct {
+	name     string
+	mode     os.FileMode
+	size     int64
+	modified time.Time
+	link     string
+}
+
+// maxArchiveLink bounds the length of a symlink target stored in a zip file
+const maxArchiveLink = 4096
+
+// maxZstdWindow is the largest zstd window readArchive accepts, the default
+// limit of the zstd command line tool
+const maxZstdWindow = 1 << 27
+
+// readArchive calls fn for each entry of the archive at name in order. For
+// regular files r reads the content; it is only valid during the call.
+// maxSize is the most the archive may unpack to, which also bounds the
+// memory the decompressor may allocate.
+func readArchive(b Backend, name, format string, maxSize int64, fn func(entry archiveEntry, r io.Reader) error) error {
+	f, err := b.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	switch format {
+	case ArchiveZip:
+		return readZip(f, fn)
+	case ArchiveTar:
+		return readTar(f, fn)
+	case ArchiveTarGz:
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		return readTar(gz, fn)
+	case ArchiveTarZst:
+		// A frame needs no window larger than what it unpacks to
+		window := uint64(maxSize)
+		switch {
+		case window < zstd.MinWindowSize:
+			window = zstd.MinWindowSize
+		case window > maxZstdWindow:
+			window = maxZstdWindow
+		}
+		zr, err := zstd.NewReader(f,
+			zstd.WithDecoderMaxWindow(window),
+			zstd.WithDecoderMaxMemory(uint64(maxSize)),
+		)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		return readTar(zr, fn)
+	}
+	return fmt.Errorf("unsupported archive format %q (expected zip, tar, tar.gz or tar.zst)", format)
+}
+
+func readZip(f File, fn func(entry archiveEntry, r io.Reader) error) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	// zip needs random access; backends without it are read into memory
+	readerAt, ok := f.(io.ReaderAt)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		readerAt = bytes.NewReader(data)
+	}
+	zr, err := zip.NewReader(readerAt, info.Size())
+	if err != nil {
+		return err
+	}
+
+	for _, zf := range zr.File {
+		entry := archiveEntry{
+			name:     zf.Name,
+			mode:     zf.Mode(),
+			size:     int64(zf.UncompressedSize64),
+			modified: zf.Modified,
+		}
+		if strings.HasSuffix(zf.Name, "/") {
+			entry.mode |= os.ModeDir
+		}
+		if !entry.mode.IsDir() && entry.mode&os.ModeSymlink == 0 && !entry.mode.IsRegular() {
+			entry.mode |= os.ModeIrregular
+		}
+
+		var rc io.ReadCloser
+		if entry.mode.IsRegular() || entry.mode&os.ModeSymlink != 0 {
+			if rc, err = zf.Open(); err != nil {
+				return err
+			}
+		}
+		if entry.mode&os.ModeSymlink != 0 {
+			link, err := io.ReadAll(io.LimitReader(rc, maxArchiveLink))
+			rc.Close()
+			if err != nil {
+				return err
+			}
+			entry.link = string(link)
+			rc = nil
+		}
+
+		err = fn(entry, rc)
+		if rc != nil {
+			rc.Close()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readTar(r io.Reader, fn func(entry archiveEntry, r io.Reader) error) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		entry := archiveEntry{
+			name:     header.Name,
+			mode:     os.FileMode(header.Mode).Perm(),
+			size:     header.Size,
+			modified: header.ModTime,
+		}
+		switch header.Typeflag {
+		case tar.TypeReg:
+		case tar.TypeDir:
+			entry.mode |= os.ModeDir
+		case tar.TypeSymlink:
+			entry.mode |= os.ModeSymlink
+			entry.link = header.Linkname
+		case tar.TypeXGlobalHeader:
+			continue
+		default:
+			entry.mode |= os.ModeIrregular
+			entry.link = header.Linkname
+		}
+		if err := fn(entry, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// archiveFilter selects archive entries by glob. Patterns without a slash
+// match the base name of an entry anywhere in the tree; patterns with one
+// match its whole name, with ** matching across directories.
+type archiveFilter struct {
+	include, exclude []archivePattern
+}
+
+type archivePattern struct {
+	glob     glob.Glob
+	fullName bool
+}
+
+func newArchiveFilter(include, exclude []string) (*archiveFilter, error) {
+	compile := func(patterns []string) ([]archivePattern, error) {
+		var result []archivePattern
+		for _, pattern := range patterns {
+			g, err := glob.Compile(pattern, '/')
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+			result = append(result, archivePattern{glob: g, fullName: strings.Contains(pattern, "/")})
+		}
+		return result, nil
+	}
+
+	f := &archiveFilter{}
+	var err error
+	if f.include, err = compile(include); err != nil {
+		return nil, err
+	}
+	if f.exclude, err = compile(exclude); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func matchArchivePatterns(patterns []archivePattern, name string) bool {
+	for _, p := range patterns {
+		if p.fullName && p.glob.Match(name) || !p.fullName && p.glob.Match(path.Base(name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// excluded reports whether the entry name (without a trailing slash) and
+// everything under it are left out
+func (f *archiveFilter) excluded(name string) bool {
+	return matchArchivePatterns(f.exclude, name)
+}
+
+// included reports whether the file name is packed. Without include
+// patterns every file is.
+func (f *archiveFilter) included(name string) bool {
+	return len(f.include) == 0 || matchArchivePatterns(f.include, name)
+}
@@ -0,0 +1,487 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// archiveSeparator separates the path of an archive from the path of an
+// entry inside it, as in bundle.zip!/docs/index.html
+const archiveSeparator = "!"
+
+// errArchiveEntryFound stops reading an archive once the wanted entry is read
+var errArchiveEntryFound = errors.New("archive entry found")
+
+// splitArchivePath splits a path like bundle.zip!/docs/index.html into the
+// archive and the cleaned name of the entry, which is "" for the root of the
+// archive. ok is false for paths that do not point into an archive.
+func splitArchivePath(p string) (archivePath, name string, ok bool) {
+	for i := 0; i < len(p); i++ {
+		if p[i] != archiveSeparator[0] {
+			continue
+		}
+		rest := p[i+1:]
+		if rest != "" && rest[0] != '/' && rest[0] != '\\' {
+			continue
+		}
+		if archiveFormat(p[:i]) == "" {
+			continue
+		}
+		name = path.Clean("/" + strings.ReplaceAll(rest, "\\", "/"))
+		return p[:i], strings.TrimPrefix(name, "/"), true
+	}
+	return "", "", false
+}
+
+// joinArchivePath is the inverse of splitArchivePath
+func joinArchivePath(archivePath, name string) string {
+	return archivePath + archiveSeparator + "/" + name
+}
+
+// archiveIndex holds the entries of an archive by cleaned name. Directories
+// that are only implied by the names of other entries are included, and ""
+// is the root.
+type archiveIndex struct {
+	path, format string
+	entries      map[string]archiveEntry
+}
+
+// openArchive validates the archive at archivePath and indexes its entries.
+// Entries with unsafe names are left out.
+func (fs *FilesystemHandler) openArchive(archivePath string) (*archiveIndex, error) {
+	validPath, err := fs.validatePath(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	info, err := fs.backend.Stat(validPath)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory, not an archive", validPath)
+	}
+
+	index := &archiveIndex{
+		path:   validPath,
+		format: archiveFormat(validPath),
+		entries: map[string]archiveEntry{
+			"": {mode: os.ModeDir | 0755, modified: info.ModTime()},
+		},
+	}
+	err = readArchive(fs.backend, validPath, index.format, fs.limits.MaxExtractSize, func(entry archiveEntry, r io.Reader) error {
+		name, err := archiveEntryName(archiveEntry{name: entry.name})
+		if err != nil {
+			return nil
+		}
+		entry.name = name
+		index.entries[name] = entry
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			if _, ok := index.entries[dir]; ok {
+				break
+			}
+			index.entries[dir] = archiveEntry{name: dir, mode: os.ModeDir | 0755, modified: entry.modified}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading archive %s: %w", validPath, err)
+	}
+	return index, nil
+}
+
+// lookup returns the entry name, failing if it does not exist
+func (ix *archiveIndex) lookup(name string) (archiveEntry, error) {
+	entry, ok := ix.entries[name]
+	if !ok {
+		return archiveEntry{}, fmt.Errorf("%s does not exist", joinArchivePath(ix.path, name))
+	}
+	return entry, nil
+}
+
+// children returns the entries directly inside the directory dir by name
+func (ix *archiveIndex) children(dir string) []archiveEntry {
+	var result []archiveEntry
+	for name, entry := range ix.entries {
+		if name != "" && archiveParent(name) == dir {
+			result = append(result, entry)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].name < result[j].name
+	})
+	return result
+}
+
+// archiveParent is path.Dir for entry names, with "" for the root
+func archiveParent(name string) string {
+	if dir := path.Dir(name); dir != "." {
+		return dir
+	}
+	return ""
+}
+
+// archiveWithin reports whether the entry name is dir or inside it
+func archiveWithin(name, dir string) bool {
+	return dir == "" || name == dir || strings.HasPrefix(name, dir+"/")
+}
+
+// readArchiveEntry returns the content of the regular file name in the archive
+func (fs *FilesystemHandler) readArchiveEntry(ix *archiveIndex, name string) ([]byte, error) {
+	var content []byte
+	err := readArchive(fs.backend, ix.path, ix.format, fs.limits.MaxExtractSize, func(entry archiveEntry, r io.Reader) error {
+		if entryName, err := archiveEntryName(archiveEntry{name: entry.name}); err != nil || entryName != name {
+			return nil
+		}
+		var err error
+		if content, err = io.ReadAll(io.LimitReader(r, entry.size)); err != nil {
+			return err
+		}
+		return errArchiveEntryFound
+	})
+	if err != errArchiveEntryFound {
+		if err == nil {
+			err = fmt.Errorf("%s does not exist", joinArchivePath(ix.path, name))
+		}
+		return nil, err
+	}
+	return content, nil
+}
+
+// archiveMimeType detects the MIME type of an archive entry from its content
+func archiveMimeType(name string, content []byte) string {
+	if mimeType := mimetype.Detect(content).String(); mimeType != "application/octet-stream" || len(content) == 0 {
+		return mimeType
+	}
+	if mimeType := mime.TypeByExtension(path.Ext(name)); mimeType != "" {
+		return mimeType
+	}
+	return "application/octet-stream"
+}
+
+// listArchiveDirectory implements list_directory for paths inside archives
+func (fs *FilesystemHandler) listArchiveDirectory(archivePath, name string) (*mcp.CallToolResult, error) {
+	ix, err := fs.openArchive(archivePath)
+	if err == nil {
+		var entry archiveEntry
+		if entry, err = ix.lookup(name); err == nil && !entry.mode.IsDir() {
+			err = errors.New("Path is not a directory")
+		}
+	}
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	dirPath := joinArchivePath(ix.path, name)
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Directory listing for: %s\n\n", dirPath))
+	for _, child := range ix.children(name) {
+		childName := path.Base(child.name)
+		resourceURI := pathToResourceURI(joinArchivePath(ix.path, child.name))
+		switch {
+		case child.mode.IsDir():
+			result.WriteString(fmt.Sprintf("[DIR]  %s (%s)\n", childName, resourceURI))
+		case child.mode&os.ModeSymlink != 0:
+			result.WriteString(fmt.Sprintf("[LINK] %s -> %s\n", childName, child.link))
+		default:
+			result.WriteString(fmt.Sprintf("[FILE] %s (%s) - %d bytes\n", childName, resourceURI, child.size))
+		}
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: result.String(),
+			},
+			mcp.EmbeddedResource{
+				Type: "resource",
+				Resource: mcp.TextResourceContents{
+					URI:      pathToResourceURI(dirPath),
+					MIMEType: "text/plain",
+					Text:     fmt.Sprintf("Directory: %s", dirPath),
+				},
+			},
+		},
+	}, nil
+}
+
+// archiveTree implements tree for paths inside archives
+func (fs *FilesystemHandler) archiveTree(archivePath, name string, maxDepth int) (*FileNode, error) {
+	ix, err := fs.openArchive(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := ix.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if !entry.mode.IsDir() {
+		return nil, errors.New("The specified path is not a directory")
+	}
+
+	var build func(entry archiveEntry, depth int) *FileNode
+	build = func(entry archiveEntry, depth int) *FileNode {
+		node := &FileNode{
+			Name:     path.Base(entry.name),
+			Path:     joinArchivePath(ix.path, entry.name),
+			Modified: entry.modified,
+		}
+		if entry.name == "" {
+			node.Name = filepath.Base(ix.path)
+		}
+		if !entry.mode.IsDir() {
+			node.Type = "file"
+			node.Size = entry.size
+			return node
+		}
+		node.Type = "directory"
+		if depth < maxDepth {
+			for _, child := range ix.children(entry.name) {
+				node.Children = append(node.Children, build(child, depth+1))
+			}
+		}
+		return node
+	}
+	return build(entry, 0), nil
+}
+
+// readArchiveFile implements read_file for paths inside archives
+func (fs *FilesystemHandler) readArchiveFile(archivePath, name string) (*mcp.CallToolResult, error) {
+	ix, err := fs.openArchive(archivePath)
+	var entry archiveEntry
+	if err == nil {
+		entry, err = ix.lookup(name)
+	}
+	if err == nil {
+		switch {
+		case entry.mode.IsDir():
+			err = fmt.Errorf("%s is a directory inside the archive, use list_directory to browse it", joinArchivePath(ix.path, name))
+		case entry.mode&os.ModeSymlink != 0:
+			err = fmt.Errorf("%s is a symbolic link to %s", joinArchivePath(ix.path, name), entry.link)
+		case !entry.mode.IsRegular():
+			err = fmt.Errorf("%s is not a regular file", joinArchivePath(ix.path, name))
+		case entry.size > fs.limits.MaxInlineSize:
+			err = fmt.Errorf("%s is too large to display inline (%d bytes), extract it with extract_archive", joinArchivePath(ix.path, name), entry.size)
+		}
+	}
+	var content []byte
+	if err == nil {
+		content, err = fs.readArchiveEntry(ix, name)
+	}
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	entryPath := joinArchivePath(ix.path, name)
+	mimeType := archiveMimeType(name, content)
+	size := int64(len(content))
+	if text, enc, ok := decodeFileText(content, mimeType); ok {
+		result := &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: text,
+				},
+			},
+		}
+		if !enc.plain() {
+			result.Content = append(result.Content, mcp.TextContent{
+				Type: "text",
+				Text: fmt.Sprintf("Encoding: %s (transcoded to UTF-8)", enc),
+			})
+		}
+		return result, nil
+	}
+	switch {
+	case size > fs.limits.MaxBase64Size:
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Binary file: %s (%s, %d bytes) is too large to display inline, extract it with extract_archive", entryPath, mimeType, size),
+				},
+			},
+		}, nil
+	case isImageFile(mimeType):
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Image file: %s (%s, %d bytes)", entryPath, mimeType, size),
+				},
+				mcp.ImageContent{
+					Type:     "image",
+					Data:     base64.StdEncoding.EncodeToString(content),
+					MIMEType: mimeType,
+				},
+			},
+		}, nil
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: fmt.Sprintf("Binary file: %s (%s, %d bytes)", entryPath, mimeType, size),
+			},
+			mcp.EmbeddedResource{
+				Type: "resource",
+				Resource: mcp.BlobResourceContents{
+					URI:      pathToResourceURI(entryPath),
+					MIMEType: mimeType,
+					Blob:     base64.StdEncoding.EncodeToString(content),
+				},
+			},
+		},
+	}, nil
+}
+
+// readArchiveResource implements resources/read for URIs inside archives
+func (fs *FilesystemHandler) readArchiveResource(uri, archivePath, name string) ([]mcp.ResourceContents, error) {
+	ix, err := fs.openArchive(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := ix.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.mode.IsDir() {
+		var result strings.Builder
+		result.WriteString(fmt.Sprintf("Directory listing for: %s\n\n", joinArchivePath(ix.path, name)))
+		for _, child := range ix.children(name) {
+			childURI := pathToResourceURI(joinArchivePath(ix.path, child.name))
+			if child.mode.IsDir() {
+				result.WriteString(fmt.Sprintf("[DIR]  %s (%s)\n", path.Base(child.name), childURI))
+			} else {
+				result.WriteString(fmt.Sprintf("[FILE] %s (%s) - %d bytes\n", path.Base(child.name), childURI, child.size))
+			}
+		}
+		return []mcp.ResourceContents{
+			mcp.TextResourceContents{
+				URI:      uri,
+				MIMEType: "text/plain",
+				Text:     result.String(),
+			},
+		}, nil
+	}
+
+	if !entry.mode.IsRegular() {
+		return nil, fmt.Errorf("%s is not a regular file", joinArchivePath(ix.path, name))
+	}
+	if entry.size > fs.limits.MaxInlineSize {
+		return nil, fmt.Errorf("%s is too large to read (%d bytes), extract it with extract_archive", joinArchivePath(ix.path, name), entry.size)
+	}
+	content, err := fs.readArchiveEntry(ix, name)
+	if err != nil {
+		return nil, err
+	}
+	mimeType := archiveMimeType(name, content)
+	if text, enc, ok := decodeFileText(content, mimeType); ok {
+		if !enc.plain() {
+			mimeType = transcodedMimeType(mimeType)
+		}
+		return []mcp.ResourceContents{
+			mcp.TextResourceContents{
+				URI:      uri,
+				MIMEType: mimeType,
+				Text:     text,
+			},
+		}, nil
+	}
+	return []mcp.ResourceContents{
+		mcp.BlobResourceContents{
+			URI:      uri,
+			MIMEType: mimeType,
+			Blob:     base64.StdEncoding.EncodeToString(content),
+		},
+	}, nil
+}
+
+// searchArchive implements search_within_files for paths inside archives
+func (fs *FilesystemHandler) searchArchive(archivePath, dir, substring string, maxDepth, maxResults int) ([]SearchResult, error) {
+	ix, err := fs.openArchive(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := ix.lookup(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !entry.mode.IsDir() {
+		return nil, errors.New("search path must be a directory")
+	}
+
+	var results []SearchResult
+	err = readArchive(fs.backend, ix.path, ix.format, fs.limits.MaxExtractSize, func(entry archiveEntry, r io.Reader) error {
+		name, err := archiveEntryName(archiveEntry{name: entry.name})
+		if err != nil || !entry.mode.IsRegular() || !archiveWithin(name, dir) || name == dir {
+			return nil
+		}
+		// Depth counts the directories between dir and the file
+		rel := strings.TrimPrefix(strings.TrimPrefix(name, dir), "/")
+		if maxDepth > 0 && strings.Count(rel, "/") >= maxDepth {
+			return nil
+		}
+		if entry.size > fs.limits.MaxSearchableSize {
+			return nil
+		}
+		content, err := io.ReadAll(io.LimitReader(r, entry.size))
+		if err != nil || !isTextFile(archiveMimeType(name, content)) {
+			return nil
+		}
+
+		entryPath := joinArchivePath(ix.path, name)
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		lineNum := 0
+		for scanner.Scan() {
+			lineNum++
+			line := scanner.Text()
+			if strings.Contains(line, substring) {
+				results = append(results, SearchResult{
+					FilePath:    entryPath,
+					LineNumber:  lineNum,
+					LineContent: line,
+					ResourceURI: pathToResourceURI(entryPath),
+				})
+				if len(results) >= maxResults {
+					return errArchiveEntryFound
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil && err != errArchiveEntryFound {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitArchivePath(t *testing.T) {
+	tests := []struct {
+		path, archive, name string
+		ok                  bool
+	}{
+		{"/src/bundle.zip!/docs/readme.md", "/src/bundle.zip", "docs/readme.md", true},
+		{"/src/bundle.zip!/", "/src/bundle.zip", "", true},
+		{"/src/bundle.zip!", "/src/bundle.zip", "", true},
+		{"/src/app.JAR!/META-INF/", "/src/app.JAR", "META-INF", true},
+		{"/src/bundle.tar.gz!/a/../b", "/src/bundle.tar.gz", "b", true},
+		{"/src/bundle.zip!/../../etc/passwd", "/src/bundle.zip", "etc/passwd", true},
+		{`C:\src\bundle.zip!\docs\readme.md`, `C:\src\bundle.zip`, "docs/readme.md", true},
+		{"/src/wow!/bundle.zip!/a", "/src/wow!/bundle.zip", "a", true},
+		{"/src/bundle.zip", "", "", false},
+		{"/src/hello!/world.txt", "", "", false},
+		{"/src/bundle.zip!x", "", "", false},
+	}
+	for _, tt := range tests {
+		archive, name, ok := splitArchivePath(tt.path)
+		assert.Equal(t, tt.ok, ok, tt.path)
+		assert.Equal(t, tt.archive, archive, tt.path)
+		assert.Equal(t, tt.name, name, tt.path)
+	}
+}
+
+func writeTestZip(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	zw := zip.NewWriter(f)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	require.NoError(t, f.Close())
+}
+
+func TestArchiveBrowsing(t *testing.T) {
+	tmpDir := t.TempDir()
+	allowedDirs := resolveAllowedDirs(t, tmpDir)
+	fsHandler, err := NewFilesystemHandler(allowedDirs)
+	require.NoError(t, err)
+	dir := allowedDirs[0]
+
+	bundle := filepath.Join(dir, "bundle.zip")
+	writeTestZip(t, bundle, map[string]string{
+		"docs/":               "",
+		"docs/readme.md":      "# Bundle\nversion 1.2.3\n",
+		"docs/guide/intro.md": "see version notes\n",
+		"docs/utf16.txt":      "\xff\xfeh\x00\xe9\x00\n\x00",
+		"bin/tool.exe":        "MZ\x90\x00\x03\x00\x00\x00\x04\x00\x00\x00\xff\xff",
+		"../evil.txt":         "version",
+	})
+	release := filepath.Join(dir, "release.tar")
+	writeTestTar(t, release, tarEntry{name: "release/notes.txt", content: "version 2\n"})
+
+	call := func(t *testing.T, handle func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error), args map[string]any) *mcp.CallToolResult {
+		t.Helper()
+		req := mcp.CallToolRequest{}
+		req.Params.Arguments = args
+		res, err := handle(context.Background(), req)
+		require.NoError(t, err)
+		return res
+	}
+
+	t.Run("list directory", func(t *testing.T) {
+		res := call(t, fsHandler.HandleListDirectory, map[string]any{"path": bundle + "!/"})
+		require.False(t, res.IsError)
+		text := res.Content[0].(mcp.TextContent).Text
+		assert.Contains(t, text, "[DIR]  bin")
+		assert.Contains(t, text, "[DIR]  docs")
+		assert.NotContains(t, text, "evil")
+
+		res = call(t, fsHandler.HandleListDirectory, map[string]any{"path": bundle + "!/docs"})
+		require.False(t, res.IsError)
+		text = res.Content[0].(mcp.TextContent).Text
+		assert.Contains(t, text, "[DIR]  guide")
+		assert.Contains(t, text, "[FILE] readme.md")
+		assert.Contains(t, text, "23 bytes")
+
+		res = call(t, fsHandler.HandleListDirectory, map[string]any{"path": release + "!/release"})
+		require.False(t, res.IsError)
+		assert.Contains(t, res.Content[0].(mcp.TextContent).Text, "[FILE] notes.txt")
+
+		res = call(t, fsHandler.HandleListDirectory, map[string]any{"path": bundle + "!/missing"})
+		assert.True(t, res.IsError)
+		res = call(t, fsHandler.HandleListDirectory, map[string]any{"path": bundle + "!/docs/readme.md"})
+		assert.True(t, res.IsError)
+	})
+
+	t.Run("read file", func(t *testing.T) {
+		res := call(t, fsHandler.HandleReadFile, map[string]any{"path": bundle + "!/docs/readme.md"})
+		require.False(t, res.IsError)
+		assert.Equal(t, "# Bundle\nversion 1.2.3\n", res.Content[0].(mcp.TextContent).Text)
+
+		// Text in other encodings is transcoded like files on disk
+		res = call(t, fsHandler.HandleReadFile, map[string]any{"path": bundle + "!/docs/utf16.txt"})
+		require.False(t, res.IsError)
+		assert.Equal(t, "hé\n", res.Content[0].(mcp.TextContent).Text)
+		assert.Contains(t, res.Content[1].(mcp.TextContent).Text, "utf-16le with BOM")
+
+		res = call(t, fsHandler.HandleReadFile, map[string]any{"path": bundle + "!/bin/tool.exe"})
+		require.False(t, res.IsError)
+		assert.Contains(t, res.Content[0].(mcp.TextContent).Text, "Binary file")
+		require.Len(t, res.Content, 2)
+
+		res = call(t, fsHandler.HandleReadFile, map[string]any{"path": bundle + "!/docs"})
+		assert.True(t, res.IsError)
+		assert.Contains(t, res.Content[0].(mcp.TextContent).Text, "is a directory")
+	})
+
+	t.Run("tree", func(t *testing.T) {
+		res := call(t, fsHandler.HandleTree, map[string]any{"path": bundle + "!/", "depth": float64(1)})
+		require.False(t, res.IsError)
+		text := res.Content[0].(mcp.TextContent).Text
+		assert.Contains(t, text, `"name": "bundle.zip"`)
+		assert.Contains(t, text, `"name": "docs"`)
+		assert.NotContains(t, text, "readme.md")
+
+		res = call(t, fsHandler.HandleTree, map[string]any{"path": bundle + "!/docs"})
+		require.False(t, res.IsError)
+		assert.Contains(t, res.Content[0].(mcp.TextContent).Text, "intro.md")
+	})
+
+	t.Run("search within files", func(t *testing.T) {
+		res := call(t, fsHandler.HandleSearchWithinFiles, map[string]any{"path": bundle + "!/", "substring": "version"})
+		require.False(t, res.IsError)
+		text := res.Content[0].(mcp.TextContent).Text
+		assert.Contains(t, text, "Found 2 occurrences")
+		assert.Contains(t, text, bundle+"!/docs/readme.md")
+		assert.Contains(t, text, "Line 2: version 1.2.3")
+
+		res = call(t, fsHandler.HandleSearchWithinFiles, map[string]any{"path": bundle + "!/docs", "substring": "version", "depth": float64(1)})
+		require.False(t, res.IsError)
+		assert.Contains(t, res.Content[0].(mcp.TextContent).Text, "Found 1 occurrences")
+	})
+
+	t.Run("resource", func(t *testing.T) {
+		req := mcp.ReadResourceRequest{}
+		req.Params.URI = pathToResourceURI(bundle + "!/docs/readme.md")
+		contents, err := fsHandler.HandleReadResource(context.Background(), req)
+		require.NoError(t, err)
+		require.Len(t, contents, 1)
+		assert.Equal(t, "# Bundle\nversion 1.2.3\n", contents[0].(mcp.TextResourceContents).Text)
+
+		req.Params.URI = pathToResourceURI(bundle + "!/docs/utf16.txt")
+		contents, err = fsHandler.HandleReadResource(context.Background(), req)
+		require.NoError(t, err)
+		require.Len(t, contents, 1)
+		text := contents[0].(mcp.TextResourceContents)
+		assert.Equal(t, "hé\n", text.Text)
+		assert.Equal(t, "text/plain; charset=utf-8", text.MIMEType)
+	})
+
+	t.Run("outside allowed directories", func(t *testing.T) {
+		outside := filepath.Join(t.TempDir(), "outside.zip")
+		writeTestZip(t, outside, map[string]string{"secret.txt": "secret"})
+		res := call(t, fsHandler.HandleReadFile, map[string]any{"path": outside + "!/secret.txt"})
+		assert.True(t, res.IsError)
+		assert.NotContains(t, res.Content[0].(mcp.TextContent).Text, "secret\n")
+	})
+}
@@ -0,0 +1,320 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Backend is the storage a FilesystemHandler serves files from. Paths are
+// absolute and in the host's format; the handler checks them against the
+// allowed directories before calling the backend. Errors for missing files
+// must satisfy os.IsNotExist.
+type Backend interface {
+	Stat(name string) (os.FileInfo, error)
+	// Lstat is like Stat but does not follow a symbolic link at name
+	Lstat(name string) (os.FileInfo, error)
+	Open(name string) (File, error)
+	// OpenFile opens a file with the os.O_* flags, creating it with perm
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+	// ReadDir returns the entries of a directory sorted by name
+	ReadDir(name string) ([]os.DirEntry, error)
+	Readlink(name string) (string, error)
+	Mkdir(name string, perm os.FileMode) error
+	Rename(oldpath, newpath string) error
+	// Remove removes a file or an empty directory
+	Remove(name string) error
+	Symlink(oldname, newname string) error
+	Chmod(name string, mode os.FileMode) error
+}
+
+// File is an open file of a Backend
+type File interface {
+	io.Reader
+	io.Writer
+	io.Closer
+	Stat() (os.FileInfo, error)
+}
+
+// ErrReadOnly is returned by backends that cannot be modified
+var ErrReadOnly = errors.New("read-only file system")
+
+// OSBackend serves files from the local disk
+type OSBackend struct{}
+
+func (OSBackend) Stat(name string) (os.FileInfo, error)  { return os.Stat(name) }
+func (OSBackend) Lstat(name string) (os.FileInfo, error) { return os.Lstat(name) }
+func (OSBackend) Open(name string) (File, error)         { return os.Open(name) }
+func (OSBackend) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	return os.OpenFile(name, flag, perm)
+}
+func (OSBackend) ReadDir(name string) ([]os.DirEntry, error) { return os.ReadDir(name) }
+func (OSBackend) Readlink(name string) (string, error)       { return os.Readlink(name) }
+func (OSBackend) Mkdir(name string, perm os.FileMode) error  { return os.Mkdir(name, perm) }
+func (OSBackend) Rename(oldpath, newpath string) error       { return os.Rename(oldpath, newpath) }
+func (OSBackend) Remove(name string) error                   { return os.Remove(name) }
+func (OSBackend) Symlink(oldname, newname string) error      { return os.Symlink(oldname, newname) }
+func (OSBackend) Chmod(name string, mode os.FileMode) error  { return os.Chmod(name, mode) }
+
+// The helpers below use these native implementations when available
+func (OSBackend) MkdirAll(name string, perm os.FileMode) error { return os.MkdirAll(name, perm) }
+func (OSBackend) RemoveAll(name string) error                  { return os.RemoveAll(name) }
+func (OSBackend) EvalSymlinks(path string) (string, error)     { return filepath.EvalSymlinks(path) }
+func (OSBackend) Chtimes(name string, atime, mtime time.Time) error {
+	return os.Chtimes(name, atime, mtime)
+}
+
+// readFile reads the whole file at name
+func readFile(b Backend, name string) ([]byte, error) {
+	f, err := b.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// writeFile writes data to name, creating or truncating it
+func writeFile(b Backend, name string, data []byte, perm os.FileMode) error {
+	f, err := b.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// mkdirAll creates a directory and any missing parents
+func mkdirAll(b Backend, name string, perm os.FileMode) error {
+	if m, ok := b.(interface {
+		MkdirAll(string, os.FileMode) error
+	}); ok {
+		return m.MkdirAll(name, perm)
+	}
+	return createDirs(b, name, perm)
+}
+
+// createDirs creates a directory and any missing parents one by one
+func createDirs(b Backend, name string, perm os.FileMode) error {
+	if info, err := b.Stat(name); err == nil {
+		if info.IsDir() {
+			return nil
+		}
+		return &os.PathError{Op: "mkdir", Path: name, Err: syscall.ENOTDIR}
+	}
+
+	if parent := filepath.Dir(name); parent != name {
+		if err := createDirs(b, parent, perm); err != nil {
+			return err
+		}
+	}
+	if err := b.Mkdir(name, perm); err != nil {
+		// Another caller may have created it in the meantime
+		if info, statErr := b.Lstat(name); statErr == nil && info.IsDir() {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// chtimes sets the modification time of name on backends that support it
+// and does nothing on the others
+func chtimes(b Backend, name string, mtime time.Time) error {
+	if c, ok := b.(interface {
+		Chtimes(string, time.Time, time.Time) error
+	}); ok {
+		return c.Chtimes(name, mtime, mtime)
+	}
+	return nil
+}
+
+// removeAll removes name and everything it contains
+func removeAll(b Backend, name string) error {
+	if r, ok := b.(interface{ RemoveAll(string) error }); ok {
+		return r.RemoveAll(name)
+	}
+
+	info, err := b.Lstat(name)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		entries, err := b.ReadDir(name)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := removeAll(b, filepath.Join(name, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return b.Remove(name)
+}
+
+// copyTree copies the file, directory or symbolic link at src in from to dst
+// in to, keeping permissions
+func copyTree(from Backend, src string, to Backend, dst string) error {
+	info, err := from.Lstat(src)
+	if err != nil {
+		return err
+	}
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := from.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return to.Symlink(target, dst)
+	case info.IsDir():
+		if err := to.Mkdir(dst, info.Mode().Perm()); err != nil {
+			return err
+		}
+		entries, err := from.ReadDir(src)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := copyTree(from, filepath.Join(src, entry.Name()), to, filepath.Join(dst, entry.Name())); err != nil {
+				return err
+			}
+		}
+		return to.Chmod(dst, info.Mode().Perm())
+	}
+
+	in, err := from.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := to.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if err := to.Chmod(dst, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return chtimes(to, dst, info.ModTime())
+}
+
+// walk is filepath.Walk for a backend
+func walk(b Backend, root string, fn filepath.WalkFunc) error {
+	info, err := b.Lstat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkTree(b, root, info, fn)
+	}
+	if err == filepath.SkipDir || err == filepath.SkipAll {
+		return nil
+	}
+	return err
+}
+
+func walkTree(b Backend, path string, info os.FileInfo, fn filepath.WalkFunc) error {
+	if !info.IsDir() {
+		return fn(path, info, nil)
+	}
+
+	entries, err := b.ReadDir(path)
+	err1 := fn(path, info, err)
+	if err != nil || err1 != nil {
+		return err1
+	}
+
+	for _, entry := range entries {
+		name := filepath.Join(path, entry.Name())
+		entryInfo, err := b.Lstat(name)
+		if err != nil {
+			if err := fn(name, entryInfo, err); err != nil && err != filepath.SkipDir {
+				return err
+			}
+			continue
+		}
+		if err := walkTree(b, name, entryInfo, fn); err != nil {
+			if !entryInfo.IsDir() || err != filepath.SkipDir {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// maxSymlinks bounds how many links evalSymlinks follows before giving up
+const maxSymlinks = 255
+
+// evalSymlinks is filepath.EvalSymlinks for an absolute path in a backend
+func evalSymlinks(b Backend, path string) (string, error) {
+	if e, ok := b.(interface {
+		EvalSymlinks(string) (string, error)
+	}); ok {
+		return e.EvalSymlinks(path)
+	}
+
+	sep := string(filepath.Separator)
+	split := func(p string) (string, []string) {
+		p = filepath.Clean(p)
+		volume := filepath.VolumeName(p)
+		return volume + sep, strings.Split(strings.Trim(p[len(volume):], sep), sep)
+	}
+
+	resolved, components := split(path)
+	links := 0
+	for i := 0; i < len(components); i++ {
+		component := components[i]
+		switch component {
+		case "", ".":
+			continue
+		case "..":
+			resolved = filepath.Dir(resolved)
+			continue
+		}
+
+		next := filepath.Join(resolved, component)
+		info, err := b.Lstat(next)
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+
+		links++
+		if links > maxSymlinks {
+			return "", &os.PathError{Op: "evalsymlinks", Path: path, Err: errors.New("too many links")}
+		}
+		target, err := b.Readlink(next)
+		if err != nil {
+			return "", err
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(resolved, target)
+		}
+
+		// Continue with the components of the target followed by the rest
+		var targetComponents []string
+		resolved, targetComponents = split(target)
+		components = append(targetComponents, components[i+1:]...)
+		i = -1
+	}
+	return resolved, nil
+}
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+// FSBackend serves a read-only io/fs.FS, such as an embed.FS or a zip file,
+// as if it were mounted at a directory of the host
+type FSBackend struct {
+	fsys fs.FS
+	root string
+}
+
+// NewFSBackend returns a backend serving fsys at the absolute directory root
+func NewFSBackend(fsys fs.FS, root string) (*FSBackend, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	return &FSBackend{fsys: fsys, root: abs}, nil
+}
+
+// Root returns the directory the file system is mounted at
+func (b *FSBackend) Root() string {
+	return b.root
+}
+
+func (b *FSBackend) Stat(name string) (os.FileInfo, error) {
+	fsPath, err := b.fsPath("stat", name)
+	if err != nil {
+		// The directories above the root exist so that paths can be resolved
+		if abs, absErr := filepath.Abs(name); absErr == nil && isAncestor(abs, b.root) {
+			return &memInfo{name: filepath.Base(abs), mode: os.ModeDir | 0555}, nil
+		}
+		return nil, err
+	}
+	info, err := fs.Stat(b.fsys, fsPath)
+	return info, b.hostError(err, name)
+}
+
+// Lstat is the same as Stat since io/fs has no symbolic links
+func (b *FSBackend) Lstat(name string) (os.FileInfo, error) {
+	return b.Stat(name)
+}
+
+func (b *FSBackend) Open(name string) (File, error) {
+	fsPath, err := b.fsPath("open", name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := b.fsys.Open(fsPath)
+	if err != nil {
+		return nil, b.hostError(err, name)
+	}
+	return readOnlyFile{File: f, name: name}, nil
+}
+
+func (b *FSBackend) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		return nil, &os.PathError{Op: "open", Path: name, Err: ErrReadOnly}
+	}
+	return b.Open(name)
+}
+
+func (b *FSBackend) ReadDir(name string) ([]os.DirEntry, error) {
+	fsPath, err := b.fsPath("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := fs.ReadDir(b.fsys, fsPath)
+	if err != nil {
+		return nil, b.hostError(err, name)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+func (b *FSBackend) Readlink(name string) (string, error) {
+	if _, err := b.Stat(name); err != nil {
+		return "", err
+	}
+	return "", &os.PathError{Op: "readlink", Path: name, Err: syscall.EINVAL}
+}
+
+func (b *FSBackend) Mkdir(name string, perm os.FileMode) error {
+	return &os.PathError{Op: "mkdir", Path: name, Err: ErrReadOnly}
+}
+
+func (b *FSBackend) Rename(oldpath, newpath string) error {
+	return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: ErrReadOnly}
+}
+
+func (b *FSBackend) Remove(name string) error {
+	return &os.PathError{Op: "remove", Path: name, Err: ErrReadOnly}
+}
+
+func (b *FSBackend) Symlink(oldname, newname string) error {
+	return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: ErrReadOnly}
+}
+
+func (b *FSBackend) Chmod(name string, mode os.FileMode) error {
+	return &os.PathError{Op: "chmod", Path: name, Err: ErrReadOnly}
+}
+
+// fsPath converts a host path below the root to a path of the io/fs.FS
+func (b *FSBackend) fsPath(op, name string) (string, error) {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return "", &os.PathError{Op: op, Path: name, Err: err}
+	}
+	rel, err := filepath.Rel(b.root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// isAncestor reports whether dir is a parent directory of path
+func isAncestor(dir, path string) bool {
+	if filepath.Dir(dir) == dir {
+		return path != dir
+	}
+	return strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+// hostError reports err, which names an io/fs path, with the host path name
+func (b *FSBackend) hostError(err error, name string) error {
+	if pathErr, ok := err.(*fs.PathError); ok {
+		return &os.PathError{Op: pathErr.Op, Path: name, Err: pathErr.Err}
+	}
+	return err
+}
+
+// readOnlyFile adapts an fs.File to File
+type readOnlyFile struct {
+	fs.File
+	name string
+}
+
+func (f readOnlyFile) Write(p []byte) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: f.name, Err: ErrReadOnly}
+}
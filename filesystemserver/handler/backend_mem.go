@@ -0,0 +1,430 @@
+package handler
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// MemBackend keeps files in memory. It is useful for tests and for serving
+// scratch space that must not touch the disk. The root directory of every
+// volume always exists.
+type MemBackend struct {
+	mu    sync.RWMutex
+	nodes map[string]*memNode
+}
+
+type memNode struct {
+	mode    os.FileMode
+	data    []byte
+	target  string
+	modTime time.Time
+}
+
+// NewMemBackend returns an empty in-memory backend
+func NewMemBackend() *MemBackend {
+	return &MemBackend{nodes: make(map[string]*memNode)}
+}
+
+// MkdirAll creates a directory and any missing parents
+func (m *MemBackend) MkdirAll(name string, perm os.FileMode) error {
+	return createDirs(m, name, perm)
+}
+
+// WriteFile writes data to name, creating the file and its parents
+func (m *MemBackend) WriteFile(name string, data []byte, perm os.FileMode) error {
+	if err := m.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	return writeFile(m, name, data, perm)
+}
+
+func (m *MemBackend) Stat(name string) (os.FileInfo, error) {
+	real, err := m.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.infoLocked("stat", real)
+}
+
+func (m *MemBackend) Lstat(name string) (os.FileInfo, error) {
+	real, err := m.resolveParent(name)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.infoLocked("lstat", real)
+}
+
+func (m *MemBackend) Open(name string) (File, error) {
+	return m.OpenFile(name, os.O_RDONLY, 0)
+}
+
+func (m *MemBackend) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	real, err := m.resolve(name)
+	if os.IsNotExist(err) && flag&os.O_CREATE != 0 {
+		real, err = m.resolveParent(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	node, ok := m.nodes[real]
+	switch {
+	case ok && flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
+	case ok && node.mode.IsDir() && flag&(os.O_WRONLY|os.O_RDWR) != 0:
+		return nil, &os.PathError{Op: "open", Path: name, Err: syscall.EISDIR}
+	case !ok && m.isRoot(real):
+		node = &memNode{mode: os.ModeDir | 0755}
+	case !ok:
+		if flag&os.O_CREATE == 0 {
+			return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+		}
+		if err := m.checkParentLocked("open", real); err != nil {
+			return nil, err
+		}
+		node = &memNode{mode: perm.Perm(), modTime: time.Now()}
+		m.nodes[real] = node
+	}
+
+	writable := flag&(os.O_WRONLY|os.O_RDWR) != 0
+	if writable && flag&os.O_TRUNC != 0 {
+		node.data = nil
+		node.modTime = time.Now()
+	}
+	return &memFile{
+		backend:  m,
+		path:     real,
+		node:     node,
+		reader:   bytes.NewReader(bytes.Clone(node.data)),
+		writable: writable,
+	}, nil
+}
+
+func (m *MemBackend) ReadDir(name string) ([]os.DirEntry, error) {
+	real, err := m.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	info, err := m.infoLocked("readdir", real)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, &os.PathError{Op: "readdir", Path: name, Err: syscall.ENOTDIR}
+	}
+
+	var entries []os.DirEntry
+	for path := range m.nodes {
+		if filepath.Dir(path) == real && path != real {
+			childInfo, _ := m.infoLocked("readdir", path)
+			entries = append(entries, fs.FileInfoToDirEntry(childInfo))
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+func (m *MemBackend) Readlink(name string) (string, error) {
+	real, err := m.resolveParent(name)
+	if err != nil {
+		return "", err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	node, ok := m.nodes[real]
+	if !ok {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: os.ErrNotExist}
+	}
+	if node.mode&os.ModeSymlink == 0 {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: syscall.EINVAL}
+	}
+	return node.target, nil
+}
+
+func (m *MemBackend) Mkdir(name string, perm os.FileMode) error {
+	return m.create("mkdir", name, &memNode{mode: os.ModeDir | perm.Perm(), modTime: time.Now()})
+}
+
+func (m *MemBackend) Symlink(oldname, newname string) error {
+	return m.create("symlink", newname, &memNode{mode: os.ModeSymlink | 0777, target: oldname, modTime: time.Now()})
+}
+
+func (m *MemBackend) Rename(oldpath, newpath string) error {
+	src, err := m.resolveParent(oldpath)
+	if err != nil {
+		return err
+	}
+	dst, err := m.resolveParent(newpath)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	node, ok := m.nodes[src]
+	if !ok {
+		return &os.PathError{Op: "rename", Path: oldpath, Err: os.ErrNotExist}
+	}
+	if src == dst {
+		return nil
+	}
+	if node.mode.IsDir() && strings.HasPrefix(dst, src+string(filepath.Separator)) {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EINVAL}
+	}
+	if err := m.checkParentLocked("rename", dst); err != nil {
+		return err
+	}
+	if existing, ok := m.nodes[dst]; ok {
+		if existing.mode.IsDir() != node.mode.IsDir() || m.hasChildrenLocked(dst) {
+			return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: os.ErrExist}
+		}
+	}
+
+	// Move the node and everything below it
+	prefix := src + string(filepath.Separator)
+	for path, child := range m.nodes {
+		if strings.HasPrefix(path, prefix) {
+			delete(m.nodes, path)
+			m.nodes[dst+string(filepath.Separator)+path[len(prefix):]] = child
+		}
+	}
+	delete(m.nodes, src)
+	m.nodes[dst] = node
+	return nil
+}
+
+func (m *MemBackend) Remove(name string) error {
+	real, err := m.resolveParent(name)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.nodes[real]; !ok {
+		return &os.PathError{Op: "remove", Path: name, Err: os.ErrNotExist}
+	}
+	if m.hasChildrenLocked(real) {
+		return &os.PathError{Op: "remove", Path: name, Err: syscall.ENOTEMPTY}
+	}
+	delete(m.nodes, real)
+	return nil
+}
+
+func (m *MemBackend) Chmod(name string, mode os.FileMode) error {
+	real, err := m.resolve(name)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	node, ok := m.nodes[real]
+	if !ok {
+		return &os.PathError{Op: "chmod", Path: name, Err: os.ErrNotExist}
+	}
+	node.mode = node.mode&os.ModeType | mode.Perm()
+	return nil
+}
+
+// create adds a new node at name, whose parent must be an existing directory
+func (m *MemBackend) create(op, name string, node *memNode) error {
+	real, err := m.resolveParent(name)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.nodes[real]; ok || m.isRoot(real) {
+		return &os.PathError{Op: op, Path: name, Err: os.ErrExist}
+	}
+	if err := m.checkParentLocked(op, real); err != nil {
+		return err
+	}
+	m.nodes[real] = node
+	return nil
+}
+
+// resolve returns the path of name with all symbolic links followed
+func (m *MemBackend) resolve(name string) (string, error) {
+	path, err := memPath(name)
+	if err != nil {
+		return "", err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return evalSymlinks(memLinkReader{m}, path)
+}
+
+// resolveParent returns the path of name with the links in its parent
+// followed, so that it refers to name itself even if it is a link
+func (m *MemBackend) resolveParent(name string) (string, error) {
+	path, err := memPath(name)
+	if err != nil {
+		return "", err
+	}
+	parent := filepath.Dir(path)
+	if parent == path {
+		return path, nil
+	}
+	realParent, err := m.resolve(parent)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(realParent, filepath.Base(path)), nil
+}
+
+func (m *MemBackend) infoLocked(op, path string) (os.FileInfo, error) {
+	node, ok := m.nodes[path]
+	if !ok {
+		if m.isRoot(path) {
+			return &memInfo{name: path, mode: os.ModeDir | 0755}, nil
+		}
+		return nil, &os.PathError{Op: op, Path: path, Err: os.ErrNotExist}
+	}
+	return &memInfo{
+		name:    filepath.Base(path),
+		size:    int64(len(node.data)),
+		mode:    node.mode,
+		modTime: node.modTime,
+	}, nil
+}
+
+func (m *MemBackend) checkParentLocked(op, path string) error {
+	parent := filepath.Dir(path)
+	if m.isRoot(parent) {
+		return nil
+	}
+	node, ok := m.nodes[parent]
+	if !ok {
+		return &os.PathError{Op: op, Path: path, Err: os.ErrNotExist}
+	}
+	if !node.mode.IsDir() {
+		return &os.PathError{Op: op, Path: path, Err: syscall.ENOTDIR}
+	}
+	return nil
+}
+
+func (m *MemBackend) hasChildrenLocked(path string) bool {
+	prefix := path + string(filepath.Separator)
+	for p := range m.nodes {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MemBackend) isRoot(path string) bool {
+	return filepath.Dir(path) == path
+}
+
+// memPath returns the absolute, clean form of name
+func memPath(name string) (string, error) {
+	path, err := filepath.Abs(name)
+	if err != nil {
+		return "", &os.PathError{Op: "abs", Path: name, Err: err}
+	}
+	return path, nil
+}
+
+// memLinkReader looks up nodes for evalSymlinks while the backend's lock is
+// held
+type memLinkReader struct {
+	*MemBackend
+}
+
+func (r memLinkReader) Lstat(name string) (os.FileInfo, error) {
+	return r.infoLocked("lstat", name)
+}
+
+func (r memLinkReader) Readlink(name string) (string, error) {
+	node, ok := r.nodes[name]
+	if !ok || node.mode&os.ModeSymlink == 0 {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: syscall.EINVAL}
+	}
+	return node.target, nil
+}
+
+// memInfo describes a node of a MemBackend
+type memInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (i *memInfo) Name() string       { return i.name }
+func (i *memInfo) Size() int64        { return i.size }
+func (i *memInfo) Mode() os.FileMode  { return i.mode }
+func (i *memInfo) ModTime() time.Time { return i.modTime }
+func (i *memInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i *memInfo) Sys() any           { return nil }
+
+// memFile is an open file of a MemBackend. Reads see the content at the time
+// the file was opened; writes append to the file.
+type memFile struct {
+	backend  *MemBackend
+	path     string
+	node     *memNode
+	reader   *bytes.Reader
+	writable bool
+}
+
+func (f *memFile) Read(p []byte) (int, error) {
+	if f.node.mode.IsDir() {
+		return 0, &os.PathError{Op: "read", Path: f.path, Err: syscall.EISDIR}
+	}
+	return f.reader.Read(p)
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	if !f.writable {
+		return 0, &os.PathError{Op: "write", Path: f.path, Err: os.ErrPermission}
+	}
+	f.backend.mu.Lock()
+	defer f.backend.mu.Unlock()
+	f.node.data = append(f.node.data, p...)
+	f.node.modTime = time.Now()
+	return len(p), nil
+}
+
+func (f *memFile) Close() error {
+	return nil
+}
+
+func (f *memFile) Stat() (os.FileInfo, error) {
+	f.backend.mu.RLock()
+	defer f.backend.mu.RUnlock()
+	return &memInfo{
+		name:    filepath.Base(f.path),
+		size:    int64(len(f.node.data)),
+		mode:    f.node.mode,
+		modTime: f.node.modTime,
+	}, nil
+}
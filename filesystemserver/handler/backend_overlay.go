@@ -0,0 +1,737 @@
+package handler
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+const (
+	// Prefix of the files in the upper layer that mark a lower entry as deleted
+	overlayWhiteoutPrefix = ".wh."
+	// File in an upper directory that hides the lower directory's entries
+	overlayOpaqueMarker = ".wh..wh..opq"
+	// Directory of the overlay holding the upper layer
+	overlayUpperDir = "upper"
+)
+
+// OverlayBackend is a copy-on-write view of a lower backend, like overlayfs.
+// The lower layer is never modified: written files are copied into an upper
+// directory on the local disk and deletions are recorded as whiteout files.
+// The changes can be listed, committed to the lower layer or discarded.
+type OverlayBackend struct {
+	lower Backend
+	upper string
+	mu    sync.Mutex
+}
+
+// NewOverlayBackend returns an overlay on top of lower that keeps its changes
+// in dir
+func NewOverlayBackend(lower Backend, dir string) (*OverlayBackend, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	upper := filepath.Join(abs, overlayUpperDir)
+	if err := os.MkdirAll(upper, 0700); err != nil {
+		return nil, err
+	}
+	return &OverlayBackend{lower: lower, upper: upper}, nil
+}
+
+func (o *OverlayBackend) Stat(name string) (os.FileInfo, error) {
+	path, err := o.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	return o.Lstat(path)
+}
+
+func (o *OverlayBackend) Lstat(name string) (os.FileInfo, error) {
+	path, err := filepath.Abs(name)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(filepath.Base(path), overlayWhiteoutPrefix) {
+		return nil, &os.PathError{Op: "lstat", Path: name, Err: os.ErrNotExist}
+	}
+	info, err := os.Lstat(o.upperPath(path))
+	if err == nil || !os.IsNotExist(err) {
+		return info, err
+	}
+	if o.hidden(path) {
+		return nil, &os.PathError{Op: "lstat", Path: name, Err: os.ErrNotExist}
+	}
+	return o.lower.Lstat(path)
+}
+
+func (o *OverlayBackend) Open(name string) (File, error) {
+	path, err := o.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(o.upperPath(path))
+	if err == nil || !os.IsNotExist(err) {
+		return file, err
+	}
+	if o.hidden(path) {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return o.lower.Open(path)
+}
+
+func (o *OverlayBackend) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) == 0 {
+		return o.Open(name)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	path, err := o.resolve(name)
+	if os.IsNotExist(err) && flag&os.O_CREATE != 0 {
+		path, err = o.resolveParent(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := o.Lstat(path)
+	switch {
+	case err == nil && flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
+	case err == nil && info.IsDir():
+		return nil, &os.PathError{Op: "open", Path: name, Err: syscall.EISDIR}
+	case err == nil && info.Mode()&os.ModeSymlink != 0:
+		// A dangling link; writing through it would leave the overlay
+		return nil, &os.PathError{Op: "open", Path: name, Err: syscall.EINVAL}
+	case err == nil:
+		// Copy the lower file up unless it is about to be truncated anyway
+		if flag&os.O_TRUNC == 0 {
+			err = o.copyUp(path)
+		} else {
+			err = o.copyUpParents(path)
+		}
+		if err != nil {
+			return nil, err
+		}
+		flag |= os.O_CREATE
+		perm = info.Mode().Perm()
+	case os.IsNotExist(err) && flag&os.O_CREATE != 0:
+		if _, err := o.prepareCreate(path); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+	return os.OpenFile(o.upperPath(path), flag, perm)
+}
+
+func (o *OverlayBackend) ReadDir(name string) ([]os.DirEntry, error) {
+	path, err := o.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := o.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, &os.PathError{Op: "readdir", Path: name, Err: syscall.ENOTDIR}
+	}
+
+	entries := make(map[string]os.DirEntry)
+	whiteouts := make(map[string]bool)
+	opaque := false
+	upperEntries, err := os.ReadDir(o.upperPath(path))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, entry := range upperEntries {
+		switch {
+		case entry.Name() == overlayOpaqueMarker:
+			opaque = true
+		case strings.HasPrefix(entry.Name(), overlayWhiteoutPrefix):
+			whiteouts[strings.TrimPrefix(entry.Name(), overlayWhiteoutPrefix)] = true
+		default:
+			entries[entry.Name()] = entry
+		}
+	}
+
+	if !opaque && !o.hidden(path) {
+		lowerEntries, err := o.lower.ReadDir(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for _, entry := range lowerEntries {
+			if _, ok := entries[entry.Name()]; !ok && !whiteouts[entry.Name()] {
+				entries[entry.Name()] = entry
+			}
+		}
+	}
+
+	result := make([]os.DirEntry, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, entry)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name() < result[j].Name()
+	})
+	return result, nil
+}
+
+func (o *OverlayBackend) Readlink(name string) (string, error) {
+	path, err := o.resolveParent(name)
+	if err != nil {
+		return "", err
+	}
+	target, err := os.Readlink(o.upperPath(path))
+	if err == nil || !os.IsNotExist(err) {
+		return target, err
+	}
+	if o.hidden(path) {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: os.ErrNotExist}
+	}
+	return o.lower.Readlink(path)
+}
+
+func (o *OverlayBackend) Mkdir(name string, perm os.FileMode) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	path, err := o.resolveParent(name)
+	if err != nil {
+		return err
+	}
+	if _, err := o.Lstat(path); err == nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrExist}
+	}
+	replaced, err := o.prepareCreate(path)
+	if err != nil {
+		return err
+	}
+	if err := os.Mkdir(o.upperPath(path), perm); err != nil {
+		return err
+	}
+	if replaced {
+		return o.markOpaque(path)
+	}
+	return nil
+}
+
+func (o *OverlayBackend) Symlink(oldname, newname string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	path, err := o.resolveParent(newname)
+	if err != nil {
+		return err
+	}
+	if _, err := o.Lstat(path); err == nil {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: os.ErrExist}
+	}
+	if _, err := o.prepareCreate(path); err != nil {
+		return err
+	}
+	return os.Symlink(oldname, o.upperPath(path))
+}
+
+func (o *OverlayBackend) Rename(oldpath, newpath string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	src, err := o.resolveParent(oldpath)
+	if err != nil {
+		return err
+	}
+	dst, err := o.resolveParent(newpath)
+	if err != nil {
+		return err
+	}
+	info, err := o.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if src == dst {
+		return nil
+	}
+	if info.IsDir() && strings.HasPrefix(dst, src+string(filepath.Separator)) {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EINVAL}
+	}
+
+	// Replace the destination like os.Rename does
+	if existing, err := o.Lstat(dst); err == nil {
+		if existing.IsDir() != info.IsDir() {
+			return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: os.ErrExist}
+		}
+		if err := o.remove(dst); err != nil {
+			return err
+		}
+	}
+	replaced, err := o.prepareCreate(dst)
+	if err != nil {
+		return err
+	}
+
+	// Entries only in the upper layer can simply be moved
+	if _, err := o.lower.Lstat(src); os.IsNotExist(err) || o.hidden(src) {
+		err = os.Rename(o.upperPath(src), o.upperPath(dst))
+	} else if err = o.copyTree(src, dst); err == nil {
+		err = o.removeTree(src)
+	}
+	if err == nil && replaced && info.IsDir() {
+		err = o.markOpaque(dst)
+	}
+	return err
+}
+
+func (o *OverlayBackend) Remove(name string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	path, err := o.resolveParent(name)
+	if err != nil {
+		return err
+	}
+	return o.remove(path)
+}
+
+func (o *OverlayBackend) Chmod(name string, mode os.FileMode) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	path, err := o.resolve(name)
+	if err != nil {
+		return err
+	}
+	if err := o.copyUp(path); err != nil {
+		return err
+	}
+	return os.Chmod(o.upperPath(path), mode)
+}
+
+// Changes lists the differences between the overlay and its lower layer,
+// sorted by path
+func (o *OverlayBackend) Changes() ([]OverlayChange, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.changes()
+}
+
+// Commit applies the changes to the lower layer and empties the overlay
+func (o *OverlayBackend) Commit() ([]OverlayChange, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	changes, err := o.changes()
+	if err != nil {
+		return nil, err
+	}
+	// Parents sort before their children, so directories exist when the
+	// files in them are written
+	for _, change := range changes {
+		if err := o.commitChange(change); err != nil {
+			return nil, err
+		}
+	}
+	return changes, o.discard()
+}
+
+// Discard throws away all changes
+func (o *OverlayBackend) Discard() ([]OverlayChange, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	changes, err := o.changes()
+	if err != nil {
+		return nil, err
+	}
+	return changes, o.discard()
+}
+
+func (o *OverlayBackend) changes() ([]OverlayChange, error) {
+	var changes []OverlayChange
+	err := filepath.Walk(o.upper, func(upperPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if upperPath == o.upper || filepath.Base(upperPath) == overlayOpaqueMarker {
+			return nil
+		}
+		path := o.hostPath(upperPath)
+
+		if name := filepath.Base(path); strings.HasPrefix(name, overlayWhiteoutPrefix) {
+			deleted := filepath.Join(filepath.Dir(path), strings.TrimPrefix(name, overlayWhiteoutPrefix))
+			lowerInfo, err := o.lower.Lstat(deleted)
+			if err != nil {
+				return nil
+			}
+			changes = append(changes, OverlayChange{Path: deleted, Status: "removed", Type: overlayEntryType(lowerInfo)})
+			return nil
+		}
+
+		change := OverlayChange{Path: path, Status: "added", Type: overlayEntryType(info)}
+		lowerInfo, err := o.lower.Lstat(path)
+		if err == nil {
+			change.Status = "modified"
+			// Directories copied up to hold changed files are not changes themselves
+			if info.IsDir() && lowerInfo.IsDir() && info.Mode().Perm() == lowerInfo.Mode().Perm() && !o.opaque(upperPath) {
+				return nil
+			}
+		}
+		changes = append(changes, change)
+
+		// Entries of the lower directory hidden by an opaque directory are removed
+		if info.IsDir() && lowerInfo != nil && lowerInfo.IsDir() && o.opaque(upperPath) {
+			lowerEntries, err := o.lower.ReadDir(path)
+			if err != nil {
+				return err
+			}
+			for _, entry := range lowerEntries {
+				if _, err := os.Lstat(filepath.Join(upperPath, entry.Name())); os.IsNotExist(err) {
+					entryInfo, err := entry.Info()
+					if err != nil {
+						return err
+					}
+					changes = append(changes, OverlayChange{
+						Path:   filepath.Join(path, entry.Name()),
+						Status: "removed",
+						Type:   overlayEntryType(entryInfo),
+					})
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes, nil
+}
+
+// commitChange applies a single change to the lower layer
+func (o *OverlayBackend) commitChange(change OverlayChange) error {
+	if change.Status == "removed" {
+		return removeAll(o.lower, change.Path)
+	}
+
+	upperPath := o.upperPath(change.Path)
+	info, err := os.Lstat(upperPath)
+	if err != nil {
+		return err
+	}
+	// Replace entries of a different type
+	if lowerInfo, err := o.lower.Lstat(change.Path); err == nil && overlayEntryType(lowerInfo) != change.Type {
+		if err := removeAll(o.lower, change.Path); err != nil {
+			return err
+		}
+	}
+
+	switch change.Type {
+	case checkpointTypeDir:
+		if err := mkdirAll(o.lower, change.Path, info.Mode().Perm()); err != nil {
+			return err
+		}
+		return o.lower.Chmod(change.Path, info.Mode().Perm())
+	case checkpointTypeSymlink:
+		target, err := os.Readlink(upperPath)
+		if err != nil {
+			return err
+		}
+		if err := removeAll(o.lower, change.Path); err != nil {
+			return err
+		}
+		return o.lower.Symlink(target, change.Path)
+	default:
+		src, err := os.Open(upperPath)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		dst, err := o.lower.OpenFile(change.Path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(dst, src); err != nil {
+			dst.Close()
+			return err
+		}
+		if err := dst.Close(); err != nil {
+			return err
+		}
+		return o.lower.Chmod(change.Path, info.Mode().Perm())
+	}
+}
+
+// discard empties the upper layer
+func (o *OverlayBackend) discard() error {
+	if err := os.RemoveAll(o.upper); err != nil {
+		return err
+	}
+	return os.MkdirAll(o.upper, 0700)
+}
+
+// remove deletes an entry, recording a whiteout if the lower layer has it
+func (o *OverlayBackend) remove(path string) error {
+	info, err := o.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		entries, err := o.ReadDir(path)
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			return &os.PathError{Op: "remove", Path: path, Err: syscall.ENOTEMPTY}
+		}
+	}
+
+	// An upper directory may still hold whiteouts
+	if err := os.RemoveAll(o.upperPath(path)); err != nil {
+		return err
+	}
+	if _, err := o.lower.Lstat(path); err == nil && !o.hidden(path) {
+		if err := o.copyUpParents(path); err != nil {
+			return err
+		}
+		return os.WriteFile(o.whiteoutPath(path), nil, 0600)
+	}
+	return nil
+}
+
+// removeTree removes path and everything below it
+func (o *OverlayBackend) removeTree(path string) error {
+	info, err := o.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		entries, err := o.ReadDir(path)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := o.removeTree(filepath.Join(path, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return o.remove(path)
+}
+
+// copyTree copies the merged view of src to dst in the upper layer
+func (o *OverlayBackend) copyTree(src, dst string) error {
+	info, err := o.Lstat(src)
+	if err != nil {
+		return err
+	}
+	dstPath := o.upperPath(dst)
+
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := o.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, dstPath)
+	case info.IsDir():
+		if err := os.Mkdir(dstPath, info.Mode().Perm()|0700); err != nil {
+			return err
+		}
+		entries, err := o.ReadDir(src)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := o.copyTree(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name())); err != nil {
+				return err
+			}
+		}
+		return os.Chmod(dstPath, info.Mode().Perm())
+	default:
+		return o.copyFileUp(src, dstPath, info.Mode().Perm())
+	}
+}
+
+// copyUp makes sure path exists in the upper layer, copying it from the
+// lower layer if needed
+func (o *OverlayBackend) copyUp(path string) error {
+	upperPath := o.upperPath(path)
+	if _, err := os.Lstat(upperPath); err == nil {
+		return nil
+	}
+	if err := o.copyUpParents(path); err != nil {
+		return err
+	}
+
+	info, err := o.lower.Lstat(path)
+	if err != nil {
+		return err
+	}
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := o.lower.Readlink(path)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, upperPath)
+	case info.IsDir():
+		if err := os.Mkdir(upperPath, 0700); err != nil {
+			return err
+		}
+		// Set the mode explicitly so that the umask does not change it
+		return os.Chmod(upperPath, info.Mode().Perm())
+	default:
+		return o.copyFileUp(path, upperPath, info.Mode().Perm())
+	}
+}
+
+// copyUpParents copies the parent directories of path to the upper layer
+func (o *OverlayBackend) copyUpParents(path string) error {
+	parent := filepath.Dir(path)
+	if parent == path {
+		return nil
+	}
+	return o.copyUp(parent)
+}
+
+// copyFileUp copies the merged view of the file src to upperPath
+func (o *OverlayBackend) copyFileUp(src, upperPath string, perm os.FileMode) error {
+	file, err := o.Open(src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	dst, err := os.OpenFile(upperPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, file); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// prepareCreate checks that a new entry can be created at path and makes room
+// for it in the upper layer. It reports whether the entry replaces a deleted
+// one, in which case a new directory must hide the old contents.
+func (o *OverlayBackend) prepareCreate(path string) (bool, error) {
+	info, err := o.Stat(filepath.Dir(path))
+	if err != nil {
+		return false, err
+	}
+	if !info.IsDir() {
+		return false, &os.PathError{Op: "create", Path: path, Err: syscall.ENOTDIR}
+	}
+	if err := o.copyUpParents(path); err != nil {
+		return false, err
+	}
+
+	err = os.Remove(o.whiteoutPath(path))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// markOpaque hides the entries of the lower directory at path
+func (o *OverlayBackend) markOpaque(path string) error {
+	return os.WriteFile(filepath.Join(o.upperPath(path), overlayOpaqueMarker), nil, 0600)
+}
+
+// opaque reports whether the upper directory hides the lower one
+func (o *OverlayBackend) opaque(upperPath string) bool {
+	_, err := os.Lstat(filepath.Join(upperPath, overlayOpaqueMarker))
+	return err == nil
+}
+
+// hidden reports whether the lower entry at path is deleted in the overlay,
+// because it or one of its parents was removed or replaced
+func (o *OverlayBackend) hidden(path string) bool {
+	for {
+		parent := filepath.Dir(path)
+		if parent == path {
+			return false
+		}
+		if _, err := os.Lstat(o.whiteoutPath(path)); err == nil {
+			return true
+		}
+		if o.opaque(o.upperPath(parent)) {
+			return true
+		}
+		path = parent
+	}
+}
+
+// upperPath returns where path is kept in the upper layer
+func (o *OverlayBackend) upperPath(path string) string {
+	volume := filepath.VolumeName(path)
+	return filepath.Join(o.upper, strings.TrimSuffix(volume, ":"), path[len(volume):])
+}
+
+// hostPath is the inverse of upperPath
+func (o *OverlayBackend) hostPath(upperPath string) string {
+	rel, _ := filepath.Rel(o.upper, upperPath)
+	sep := string(filepath.Separator)
+	if runtime.GOOS == "windows" {
+		volume, rest, _ := strings.Cut(rel, sep)
+		return volume + ":" + sep + rest
+	}
+	return sep + rel
+}
+
+// whiteoutPath returns the whiteout file that marks path as deleted
+func (o *OverlayBackend) whiteoutPath(path string) string {
+	return filepath.Join(o.upperPath(filepath.Dir(path)), overlayWhiteoutPrefix+filepath.Base(path))
+}
+
+// resolve returns the absolute path of name with all symbolic links followed
+func (o *OverlayBackend) resolve(name string) (string, error) {
+	path, err := filepath.Abs(name)
+	if err != nil {
+		return "", err
+	}
+	return evalSymlinks(o, path)
+}
+
+// resolveParent returns the absolute path of name with the links in its
+// parent followed
+func (o *OverlayBackend) resolveParent(name string) (string, error) {
+	path, err := filepath.Abs(name)
+	if err != nil {
+		return "", err
+	}
+	parent := filepath.Dir(path)
+	if parent == path {
+		return path, nil
+	}
+	realParent, err := evalSymlinks(o, parent)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(realParent, filepath.Base(path)), nil
+}
+
+// overlayEntryType names the type of entry info describes
+func overlayEntryType(info os.FileInfo) string {
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		return checkpointTypeSymlink
+	case info.IsDir():
+		return checkpointTypeDir
+	default:
+		return checkpointTypeFile
+	}
+}
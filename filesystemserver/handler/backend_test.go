@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// callTool calls a handler function with arguments
+func callTool(
+	t *testing.T,
+	handle func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error),
+	arguments map[string]any,
+) *mcp.CallToolResult {
+	t.Helper()
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = arguments
+	result, err := handle(context.Background(), request)
+	require.NoError(t, err)
+	return result
+}
+
+func TestMemBackend(t *testing.T) {
+	// A root that does not exist on the local disk
+	root := filepath.Join(t.TempDir(), "absent")
+	b := NewMemBackend()
+	require.NoError(t, b.WriteFile(filepath.Join(root, "src", "main.go"), []byte("package main"), 0644))
+	require.NoError(t, b.WriteFile(filepath.Join(root, "README.md"), []byte("# readme"), 0644))
+
+	t.Run("read and list", func(t *testing.T) {
+		data, err := readFile(b, filepath.Join(root, "src", "main.go"))
+		require.NoError(t, err)
+		assert.Equal(t, "package main", string(data))
+
+		entries, err := b.ReadDir(root)
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "README.md", entries[0].Name())
+		assert.Equal(t, "src", entries[1].Name())
+		assert.True(t, entries[1].IsDir())
+
+		_, err = b.Stat(filepath.Join(root, "missing"))
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("symlinks", func(t *testing.T) {
+		link := filepath.Join(root, "code")
+		require.NoError(t, b.Symlink("src", link))
+
+		info, err := b.Lstat(link)
+		require.NoError(t, err)
+		assert.NotZero(t, info.Mode()&os.ModeSymlink)
+
+		resolved, err := evalSymlinks(b, filepath.Join(link, "main.go"))
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(root, "src", "main.go"), resolved)
+
+		require.NoError(t, b.Remove(link))
+		_, err = b.Stat(filepath.Join(root, "src"))
+		assert.NoError(t, err, "removing a link keeps its target")
+	})
+
+	t.Run("rename and remove", func(t *testing.T) {
+		require.NoError(t, b.Rename(filepath.Join(root, "src"), filepath.Join(root, "lib")))
+		_, err := b.Stat(filepath.Join(root, "lib", "main.go"))
+		assert.NoError(t, err)
+
+		assert.Error(t, b.Remove(filepath.Join(root, "lib")), "directory is not empty")
+		require.NoError(t, removeAll(b, filepath.Join(root, "lib")))
+		_, err = b.Stat(filepath.Join(root, "lib", "main.go"))
+		assert.True(t, os.IsNotExist(err))
+	})
+}
+
+func TestFSBackend(t *testing.T) {
+	root, err := filepath.Abs("/site")
+	require.NoError(t, err)
+	b, err := NewFSBackend(fstest.MapFS{
+		"index.html":     {Data: []byte("<h1>hello</h1>"), Mode: 0644},
+		"assets/app.css": {Data: []byte("body {}"), Mode: 0644},
+	}, root)
+	require.NoError(t, err)
+
+	data, err := readFile(b, filepath.Join(root, "assets", "app.css"))
+	require.NoError(t, err)
+	assert.Equal(t, "body {}", string(data))
+
+	entries, err := b.ReadDir(root)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "assets", entries[0].Name())
+
+	// The parents of the root exist so that paths resolve
+	info, err := b.Stat(filepath.Dir(root))
+	require.NoError(t, err)
+	assert.True(t, info.IsDir())
+	_, err = b.Stat(filepath.Join(filepath.Dir(root), "other"))
+	assert.True(t, os.IsNotExist(err))
+
+	err = writeFile(b, filepath.Join(root, "index.html"), []byte("changed"), 0644)
+	assert.True(t, errors.Is(err, ErrReadOnly))
+	assert.True(t, errors.Is(b.Remove(filepath.Join(root, "index.html")), ErrReadOnly))
+
+	// Served by a handler, files can be read but not written
+	handler, err := NewFilesystemHandlerWithBackend([]string{root}, b)
+	require.NoError(t, err)
+	result := callTool(t, handler.HandleReadFile, map[string]any{"path": filepath.Join(root, "index.html")})
+	require.False(t, result.IsError, result.Content)
+	assert.Equal(t, "<h1>hello</h1>", result.Content[0].(mcp.TextContent).Text)
+
+	result = callTool(t, handler.HandleWriteFile, map[string]any{"path": filepath.Join(root, "index.html"), "content": "x"})
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.Content[0].(mcp.TextContent).Text, "read-only")
+}
+
+func TestHandlerWithMemBackend(t *testing.T) {
+	// A root that does not exist on the local disk
+	root := filepath.Join(t.TempDir(), "absent")
+	b := NewMemBackend()
+	require.NoError(t, b.MkdirAll(root, 0755))
+
+	require.NoError(t, b.MkdirAll(filepath.Join(root, "docs"), 0755))
+	require.NoError(t, b.MkdirAll(filepath.Join(root, "backup"), 0755))
+
+	handler, err := NewFilesystemHandlerWithBackend([]string{root}, b)
+	require.NoError(t, err)
+	notes := filepath.Join(root, "docs", "notes.txt")
+
+	result := callTool(t, handler.HandleWriteFile, map[string]any{"path": notes, "content": "remember the milk"})
+	require.False(t, result.IsError, result.Content)
+	data, err := readFile(b, notes)
+	require.NoError(t, err)
+	assert.Equal(t, "remember the milk", string(data))
+
+	result = callTool(t, handler.HandleReadFile, map[string]any{"path": notes})
+	require.False(t, result.IsError)
+	assert.Equal(t, "remember the milk", result.Content[0].(mcp.TextContent).Text)
+
+	result = callTool(t, handler.HandleModifyFile, map[string]any{"path": notes, "find": "milk", "replace": "eggs"})
+	require.False(t, result.IsError, result.Content)
+
+	result = callTool(t, handler.HandleSearchWithinFiles, map[string]any{"path": root, "substring": "eggs"})
+	require.False(t, result.IsError, result.Content)
+	assert.Contains(t, result.Content[0].(mcp.TextContent).Text, notes)
+
+	copied := filepath.Join(root, "backup", "notes.txt")
+	result = callTool(t, handler.HandleCopyFile, map[string]any{"source": notes, "destination": copied})
+	require.False(t, result.IsError, result.Content)
+
+	result = callTool(t, handler.HandleTree, map[string]any{"path": root})
+	require.False(t, result.IsError, result.Content)
+	assert.Contains(t, result.Content[0].(mcp.TextContent).Text, "backup")
+
+	result = callTool(t, handler.HandleDeleteFile, map[string]any{"path": filepath.Join(root, "docs"), "recursive": true})
+	require.False(t, result.IsError, result.Content)
+	_, err = b.Stat(notes)
+	assert.True(t, os.IsNotExist(err))
+
+	result = callTool(t, handler.HandleGetFileInfo, map[string]any{"path": copied})
+	require.False(t, result.IsError, result.Content)
+
+	// Nothing was written to the local disk
+	_, err = os.Stat(root)
+	assert.True(t, os.IsNotExist(err))
+
+	// The trash keeps its data on the local disk
+	assert.Error(t, handler.EnableTrash(t.TempDir(), 0))
+}
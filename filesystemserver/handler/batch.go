@@ -0,0 +1,284 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// batchOperation describes one operation type accepted by the batch tool
+type batchOperation struct {
+	// Arguments that must be present, as strings
+	required []string
+	// Arguments holding paths that are checked before anything runs
+	paths  []string
+	handle func(*FilesystemHandler, context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error)
+}
+
+var batchOperations = map[string]batchOperation{
+	"write": {
+		required: []string{"path", "content"},
+		paths:    []string{"path"},
+		handle:   (*FilesystemHandler).HandleWriteFile,
+	},
+	"modify": {
+		required: []string{"path", "find", "replace"},
+		paths:    []string{"path"},
+		handle:   (*FilesystemHandler).HandleModifyFile,
+	},
+	"move": {
+		required: []string{"source", "destination"},
+		paths:    []string{"source", "destination"},
+		handle:   (*FilesystemHandler).HandleMoveFile,
+	},
+	"copy": {
+		required: []string{"source", "destination"},
+		paths:    []string{"source", "destination"},
+		handle:   (*FilesystemHandler).HandleCopyFile,
+	},
+	"delete": {
+		required: []string{"path"},
+		paths:    []string{"path"},
+		handle:   (*FilesystemHandler).HandleDeleteFile,
+	},
+	"mkdir": {
+		required: []string{"path"},
+		paths:    []string{"path"},
+		handle:   (*FilesystemHandler).HandleCreateDirectory,
+	},
+}
+
+func (fs *FilesystemHandler) HandleBatch(
+	ctx context.Context,
+	request mcp.CallToolRequest,
+) (*mcp.CallToolResult, error) {
+	rawOperations, ok := request.GetArguments()["operations"].([]any)
+	if !ok {
+		return nil, fmt.Errorf("required argument \"operations\" not found or not an array")
+	}
+
+	if len(rawOperations) == 0 {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: "Error: No operations specified",
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	if len(rawOperations) > fs.limits.MaxBatchOperations {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error: Too many operations. Maximum is %d operations per batch.", fs.limits.MaxBatchOperations),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	// Validate every operation before changing anything
+	operations := make([]map[string]any, len(rawOperations))
+	for i, raw := range rawOperations {
+		args, err := fs.validateBatchOperation(raw)
+		if err != nil {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					mcp.TextContent{
+						Type: "text",
+						Text: fmt.Sprintf("Error: Operation %d is invalid: %v. Nothing was changed.", i+1, err),
+					},
+				},
+				IsError: true,
+			}, nil
+		}
+		operations[i] = args
+	}
+
+	// Record how to reverse each operation. When the undo journal is enabled
+	// the whole batch becomes a single journal entry.
+	summary := fmt.Sprintf("batch of %d operation(s)", len(operations))
+	rollback, err := fs.journalBegin(ctx, "batch", summary)
+	if err == nil && rollback == nil {
+		rollback, err = newScratchEntry(fs.backend, "batch", summary)
+		defer rollback.discard()
+	}
+	if err != nil {
+		return journalErrorResult(err), nil
+	}
+
+	// The steps recorded for each operation, in execution order
+	var opSteps [][]journalStep
+	opCtx := withoutJournal(ctx)
+
+	var result strings.Builder
+	for i, args := range operations {
+		op := args["op"].(string)
+
+		before := len(rollback.Steps)
+		err := fs.recordBatchOperation(rollback, op, args)
+		opSteps = append(opSteps, slices.Clone(rollback.Steps[before:]))
+
+		var res *mcp.CallToolResult
+		if err == nil {
+			res, err = batchOperations[op].handle(fs, opCtx, mcp.CallToolRequest{
+				Params: mcp.CallToolParams{
+					Name:      op,
+					Arguments: args,
+				},
+			})
+		}
+		if err == nil && res.IsError {
+			err = fmt.Errorf("%s", resultText(res))
+		}
+
+		if err != nil {
+			rollbackErr := fs.rollbackBatch(rollback, opSteps)
+			rollback.discard()
+
+			text := fmt.Sprintf("Error: Operation %d (%s) failed: %v\n", i+1, op, err)
+			if rollbackErr != nil {
+				text += fmt.Sprintf("Rollback failed: %v. Files may be partially modified.", rollbackErr)
+			} else {
+				text += fmt.Sprintf("Rolled back %d completed operation(s). No changes were kept.", i)
+			}
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					mcp.TextContent{
+						Type: "text",
+						Text: text,
+					},
+				},
+				IsError: true,
+			}, nil
+		}
+
+		result.WriteString(fmt.Sprintf("%d. %s: %s\n", i+1, op, resultText(res)))
+	}
+
+	if fs.journal != nil && ctx.Value(journalSuppressedKey{}) == nil {
+		// Undo reverses the operations in the opposite order they ran
+		var steps []journalStep
+		for i := len(opSteps) - 1; i >= 0; i-- {
+			steps = append(steps, opSteps[i]...)
+		}
+		rollback.Steps = steps
+		fs.journalCommit(rollback)
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: fmt.Sprintf("Successfully completed %d operation(s):\n\n%s", len(operations), result.String()),
+			},
+		},
+	}, nil
+}
+
+// validateBatchOperation checks an operation's type, arguments and paths and
+// returns its arguments
+func (fs *FilesystemHandler) validateBatchOperation(raw any) (map[string]any, error) {
+	args, ok := raw.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("operation must be an object")
+	}
+
+	op, _ := args["op"].(string)
+	spec, ok := batchOperations[op]
+	if !ok {
+		return nil, fmt.Errorf("unknown op %q (expected write, modify, move, copy, delete or mkdir)", op)
+	}
+
+	for _, name := range spec.required {
+		if _, ok := args[name].(string); !ok {
+			return nil, fmt.Errorf("%s: missing string argument %q", op, name)
+		}
+	}
+
+	// Targets may not exist yet because an earlier operation creates them,
+	// so only check that each path lies within the allowed directories here.
+	// The full validation runs again right before the operation executes.
+	for _, name := range spec.paths {
+		abs, err := filepath.Abs(args[name].(string))
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid path: %w", op, err)
+		}
+		if !fs.isPathInAllowedDirs(abs) {
+			return nil, fmt.Errorf("%s: access denied - path outside allowed directories: %s", op, abs)
+		}
+	}
+
+	return args, nil
+}
+
+// recordBatchOperation records in e how to reverse the operation
+func (fs *FilesystemHandler) recordBatchOperation(e *journalEntry, op string, args map[string]any) error {
+	switch op {
+	case "move":
+		validSource, err := fs.validatePath(args["source"].(string))
+		if err != nil {
+			return fmt.Errorf("source: %w", err)
+		}
+		destination := args["destination"].(string)
+		validDestDir, err := fs.validatePath(filepath.Dir(destination))
+		if err != nil {
+			return fmt.Errorf("destination: %w", err)
+		}
+		validDest := filepath.Join(validDestDir, filepath.Base(destination))
+		e.moveBack(validSource, validDest)
+		return e.snapshot(validDest)
+	case "copy":
+		validDest, err := fs.validatePath(args["destination"].(string))
+		if err != nil {
+			return fmt.Errorf("destination: %w", err)
+		}
+		return e.snapshot(validDest)
+	default:
+		validPath, err := fs.validatePath(args["path"].(string))
+		if err != nil {
+			return err
+		}
+		return e.snapshot(validPath)
+	}
+}
+
+// rollbackBatch reverses the operations of a failed batch, newest first. The
+// last operation failed, so only the steps that reset a path to its recorded
+// state are applied for it; moves back and trash restores assume it succeeded.
+func (fs *FilesystemHandler) rollbackBatch(e *journalEntry, opSteps [][]journalStep) error {
+	for i := len(opSteps) - 1; i >= 0; i-- {
+		steps := opSteps[i]
+		if i == len(opSteps)-1 {
+			var reset []journalStep
+			for _, step := range steps {
+				if step.Op == stepRestore || step.Op == stepRemove {
+					reset = append(reset, step)
+				}
+			}
+			steps = reset
+		}
+		if err := e.applySteps(fs, steps); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resultText returns the text of the first text content of a tool result
+func resultText(res *mcp.CallToolResult) string {
+	for _, content := range res.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			return text.Text
+		}
+	}
+	return ""
+}
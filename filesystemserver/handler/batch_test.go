@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleBatch(t *testing.T) {
+	// Setup a temporary directory for the test
+	tmpDir := t.TempDir()
+
+	allowedDirs := resolveAllowedDirs(t, tmpDir)
+	fsHandler, err := NewFilesystemHandler(allowedDirs)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	runBatch := func(t *testing.T, operations ...map[string]any) *mcp.CallToolResult {
+		t.Helper()
+		ops := make([]any, len(operations))
+		for i, op := range operations {
+			ops[i] = op
+		}
+		req := mcp.CallToolRequest{
+			Params: mcp.CallToolParams{
+				Arguments: map[string]interface{}{
+					"operations": ops,
+				},
+			},
+		}
+		res, err := fsHandler.HandleBatch(ctx, req)
+		require.NoError(t, err)
+		return res
+	}
+
+	t.Run("all operations succeed", func(t *testing.T) {
+		dir := filepath.Join(tmpDir, "refactor")
+		res := runBatch(t,
+			map[string]any{"op": "mkdir", "path": dir},
+			map[string]any{"op": "write", "path": filepath.Join(dir, "old.go"), "content": "func Old() {}"},
+			map[string]any{"op": "modify", "path": filepath.Join(dir, "old.go"), "find": "Old", "replace": "New"},
+			map[string]any{"op": "move", "source": filepath.Join(dir, "old.go"), "destination": filepath.Join(dir, "new.go")},
+			map[string]any{"op": "copy", "source": filepath.Join(dir, "new.go"), "destination": filepath.Join(dir, "copy.go")},
+		)
+		require.False(t, res.IsError, "%v", res.Content)
+
+		content, err := os.ReadFile(filepath.Join(dir, "new.go"))
+		require.NoError(t, err)
+		assert.Equal(t, "func New() {}", string(content))
+		_, err = os.Stat(filepath.Join(dir, "copy.go"))
+		require.NoError(t, err)
+		_, err = os.Stat(filepath.Join(dir, "old.go"))
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("failure rolls back completed operations", func(t *testing.T) {
+		keep := filepath.Join(tmpDir, "keep.txt")
+		remove := filepath.Join(tmpDir, "remove.txt")
+		require.NoError(t, os.WriteFile(keep, []byte("original"), 0644))
+		require.NoError(t, os.WriteFile(remove, []byte("still here"), 0644))
+
+		res := runBatch(t,
+			map[string]any{"op": "write", "path": keep, "content": "changed"},
+			map[string]any{"op": "delete", "path": remove},
+			map[string]any{"op": "mkdir", "path": filepath.Join(tmpDir, "created")},
+			map[string]any{"op": "move", "source": filepath.Join(tmpDir, "missing.txt"), "destination": filepath.Join(tmpDir, "x.txt")},
+		)
+		require.True(t, res.IsError)
+		assert.Contains(t, res.Content[0].(mcp.TextContent).Text, "Operation 4 (move) failed")
+
+		content, err := os.ReadFile(keep)
+		require.NoError(t, err)
+		assert.Equal(t, "original", string(content))
+
+		content, err = os.ReadFile(remove)
+		require.NoError(t, err)
+		assert.Equal(t, "still here", string(content))
+
+		_, err = os.Stat(filepath.Join(tmpDir, "created"))
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("invalid operations are rejected before anything runs", func(t *testing.T) {
+		target := filepath.Join(tmpDir, "untouched.txt")
+
+		res := runBatch(t,
+			map[string]any{"op": "write", "path": target, "content": "data"},
+			map[string]any{"op": "write", "path": filepath.Join(t.TempDir(), "outside.txt"), "content": "data"},
+		)
+		require.True(t, res.IsError)
+
+		_, err := os.Stat(target)
+		assert.True(t, os.IsNotExist(err))
+
+		res = runBatch(t,
+			map[string]any{"op": "write", "path": target, "content": "data"},
+			map[string]any{"op": "chmod", "path": target},
+		)
+		require.True(t, res.IsError)
+
+		_, err = os.Stat(target)
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("batch is a single undo journal entry", func(t *testing.T) {
+		require.NoError(t, fsHandler.EnableJournal(t.TempDir(), 0))
+		defer func() { fsHandler.journal = nil }()
+
+		first := filepath.Join(tmpDir, "journaled_1.txt")
+		second := filepath.Join(tmpDir, "journaled_2.txt")
+		res := runBatch(t,
+			map[string]any{"op": "write", "path": first, "content": "one"},
+			map[string]any{"op": "move", "source": first, "destination": second},
+		)
+		require.False(t, res.IsError, "%v", res.Content)
+
+		entries, err := fsHandler.journal.list()
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "batch", entries[0].Tool)
+
+		res, err = fsHandler.HandleUndoLast(ctx, mcp.CallToolRequest{})
+		require.NoError(t, err)
+		require.False(t, res.IsError, "%v", res.Content)
+
+		_, err = os.Stat(first)
+		assert.True(t, os.IsNotExist(err))
+		_, err = os.Stat(second)
+		assert.True(t, os.IsNotExist(err))
+	})
+}
+
+func TestHandleBatchRollbackOnBackends(t *testing.T) {
+	// A root that does not exist on the local disk
+	memRoot := filepath.Join(t.TempDir(), "absent")
+	overlayRoot, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		root  string
+		setup func(t *testing.T) *FilesystemHandler
+	}{
+		{"memory", memRoot, func(t *testing.T) *FilesystemHandler {
+			b := NewMemBackend()
+			require.NoError(t, b.MkdirAll(memRoot, 0755))
+			fsHandler, err := NewFilesystemHandlerWithBackend([]string{memRoot}, b)
+			require.NoError(t, err)
+			return fsHandler
+		}},
+		{"overlay", overlayRoot, func(t *testing.T) *FilesystemHandler {
+			fsHandler, err := NewFilesystemHandler([]string{overlayRoot})
+			require.NoError(t, err)
+			require.NoError(t, fsHandler.EnableOverlay(t.TempDir()))
+			return fsHandler
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsHandler := tt.setup(t)
+			b := fsHandler.backend
+			path := func(name string) string {
+				return filepath.Join(tt.root, name)
+			}
+			require.NoError(t, writeFile(b, path("keep.txt"), []byte("original"), 0644))
+			require.NoError(t, mkdirAll(b, path("dir"), 0755))
+			require.NoError(t, writeFile(b, path("dir/remove.txt"), []byte("still here"), 0644))
+
+			res := callTool(t, fsHandler.HandleBatch, map[string]any{"operations": []any{
+				map[string]any{"op": "write", "path": path("keep.txt"), "content": "changed"},
+				map[string]any{"op": "delete", "path": path("dir"), "recursive": true},
+				map[string]any{"op": "move", "source": path("keep.txt"), "destination": path("moved/keep.txt")},
+				map[string]any{"op": "move", "source": path("missing.txt"), "destination": path("x.txt")},
+			}})
+			require.True(t, res.IsError)
+			assert.Contains(t, res.Content[0].(mcp.TextContent).Text, "Operation 4 (move) failed")
+
+			content, err := readFile(b, path("keep.txt"))
+			require.NoError(t, err)
+			assert.Equal(t, "original", string(content))
+			content, err = readFile(b, path("dir/remove.txt"))
+			require.NoError(t, err)
+			assert.Equal(t, "still here", string(content))
+			_, err = b.Stat(path("moved"))
+			assert.True(t, os.IsNotExist(err))
+		})
+	}
+}
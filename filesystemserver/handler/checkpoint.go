@@ -0,0 +1,533 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+const (
+	// Directory of the checkpoint store holding content-addressed file data
+	checkpointBlobDir = "blobs"
+	// Directory of the checkpoint store holding one manifest per checkpoint
+	checkpointManifestDir = "checkpoints"
+)
+
+// Entry types recorded in a checkpoint manifest
+const (
+	checkpointTypeFile    = "file"
+	checkpointTypeDir     = "directory"
+	checkpointTypeSymlink = "symlink"
+)
+
+// checkpointNamePattern restricts names to something safe to use as a file name
+var checkpointNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// checkpointHashPattern matches the hex-encoded SHA-256 that names a blob
+var checkpointHashPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// checkpointStore keeps snapshots of directory trees. File contents are stored
+// once per distinct SHA-256 hash; each checkpoint is a manifest referencing them.
+type checkpointStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// checkpointFile is a single entry of a checkpoint manifest
+type checkpointFile struct {
+	Path     string      `json:"path"`
+	Type     string      `json:"type"`
+	Mode     os.FileMode `json:"mode"`
+	Size     int64       `json:"size,omitempty"`
+	Modified time.Time   `json:"modified"`
+	Hash     string      `json:"hash,omitempty"`
+	Target   string      `json:"target,omitempty"`
+}
+
+// checkpointManifest describes the full state of a directory tree
+type checkpointManifest struct {
+	Checkpoint
+	Entries []checkpointFile `json:"entries"`
+}
+
+// checkpointChange is a difference between a checkpoint and the current tree
+type checkpointChange struct {
+	Path   string
+	Status string // "added", "removed" or "modified"
+}
+
+// EnableCheckpoints stores workspace checkpoints in dir
+func (fs *FilesystemHandler) EnableCheckpoints(dir string) error {
+	if !fs.localDisk() {
+		return fmt.Errorf("checkpoints require the local disk backend")
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve checkpoint directory %s: %w", dir, err)
+	}
+	for _, sub := range []string{checkpointBlobDir, checkpointManifestDir} {
+		if err := os.MkdirAll(filepath.Join(abs, sub), 0700); err != nil {
+			return fmt.Errorf("failed to create checkpoint directory %s: %w", abs, err)
+		}
+	}
+	realDir, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return fmt.Errorf("failed to resolve checkpoint directory %s: %w", abs, err)
+	}
+
+	// Tools must not be able to edit the manifests that restore replays
+	if err := fs.checkAllowedOverlap(realDir); err != nil {
+		return fmt.Errorf("checkpoint directory %s %w", abs, err)
+	}
+
+	fs.checkpoints = &checkpointStore{dir: filepath.Clean(realDir)}
+	return nil
+}
+
+// create snapshots the directory tree at root under the given name
+func (s *checkpointStore) create(name, root string) (*checkpointManifest, error) {
+	if !checkpointNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid checkpoint name %q: use letters, digits, '.', '_' and '-'", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	manifestPath := s.manifestPath(name)
+	if _, err := os.Stat(manifestPath); err == nil {
+		return nil, fmt.Errorf("checkpoint already exists: %s", name)
+	}
+
+	manifest := &checkpointManifest{
+		Checkpoint: Checkpoint{
+			Name:    name,
+			Root:    root,
+			Created: time.Now().UTC(),
+		},
+	}
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+		// Never snapshot the store itself
+		if s.contains(path) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		entry := checkpointFile{
+			Path:     filepath.ToSlash(rel),
+			Mode:     info.Mode().Perm(),
+			Modified: info.ModTime().UTC(),
+		}
+
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			entry.Type = checkpointTypeSymlink
+			entry.Target = target
+		case info.IsDir():
+			entry.Type = checkpointTypeDir
+		case info.Mode().IsRegular():
+			hash, err := s.storeBlob(path)
+			if err != nil {
+				return err
+			}
+			entry.Type = checkpointTypeFile
+			entry.Size = info.Size()
+			entry.Hash = hash
+			manifest.Size += info.Size()
+		default:
+			// Skip devices, sockets and pipes
+			return nil
+		}
+
+		manifest.Entries = append(manifest.Entries, entry)
+		if entry.Type != checkpointTypeDir {
+			manifest.Files++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(manifestPath, data, 0600); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// list returns all checkpoints, newest first
+func (s *checkpointStore) list() ([]Checkpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dirEntries, err := os.ReadDir(filepath.Join(s.dir, checkpointManifestDir))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Checkpoint, 0, len(dirEntries))
+	for _, dirEntry := range dirEntries {
+		name, ok := strings.CutSuffix(dirEntry.Name(), ".json")
+		if !ok {
+			continue
+		}
+		manifest, err := s.load(name)
+		if err != nil {
+			continue
+		}
+		result = append(result, manifest.Checkpoint)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Created.After(result[j].Created)
+	})
+	return result, nil
+}
+
+// get loads the manifest of the named checkpoint
+func (s *checkpointStore) get(name string) (*checkpointManifest, error) {
+	if !checkpointNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid checkpoint name %q", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.load(name)
+}
+
+// load reads a manifest; the caller must hold s.mu
+func (s *checkpointStore) load(name string) (*checkpointManifest, error) {
+	data, err := os.ReadFile(s.manifestPath(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("checkpoint not found: %s", name)
+		}
+		return nil, err
+	}
+	var manifest checkpointManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("corrupt checkpoint %s: %w", name, err)
+	}
+	// Restore writes every entry below the root and reads its blob
+	for _, entry := range manifest.Entries {
+		if !filepath.IsLocal(filepath.FromSlash(entry.Path)) {
+			return nil, fmt.Errorf("corrupt checkpoint %s: entry %q is outside its root", name, entry.Path)
+		}
+		if entry.Type == checkpointTypeFile && !checkpointHashPattern.MatchString(entry.Hash) {
+			return nil, fmt.Errorf("corrupt checkpoint %s: invalid hash %q", name, entry.Hash)
+		}
+	}
+	return &manifest, nil
+}
+
+// diff compares a checkpoint with the current state of its root directory
+func (s *checkpointStore) diff(manifest *checkpointManifest) ([]checkpointChange, error) {
+	current, err := s.scan(manifest.Root)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []checkpointChange
+	for _, entry := range manifest.Entries {
+		now, ok := current[entry.Path]
+		switch {
+		case !ok:
+			changes = append(changes, checkpointChange{Path: entry.Path, Status: "removed"})
+		case !sameCheckpointContent(entry, now, filepath.Join(manifest.Root, filepath.FromSlash(entry.Path))):
+			changes = append(changes, checkpointChange{Path: entry.Path, Status: "modified"})
+		}
+		delete(current, entry.Path)
+	}
+	for path := range current {
+		changes = append(changes, checkpointChange{Path: path, Status: "added"})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes, nil
+}
+
+// fileDiff renders a change of a file or symlink as a unified diff relative
+// to the checkpointed root, whose current content is read from b.
+// Directories have no diff and return "".
+func (s *checkpointStore) fileDiff(b Backend, manifest *checkpointManifest, change checkpointChange) (string, error) {
+	oldName := "a/" + change.Path
+	newName := "b/" + change.Path
+
+	var before, after []byte
+	var err error
+	if change.Status != "added" {
+		for _, entry := range manifest.Entries {
+			if entry.Path != change.Path {
+				continue
+			}
+			switch entry.Type {
+			case checkpointTypeDir:
+				return "", nil
+			case checkpointTypeSymlink:
+				before = []byte(entry.Target)
+			case checkpointTypeFile:
+				if before, err = os.ReadFile(s.blobPath(entry.Hash)); err != nil {
+					return "", err
+				}
+			}
+			break
+		}
+	} else {
+		oldName = "/dev/null"
+	}
+	if change.Status != "removed" {
+		path := filepath.Join(manifest.Root, filepath.FromSlash(change.Path))
+		info, err := b.Lstat(path)
+		if err != nil {
+			return "", err
+		}
+		if info.IsDir() {
+			return "", nil
+		}
+		if after, err = overlayContent(b, path); err != nil {
+			return "", err
+		}
+	} else {
+		newName = "/dev/null"
+	}
+
+	if isBinary(before) || isBinary(after) {
+		return fmt.Sprintf("Binary files %s and %s differ\n", oldName, newName), nil
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(before)),
+		B:        difflib.SplitLines(string(after)),
+		FromFile: oldName,
+		ToFile:   newName,
+		Context:  3,
+	})
+}
+
+// restore makes the root directory match the checkpoint exactly: files added
+// since are removed and changed or removed files are written back
+func (s *checkpointStore) restore(manifest *checkpointManifest) ([]checkpointChange, error) {
+	changes, err := s.diff(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(manifest.Root, 0755); err != nil {
+		return nil, err
+	}
+
+	// Remove what was added or changed first, so that directories can be
+	// recreated in place of files and vice versa
+	for _, change := range changes {
+		if change.Status == "removed" {
+			continue
+		}
+		path := filepath.Join(manifest.Root, filepath.FromSlash(change.Path))
+		if err := os.RemoveAll(path); err != nil {
+			return nil, err
+		}
+	}
+
+	// Entries are stored in walk order, so parents come before children
+	for _, entry := range manifest.Entries {
+		path := filepath.Join(manifest.Root, filepath.FromSlash(entry.Path))
+		switch entry.Type {
+		case checkpointTypeDir:
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return nil, err
+			}
+		case checkpointTypeSymlink:
+			if _, err := os.Lstat(path); err == nil {
+				continue
+			}
+			if err := os.Symlink(entry.Target, path); err != nil {
+				return nil, err
+			}
+		case checkpointTypeFile:
+			if _, err := os.Lstat(path); err == nil {
+				continue
+			}
+			if err := copyFile(OSBackend{}, s.blobPath(entry.Hash), path); err != nil {
+				return nil, err
+			}
+			if err := os.Chmod(path, entry.Mode); err != nil {
+				return nil, err
+			}
+			if err := os.Chtimes(path, entry.Modified, entry.Modified); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Restore directory modes and times last, deepest first, as writing
+	// children changes them and read-only modes would prevent the writes
+	for i := len(manifest.Entries) - 1; i >= 0; i-- {
+		entry := manifest.Entries[i]
+		if entry.Type != checkpointTypeDir {
+			continue
+		}
+		path := filepath.Join(manifest.Root, filepath.FromSlash(entry.Path))
+		if err := os.Chmod(path, entry.Mode); err != nil {
+			return nil, err
+		}
+		_ = os.Chtimes(path, entry.Modified, entry.Modified)
+	}
+
+	return changes, nil
+}
+
+// scan records the current state of the tree at root without storing content
+func (s *checkpointStore) scan(root string) (map[string]checkpointFile, error) {
+	result := make(map[string]checkpointFile)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if path == root {
+			return nil
+		}
+		if s.contains(path) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		entry := checkpointFile{
+			Path: filepath.ToSlash(rel),
+			Mode: info.Mode().Perm(),
+			Size: info.Size(),
+		}
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			entry.Type = checkpointTypeSymlink
+			entry.Target, _ = os.Readlink(path)
+		case info.IsDir():
+			entry.Type = checkpointTypeDir
+		case info.Mode().IsRegular():
+			entry.Type = checkpointTypeFile
+		default:
+			return nil
+		}
+		result[entry.Path] = entry
+		return nil
+	})
+	return result, err
+}
+
+// sameCheckpointContent reports whether the current entry matches the
+// checkpointed one. File contents are only hashed when the sizes match.
+func sameCheckpointContent(saved, current checkpointFile, path string) bool {
+	if saved.Type != current.Type {
+		return false
+	}
+	switch saved.Type {
+	case checkpointTypeSymlink:
+		return saved.Target == current.Target
+	case checkpointTypeFile:
+		if saved.Size != current.Size || saved.Mode != current.Mode {
+			return false
+		}
+		hash, err := hashFile(path)
+		return err == nil && hash == saved.Hash
+	}
+	return true
+}
+
+// storeBlob copies the file at path into the store, keyed by its hash
+func (s *checkpointStore) storeBlob(path string) (string, error) {
+	hash, err := hashFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	blobPath := s.blobPath(hash)
+	if _, err := os.Stat(blobPath); err == nil {
+		// Already stored by an earlier checkpoint
+		return hash, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(blobPath), 0700); err != nil {
+		return "", err
+	}
+
+	// Write to a temporary name first so an interrupted copy never leaves a
+	// blob whose content does not match its hash
+	tmpPath := blobPath + ".tmp"
+	if err := copyFile(OSBackend{}, path, tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+	if err := os.Rename(tmpPath, blobPath); err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+	return hash, nil
+}
+
+func (s *checkpointStore) blobPath(hash string) string {
+	return filepath.Join(s.dir, checkpointBlobDir, hash[:2], hash)
+}
+
+func (s *checkpointStore) manifestPath(name string) string {
+	return filepath.Join(s.dir, checkpointManifestDir, name+".json")
+}
+
+// contains reports whether path is the store directory or inside it
+func (s *checkpointStore) contains(path string) bool {
+	return path == s.dir || strings.HasPrefix(path, s.dir+string(filepath.Separator))
+}
+
+// hashFile returns the hex-encoded SHA-256 of the file's content
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func (fs *FilesystemHandler) HandleCheckpointCreate(
+	ctx context.Context,
+	request mcp.CallToolRequest,
+) (*mcp.CallToolResult, error) {
+	name, err := request.RequireString("name")
+	if err != nil {
+		return nil, err
+	}
+	path, err := request.RequireString("path")
+	if err != nil {
+		return nil, err
+	}
+
+	if fs.checkpoints == nil {
+		return checkpointsDisabledResult(), nil
+	}
+
+	// Handle empty or relative paths like "." or "./" by converting to absolute path
+	if path == "." || path == "./" {
+		// Get current working directory
+		cwd, err := os.Getwd()
+		if err != nil {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					mcp.TextContent{
+						Type: "text",
+						Text: fmt.Sprintf("Error resolving current directory: %v", err),
+					},
+				},
+				IsError: true,
+			}, nil
+		}
+		path = cwd
+	}
+
+	validPath, err := fs.validatePath(path)
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	info, err := os.Stat(validPath)
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	if !info.IsDir() || fs.checkpoints.contains(validPath) {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: "Error: Path must be a directory outside the checkpoint store",
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	manifest, err := fs.checkpoints.create(name, validPath)
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error creating checkpoint: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: fmt.Sprintf(
+					"Created checkpoint %s of %s (%d files, %d bytes)",
+					manifest.Name,
+					validPath,
+					manifest.Files,
+					manifest.Size,
+				),
+			},
+		},
+	}, nil
+}
+
+// checkpointsDisabledResult is returned by the checkpoint tools when they are off
+func checkpointsDisabledResult() *mcp.CallToolResult {
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: "Error: Checkpoints are not enabled on this server",
+			},
+		},
+		IsError: true,
+	}
+}
+
+// checkpoint looks up a checkpoint and makes sure its root is still accessible
+func (fs *FilesystemHandler) checkpoint(name string) (*checkpointManifest, error) {
+	manifest, err := fs.checkpoints.get(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fs.validatePath(manifest.Root); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// loadCheckpoint is checkpoint for tools, returning errors as a tool result
+func (fs *FilesystemHandler) loadCheckpoint(name string) (*checkpointManifest, *mcp.CallToolResult) {
+	manifest, err := fs.checkpoint(name)
+	if err != nil {
+		return nil, &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error: %v", err),
+				},
+			},
+			IsError: true,
+		}
+	}
+	return manifest, nil
+}
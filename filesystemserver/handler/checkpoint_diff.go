@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func (fs *FilesystemHandler) HandleCheckpointDiff(
+	ctx context.Context,
+	request mcp.CallToolRequest,
+) (*mcp.CallToolResult, error) {
+	name, err := request.RequireString("name")
+	if err != nil {
+		return nil, err
+	}
+
+	if fs.checkpoints == nil {
+		return checkpointsDisabledResult(), nil
+	}
+
+	manifest, errResult := fs.loadCheckpoint(name)
+	if errResult != nil {
+		return errResult, nil
+	}
+
+	changes, err := fs.checkpoints.diff(manifest)
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error comparing with checkpoint: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	if len(changes) == 0 {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("No changes in %s since checkpoint %s", manifest.Root, name),
+				},
+			},
+		}, nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: fmt.Sprintf(
+					"%d change(s) in %s since checkpoint %s:\n\n%s",
+					len(changes),
+					manifest.Root,
+					name,
+					formatCheckpointChanges(changes),
+				),
+			},
+		},
+	}, nil
+}
+
+// formatCheckpointChanges renders changes one per line, e.g. "M src/main.go"
+func formatCheckpointChanges(changes []checkpointChange) string {
+	var result strings.Builder
+	for _, change := range changes {
+		marker := "M"
+		switch change.Status {
+		case "added":
+			marker = "A"
+		case "removed":
+			marker = "D"
+		}
+		result.WriteString(fmt.Sprintf("%s %s\n", marker, change.Path))
+	}
+	return result.String()
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func (fs *FilesystemHandler) HandleCheckpointList(
+	ctx context.Context,
+	request mcp.CallToolRequest,
+) (*mcp.CallToolResult, error) {
+	if fs.checkpoints == nil {
+		return checkpointsDisabledResult(), nil
+	}
+
+	checkpoints, err := fs.checkpoints.list()
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error reading checkpoints: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	if len(checkpoints) == 0 {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: "No checkpoints",
+				},
+			},
+		}, nil
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("%d checkpoint(s), newest first:\n\n", len(checkpoints)))
+	for _, checkpoint := range checkpoints {
+		result.WriteString(fmt.Sprintf("%s - %s (%d files, %d bytes, created %s)\n",
+			checkpoint.Name,
+			checkpoint.Root,
+			checkpoint.Files,
+			checkpoint.Size,
+			checkpoint.Created.Format(time.RFC3339),
+		))
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: result.String(),
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func (fs *FilesystemHandler) HandleCheckpointRestore(
+	ctx context.Context,
+	request mcp.CallToolRequest,
+) (*mcp.CallToolResult, error) {
+	name, err := request.RequireString("name")
+	if err != nil {
+		return nil, err
+	}
+
+	if fs.checkpoints == nil {
+		return checkpointsDisabledResult(), nil
+	}
+
+	manifest, errResult := fs.loadCheckpoint(name)
+	if errResult != nil {
+		return errResult, nil
+	}
+
+	changes, err := fs.checkpoints.restore(manifest)
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error restoring checkpoint: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	if len(changes) == 0 {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("%s already matches checkpoint %s", manifest.Root, name),
+				},
+			},
+		}, nil
+	}
+
+	resourceURI := pathToResourceURI(manifest.Root)
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: fmt.Sprintf(
+					"Restored %s to checkpoint %s, reverting %d change(s):\n\n%s",
+					manifest.Root,
+					name,
+					len(changes),
+					formatCheckpointChanges(changes),
+				),
+			},
+			mcp.EmbeddedResource{
+				Type: "resource",
+				Resource: mcp.TextResourceContents{
+					URI:      resourceURI,
+					MIMEType: "text/plain",
+					Text:     fmt.Sprintf("Directory: %s", manifest.Root),
+				},
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckpoints(t *testing.T) {
+	// Setup a workspace and a separate checkpoint store
+	tmpDir := t.TempDir()
+	storeDir := t.TempDir()
+
+	allowedDirs := resolveAllowedDirs(t, tmpDir)
+	fsHandler, err := NewFilesystemHandler(allowedDirs)
+	require.NoError(t, err)
+	require.NoError(t, fsHandler.EnableCheckpoints(storeDir))
+
+	ctx := context.Background()
+
+	workspace := filepath.Join(tmpDir, "workspace")
+	require.NoError(t, os.MkdirAll(filepath.Join(workspace, "src"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(workspace, "src", "main.go"), []byte("package main"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(workspace, "README.md"), []byte("readme"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(workspace, "copy.md"), []byte("readme"), 0644))
+
+	call := func(t *testing.T, handle func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error), args map[string]interface{}) *mcp.CallToolResult {
+		t.Helper()
+		req := mcp.CallToolRequest{
+			Params: mcp.CallToolParams{
+				Arguments: args,
+			},
+		}
+		res, err := handle(ctx, req)
+		require.NoError(t, err)
+		return res
+	}
+
+	t.Run("create checkpoint", func(t *testing.T) {
+		res := call(t, fsHandler.HandleCheckpointCreate, map[string]interface{}{"name": "before", "path": workspace})
+		require.False(t, res.IsError, "%v", res.Content)
+
+		// Identical contents are stored once
+		blobs := 0
+		err := filepath.Walk(filepath.Join(storeDir, checkpointBlobDir), func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				blobs++
+			}
+			return err
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 2, blobs)
+	})
+
+	t.Run("duplicate and invalid names are rejected", func(t *testing.T) {
+		res := call(t, fsHandler.HandleCheckpointCreate, map[string]interface{}{"name": "before", "path": workspace})
+		assert.True(t, res.IsError)
+
+		res = call(t, fsHandler.HandleCheckpointCreate, map[string]interface{}{"name": "../escape", "path": workspace})
+		assert.True(t, res.IsError)
+	})
+
+	t.Run("list checkpoints", func(t *testing.T) {
+		res := call(t, fsHandler.HandleCheckpointList, map[string]interface{}{})
+		require.False(t, res.IsError)
+		assert.Contains(t, res.Content[0].(mcp.TextContent).Text, "before")
+	})
+
+	// Change the workspace after the checkpoint
+	require.NoError(t, os.WriteFile(filepath.Join(workspace, "src", "main.go"), []byte("package broken"), 0644))
+	require.NoError(t, os.Remove(filepath.Join(workspace, "README.md")))
+	require.NoError(t, os.MkdirAll(filepath.Join(workspace, "generated"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(workspace, "generated", "out.txt"), []byte("junk"), 0644))
+
+	t.Run("diff against checkpoint", func(t *testing.T) {
+		res := call(t, fsHandler.HandleCheckpointDiff, map[string]interface{}{"name": "before"})
+		require.False(t, res.IsError, "%v", res.Content)
+
+		text := res.Content[0].(mcp.TextContent).Text
+		assert.Contains(t, text, "M src/main.go")
+		assert.Contains(t, text, "D README.md")
+		assert.Contains(t, text, "A generated\n")
+		assert.Contains(t, text, "A generated/out.txt")
+		assert.NotContains(t, text, "copy.md")
+	})
+
+	t.Run("restore checkpoint", func(t *testing.T) {
+		res := call(t, fsHandler.HandleCheckpointRestore, map[string]interface{}{"name": "before"})
+		require.False(t, res.IsError, "%v", res.Content)
+
+		content, err := os.ReadFile(filepath.Join(workspace, "src", "main.go"))
+		require.NoError(t, err)
+		assert.Equal(t, "package main", string(content))
+
+		content, err = os.ReadFile(filepath.Join(workspace, "README.md"))
+		require.NoError(t, err)
+		assert.Equal(t, "readme", string(content))
+
+		_, err = os.Stat(filepath.Join(workspace, "generated"))
+		assert.True(t, os.IsNotExist(err))
+
+		res = call(t, fsHandler.HandleCheckpointDiff, map[string]interface{}{"name": "before"})
+		require.False(t, res.IsError)
+		assert.Contains(t, res.Content[0].(mcp.TextContent).Text, "No changes")
+	})
+
+	t.Run("unknown checkpoint", func(t *testing.T) {
+		res := call(t, fsHandler.HandleCheckpointRestore, map[string]interface{}{"name": "missing"})
+		assert.True(t, res.IsError)
+	})
+
+	t.Run("path outside allowed directories", func(t *testing.T) {
+		res := call(t, fsHandler.HandleCheckpointCreate, map[string]interface{}{"name": "outside", "path": t.TempDir()})
+		assert.True(t, res.IsError)
+	})
+
+	t.Run("forged manifest entries", func(t *testing.T) {
+		manifestPath := filepath.Join(storeDir, checkpointManifestDir, "before.json")
+		original, err := os.ReadFile(manifestPath)
+		require.NoError(t, err)
+		defer func() { require.NoError(t, os.WriteFile(manifestPath, original, 0600)) }()
+		escaped := filepath.Join(tmpDir, "escaped.txt")
+
+		for _, entry := range []checkpointFile{
+			{Path: "../escaped.txt", Type: checkpointTypeFile, Mode: 0644, Hash: strings.Repeat("0", 64)},
+			{Path: "/etc/escaped.txt", Type: checkpointTypeDir, Mode: 0755},
+			{Path: "stolen.txt", Type: checkpointTypeFile, Mode: 0644, Hash: "../../../etc/passwd"},
+		} {
+			var manifest checkpointManifest
+			require.NoError(t, json.Unmarshal(original, &manifest))
+			manifest.Entries = append(manifest.Entries, entry)
+			data, err := json.Marshal(manifest)
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(manifestPath, data, 0600))
+
+			res := call(t, fsHandler.HandleCheckpointRestore, map[string]interface{}{"name": "before"})
+			require.True(t, res.IsError, entry.Path)
+			assert.Contains(t, res.Content[0].(mcp.TextContent).Text, "corrupt checkpoint")
+			_, err = os.Stat(escaped)
+			assert.True(t, os.IsNotExist(err))
+			_, err = os.Stat(filepath.Join(workspace, "stolen.txt"))
+			assert.True(t, os.IsNotExist(err))
+		}
+	})
+}
+
+func TestEnableCheckpointsInsideAllowedDir(t *testing.T) {
+	allowedDirs := resolveAllowedDirs(t, t.TempDir())
+	fsHandler, err := NewFilesystemHandler(allowedDirs)
+	require.NoError(t, err)
+
+	err = fsHandler.EnableCheckpoints(filepath.Join(allowedDirs[0], ".checkpoints"))
+	assert.ErrorContains(t, err, "overlaps the allowed directory")
+	assert.Nil(t, fsHandler.checkpoints)
+}
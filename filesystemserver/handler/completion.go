@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"context"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxCompletions is the most values a completion may return
+const maxCompletions = 100
+
+// CompleteResourceArgument completes the path of the file:///{+path}
+// resource template. It implements server.ResourceCompletionProvider.
+func (fs *FilesystemHandler) CompleteResourceArgument(
+	ctx context.Context,
+	uri string,
+	argument mcp.CompleteArgument,
+	context mcp.CompleteContext,
+) (*mcp.Completion, error) {
+	if argument.Name != "path" {
+		return &mcp.Completion{Values: []string{}}, nil
+	}
+
+	// The template variable is the path without the leading slash
+	partial := argument.Value
+	if runtime.GOOS != "windows" {
+		partial = "/" + partial
+	}
+	completion := fs.completePath(filepath.FromSlash(partial))
+	for i, value := range completion.Values {
+		completion.Values[i] = strings.TrimPrefix(filepath.ToSlash(value), "/")
+	}
+	return completion, nil
+}
+
+// pathArguments are the tool and prompt arguments completed as paths
+var pathArguments = []string{"path", "source", "destination"}
+
+// CompletePromptArgument completes the path, source and destination
+// arguments and the checkpoint of review_changes. MCP has no completion
+// reference for tools, so besides the prompts it also answers prompt
+// references named after a tool. It implements server.PromptCompletionProvider.
+func (fs *FilesystemHandler) CompletePromptArgument(
+	ctx context.Context,
+	name string,
+	argument mcp.CompleteArgument,
+	context mcp.CompleteContext,
+) (*mcp.Completion, error) {
+	if argument.Name == "checkpoint" && fs.checkpoints != nil {
+		return fs.completeCheckpoint(argument.Value), nil
+	}
+	if !slices.Contains(pathArguments, argument.Name) {
+		return &mcp.Completion{Values: []string{}}, nil
+	}
+	return fs.completePath(argument.Value), nil
+}
+
+// completeCheckpoint suggests the names of the checkpoints starting with
+// partial, newest first
+func (fs *FilesystemHandler) completeCheckpoint(partial string) *mcp.Completion {
+	values := []string{}
+	checkpoints, _ := fs.checkpoints.list()
+	for _, checkpoint := range checkpoints {
+		if strings.HasPrefix(checkpoint.Name, partial) && len(values) < maxCompletions {
+			values = append(values, checkpoint.Name)
+		}
+	}
+	return &mcp.Completion{Values: values}
+}
+
+// completePath suggests the paths inside the allowed directories that start
+// with partial. Directories end with a separator so that they can be
+// completed further.
+func (fs *FilesystemHandler) completePath(partial string) *mcp.Completion {
+	var values []string
+	add := func(path string, dir bool) {
+		if dir && !strings.HasSuffix(path, string(filepath.Separator)) {
+			path += string(filepath.Separator)
+		}
+		if !slices.Contains(values, path) {
+			values = append(values, path)
+		}
+	}
+
+	// Allowed directories lead the way from the file system root
+	for _, dir := range fs.AllowedDirectories() {
+		if strings.HasPrefix(dir, partial) {
+			add(dir, true)
+		}
+	}
+
+	// Entries of the directory being typed in
+	if i := strings.LastIndex(partial, string(filepath.Separator)); i >= 0 {
+		dir, prefix := partial[:i+1], partial[i+1:]
+		if validDir, err := fs.validatePath(dir); err == nil {
+			if entries, err := fs.backend.ReadDir(validDir); err == nil {
+				for _, entry := range entries {
+					if strings.HasPrefix(entry.Name(), prefix) {
+						add(dir+entry.Name(), entry.IsDir())
+					}
+				}
+			}
+		}
+	}
+
+	slices.Sort(values)
+	completion := &mcp.Completion{Values: values, Total: len(values)}
+	if len(values) > maxCompletions {
+		completion.Values = values[:maxCompletions]
+		completion.HasMore = true
+	}
+	if completion.Values == nil {
+		completion.Values = []string{}
+	}
+	return completion
+}
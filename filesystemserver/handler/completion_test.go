@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompletePromptArgument(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "docs"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "docs", "guide.md"), []byte(""), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "data.csv"), []byte(""), 0644))
+
+	fsHandler, err := NewFilesystemHandler([]string{dir})
+	require.NoError(t, err)
+
+	complete := func(tool, name, value string) []string {
+		completion, err := fsHandler.CompletePromptArgument(context.Background(), tool,
+			mcp.CompleteArgument{Name: name, Value: value}, mcp.CompleteContext{})
+		require.NoError(t, err)
+		return completion.Values
+	}
+	sep := string(filepath.Separator)
+
+	assert.Equal(t, []string{dir + sep}, complete("read_file", "path", ""))
+	assert.Equal(t, []string{filepath.Join(dir, "data.csv"), filepath.Join(dir, "docs") + sep},
+		complete("read_file", "path", filepath.Join(dir, "d")))
+	assert.Equal(t, []string{filepath.Join(dir, "docs", "guide.md")},
+		complete("move_file", "source", filepath.Join(dir, "docs")+sep))
+	assert.Equal(t, []string{filepath.Join(dir, "data.csv")},
+		complete("copy_file", "destination", filepath.Join(dir, "da")))
+	assert.Empty(t, complete("write_file", "content", filepath.Join(dir, "d")))
+	// Outside the allowed directories only the way to them is suggested
+	require.NoError(t, os.WriteFile(filepath.Join(filepath.Dir(dir), "secret.txt"), []byte(""), 0644))
+	assert.Equal(t, []string{dir + sep}, complete("read_file", "path", filepath.Dir(dir)+sep))
+}
@@ -0,0 +1,283 @@
+package handler
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxSummaryEntries bounds how many paths a summary names and
+// maxSummaryWalk how many entries are counted for it
+const (
+	maxSummaryEntries = 10
+	maxSummaryWalk    = 100000
+)
+
+// DestructiveSummary describes what calling tool with arguments would
+// overwrite or remove: recursive deletes of directories, writes, edits,
+// copies and moves onto existing files, extractions into existing
+// directories, and undoing, restoring or discarding changes. It returns ""
+// for calls that do none of these.
+func (fs *FilesystemHandler) DestructiveSummary(tool string, arguments map[string]any) string {
+	switch tool {
+	case "delete_file":
+		recursive, _ := arguments["recursive"].(bool)
+		path, _ := arguments["path"].(string)
+		if !recursive || path == "" {
+			return ""
+		}
+		return fs.removalSummary(path)
+	case "write_file":
+		path, _ := arguments["path"].(string)
+		return fs.overwriteSummary("Overwrite", path)
+	case "modify_file", "edit_lines":
+		path, _ := arguments["path"].(string)
+		return fs.overwriteSummary("Edit", path)
+	case "move_file":
+		destination, _ := arguments["destination"].(string)
+		return fs.overwriteSummary("Overwrite", destination)
+	case "copy_file":
+		destination, _ := arguments["destination"].(string)
+		if summary := fs.overwriteSummary("Overwrite", destination); summary != "" {
+			return summary
+		}
+		if source, _ := arguments["source"].(string); source != "" {
+			return fs.mergeSummary(fmt.Sprintf("Copy %s into", source), destination)
+		}
+		return ""
+	case "extract_archive":
+		path, _ := arguments["path"].(string)
+		destination, _ := arguments["destination"].(string)
+		return fs.mergeSummary(fmt.Sprintf("Extract %s into", path), destination)
+	case "empty_trash":
+		return fs.emptyTrashSummary(arguments)
+	case "undo_last", "undo_to":
+		return fs.undoSummary(tool, arguments)
+	case "checkpoint_restore":
+		name, _ := arguments["name"].(string)
+		if fs.checkpoints == nil || name == "" {
+			return ""
+		}
+		manifest, err := fs.checkpoint(name)
+		if err != nil {
+			return ""
+		}
+		changes, err := fs.checkpoints.diff(manifest)
+		if err != nil || len(changes) == 0 {
+			return ""
+		}
+		return summaryLines(
+			fmt.Sprintf("Restore %s to checkpoint %s, reverting %d change(s)", manifest.Root, name, len(changes)),
+			strings.Split(strings.TrimSuffix(formatCheckpointChanges(changes), "\n"), "\n"),
+		)
+	case "overlay_commit", "overlay_discard":
+		if fs.overlay == nil {
+			return ""
+		}
+		changes, err := fs.overlay.Changes()
+		if err != nil || len(changes) == 0 {
+			return ""
+		}
+		action := "Write %d change(s) in the overlay to the allowed directories"
+		if tool == "overlay_discard" {
+			action = "Discard %d change(s) in the overlay"
+		}
+		return summaryLines(
+			fmt.Sprintf(action, len(changes)),
+			strings.Split(strings.TrimSuffix(formatOverlayChanges(changes), "\n"), "\n"),
+		)
+	case "batch":
+		operations, _ := arguments["operations"].([]any)
+		var summaries []string
+		for i, raw := range operations {
+			args, _ := raw.(map[string]any)
+			op, _ := args["op"].(string)
+			tool := map[string]string{
+				"write":  "write_file",
+				"modify": "modify_file",
+				"move":   "move_file",
+				"copy":   "copy_file",
+				"delete": "delete_file",
+			}[op]
+			if summary := fs.DestructiveSummary(tool, args); summary != "" {
+				summaries = append(summaries, fmt.Sprintf("Operation %d: %s", i+1, summary))
+			}
+		}
+		return strings.Join(summaries, "\n")
+	}
+	return ""
+}
+
+// removalSummary describes the directory tree at path that would be removed
+func (fs *FilesystemHandler) removalSummary(path string) string {
+	validPath, err := fs.validatePath(path)
+	if err != nil {
+		return ""
+	}
+	info, err := fs.backend.Lstat(validPath)
+	if err != nil || !info.IsDir() {
+		return ""
+	}
+
+	var files, dirs int
+	var size int64
+	var names []string
+	complete := true
+	walk(fs.backend, validPath, func(entryPath string, info os.FileInfo, err error) error {
+		if err != nil || entryPath == validPath {
+			return nil
+		}
+		if files+dirs == maxSummaryWalk {
+			complete = false
+			return filepath.SkipAll
+		}
+		if info.IsDir() {
+			dirs++
+		} else {
+			files++
+			size += info.Size()
+		}
+		if len(names) < maxSummaryEntries {
+			rel, _ := filepath.Rel(validPath, entryPath)
+			names = append(names, rel)
+		}
+		return nil
+	})
+
+	var summary strings.Builder
+	count := ""
+	if !complete {
+		count = "at least "
+	}
+	summary.WriteString(fmt.Sprintf(
+		"Delete the directory %s and everything in it: %s%d file(s) and %d directory(ies), %d bytes",
+		validPath, count, files, dirs, size,
+	))
+	for _, name := range names {
+		summary.WriteString("\n  " + name)
+	}
+	if files+dirs > len(names) {
+		summary.WriteString(fmt.Sprintf("\n  ... and %d more", files+dirs-len(names)))
+	}
+	return summary.String()
+}
+
+// overwriteSummary describes the existing file at path that an operation,
+// named by action, would replace or change
+func (fs *FilesystemHandler) overwriteSummary(action, path string) string {
+	if path == "" {
+		return ""
+	}
+	validPath, err := fs.validatePath(path)
+	if err != nil {
+		return ""
+	}
+	info, err := fs.backend.Stat(validPath)
+	if err != nil || info.IsDir() {
+		return ""
+	}
+	return fmt.Sprintf(
+		"%s the existing file %s (%d bytes, modified %s)",
+		action, validPath, info.Size(), info.ModTime().Format("2006-01-02 15:04:05"),
+	)
+}
+
+// mergeSummary describes an operation, named by action, that writes into
+// the existing directory at path
+func (fs *FilesystemHandler) mergeSummary(action, path string) string {
+	if path == "" {
+		return ""
+	}
+	validPath, err := fs.validatePath(path)
+	if err != nil {
+		return ""
+	}
+	entries, err := fs.backend.ReadDir(validPath)
+	if err != nil || len(entries) == 0 {
+		return ""
+	}
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+	return summaryLines(fmt.Sprintf(
+		"%s the existing directory %s, which has %d entry(ies) that may be overwritten",
+		action, validPath, len(entries),
+	), names)
+}
+
+// emptyTrashSummary describes the trash entries empty_trash would delete
+func (fs *FilesystemHandler) emptyTrashSummary(arguments map[string]any) string {
+	if fs.trash == nil {
+		return ""
+	}
+	if id, _ := arguments["id"].(string); id != "" {
+		entry, err := fs.trash.get(id)
+		if err != nil {
+			return ""
+		}
+		return fmt.Sprintf("Permanently delete trash entry %s (%s)", id, entry.OriginalPath)
+	}
+
+	entries, err := fs.trash.list()
+	if err != nil {
+		return ""
+	}
+	var cutoff time.Time
+	if hours, ok := arguments["older_than_hours"].(float64); ok {
+		cutoff = time.Now().Add(-time.Duration(hours * float64(time.Hour)))
+	}
+	var paths []string
+	for _, entry := range entries {
+		if cutoff.IsZero() || entry.DeletedAt.Before(cutoff) {
+			paths = append(paths, entry.OriginalPath)
+		}
+	}
+	if len(paths) == 0 {
+		return ""
+	}
+	return summaryLines(fmt.Sprintf("Permanently delete %d item(s) from the trash", len(paths)), paths)
+}
+
+// undoSummary describes the journal entries undo_last or undo_to would undo
+func (fs *FilesystemHandler) undoSummary(tool string, arguments map[string]any) string {
+	if fs.journal == nil {
+		return ""
+	}
+	entries, err := fs.journal.list()
+	if err != nil {
+		return ""
+	}
+	count, ok := arguments["count"].(float64)
+	if !ok {
+		count = 1
+	}
+	checkpoint, _ := arguments["checkpoint"].(float64)
+
+	var undone []string
+	for i, entry := range entries {
+		if tool == "undo_last" && i >= int(count) || tool == "undo_to" && entry.ID <= int(checkpoint) {
+			break
+		}
+		undone = append(undone, fmt.Sprintf("#%d %s: %s", entry.ID, entry.Tool, entry.Summary))
+	}
+	if len(undone) == 0 {
+		return ""
+	}
+	return summaryLines(fmt.Sprintf("Undo %d operation(s), reverting their changes", len(undone)), undone)
+}
+
+// summaryLines adds up to maxSummaryEntries lines to summary
+func summaryLines(summary string, lines []string) string {
+	var result strings.Builder
+	result.WriteString(summary)
+	for _, line := range lines[:min(len(lines), maxSummaryEntries)] {
+		result.WriteString("\n  " + line)
+	}
+	if len(lines) > maxSummaryEntries {
+		result.WriteString(fmt.Sprintf("\n  ... and %d more", len(lines)-maxSummaryEntries))
+	}
+	return result.String()
+}
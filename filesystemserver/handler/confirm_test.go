@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDestructiveSummary(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "src", "lib"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "main.go"), []byte("package main"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("notes"), 0644))
+
+	fsHandler, err := NewFilesystemHandler([]string{dir})
+	require.NoError(t, err)
+
+	summary := fsHandler.DestructiveSummary("delete_file", map[string]any{"path": filepath.Join(dir, "src"), "recursive": true})
+	assert.Contains(t, summary, "1 file(s) and 1 directory(ies), 12 bytes")
+	assert.Contains(t, summary, "main.go")
+
+	assert.Empty(t, fsHandler.DestructiveSummary("delete_file", map[string]any{"path": filepath.Join(dir, "src")}))
+	assert.Empty(t, fsHandler.DestructiveSummary("write_file", map[string]any{"path": filepath.Join(dir, "new.txt")}))
+	assert.Empty(t, fsHandler.DestructiveSummary("read_file", map[string]any{"path": filepath.Join(dir, "notes.txt")}))
+	assert.Contains(t, fsHandler.DestructiveSummary("move_file", map[string]any{
+		"source":      filepath.Join(dir, "src", "main.go"),
+		"destination": filepath.Join(dir, "notes.txt"),
+	}), "Overwrite the existing file")
+
+	// Outside the allowed directories the tool reports the error itself
+	assert.Empty(t, fsHandler.DestructiveSummary("write_file", map[string]any{"path": filepath.Join(t.TempDir(), "x")}))
+
+	summary = fsHandler.DestructiveSummary("batch", map[string]any{"operations": []any{
+		map[string]any{"op": "mkdir", "path": filepath.Join(dir, "docs")},
+		map[string]any{"op": "write", "path": filepath.Join(dir, "notes.txt"), "content": "x"},
+	}})
+	assert.Contains(t, summary, "Operation 2: Overwrite the existing file")
+	assert.NotContains(t, summary, "Operation 1")
+
+	assert.Contains(t, fsHandler.DestructiveSummary("modify_file", map[string]any{"path": filepath.Join(dir, "notes.txt")}),
+		"Edit the existing file")
+	summary = fsHandler.DestructiveSummary("copy_file", map[string]any{
+		"source":      filepath.Join(dir, "notes.txt"),
+		"destination": filepath.Join(dir, "src"),
+	})
+	assert.Contains(t, summary, "into the existing directory "+filepath.Join(dir, "src")+", which has 2 entry(ies)")
+	assert.Contains(t, summary, "main.go")
+	assert.Contains(t, fsHandler.DestructiveSummary("extract_archive", map[string]any{
+		"path":        filepath.Join(dir, "bundle.zip"),
+		"destination": dir,
+	}), "Extract "+filepath.Join(dir, "bundle.zip")+" into the existing directory")
+	assert.Empty(t, fsHandler.DestructiveSummary("extract_archive", map[string]any{
+		"path":        filepath.Join(dir, "bundle.zip"),
+		"destination": filepath.Join(dir, "new"),
+	}))
+}
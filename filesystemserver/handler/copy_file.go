@@ -69,7 +69,7 @@ func (fs *FilesystemHandler) HandleCopyFile(
 	}
 
 	// Check if source exists
-	srcInfo, err := os.Stat(validSource)
+	srcInfo, err := fs.backend.Stat(validSource)
 	if os.IsNotExist(err) {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
@@ -105,9 +105,16 @@ func (fs *FilesystemHandler) HandleCopyFile(
 		}, nil
 	}
 
+	// Record the destination's previous state so the copy can be undone
+	entry, err := fs.journalSnapshot(ctx, "copy_file", fmt.Sprintf("copy %s to %s", validSource, validDest), validDest)
+	if err != nil {
+		return journalErrorResult(err), nil
+	}
+
 	// Create parent directory for destination if it doesn't exist
 	destDir := filepath.Dir(validDest)
-	if err := os.MkdirAll(destDir, 0755); err != nil {
+	if err := mkdirAll(fs.backend, destDir, 0755); err != nil {
+		entry.discard()
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				mcp.TextContent{
@@ -122,7 +129,8 @@ func (fs *FilesystemHandler) HandleCopyFile(
 	// Perform the copy operation based on whether source is a file or directory
 	if srcInfo.IsDir() {
 		// It's a directory, copy recursively
-		if err := copyDir(validSource, validDest); err != nil {
+		if err := copyDir(fs.backend, validSource, validDest); err != nil {
+			entry.discard()
 			return &mcp.CallToolResult{
 				Content: []mcp.Content{
 					mcp.TextContent{
@@ -135,7 +143,8 @@ func (fs *FilesystemHandler) HandleCopyFile(
 		}
 	} else {
 		// It's a file, copy directly
-		if err := copyFile(validSource, validDest); err != nil {
+		if err := copyFile(fs.backend, validSource, validDest); err != nil {
+			entry.discard()
 			return &mcp.CallToolResult{
 				Content: []mcp.Content{
 					mcp.TextContent{
@@ -148,6 +157,8 @@ func (fs *FilesystemHandler) HandleCopyFile(
 		}
 	}
 
+	fs.journalCommit(entry)
+
 	resourceURI := pathToResourceURI(validDest)
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{
@@ -172,16 +183,16 @@ func (fs *FilesystemHandler) HandleCopyFile(
 }
 
 // copyFile copies a single file from src to dst
-func copyFile(src, dst string) error {
+func copyFile(b Backend, src, dst string) error {
 	// Open the source file
-	sourceFile, err := os.Open(src)
+	sourceFile, err := b.Open(src)
 	if err != nil {
 		return err
 	}
 	defer sourceFile.Close()
 
 	// Create the destination file
-	destFile, err := os.Create(dst)
+	destFile, err := b.OpenFile(dst, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
 	if err != nil {
 		return err
 	}
@@ -193,30 +204,30 @@ func copyFile(src, dst string) error {
 	}
 
 	// Get source file mode
-	sourceInfo, err := os.Stat(src)
+	sourceInfo, err := b.Stat(src)
 	if err != nil {
 		return err
 	}
 
 	// Set the same file mode on destination
-	return os.Chmod(dst, sourceInfo.Mode())
+	return b.Chmod(dst, sourceInfo.Mode())
 }
 
 // copyDir recursively copies a directory tree from src to dst
-func copyDir(src, dst string) error {
+func copyDir(b Backend, src, dst string) error {
 	// Get properties of source dir
-	srcInfo, err := os.Stat(src)
+	srcInfo, err := b.Stat(src)
 	if err != nil {
 		return err
 	}
 
 	// Create the destination directory with the same permissions
-	if err = os.MkdirAll(dst, srcInfo.Mode()); err != nil {
+	if err = mkdirAll(b, dst, srcInfo.Mode()); err != nil {
 		return err
 	}
 
 	// Read directory entries
-	entries, err := os.ReadDir(src)
+	entries, err := b.ReadDir(src)
 	if err != nil {
 		return err
 	}
@@ -233,11 +244,11 @@ func copyDir(src, dst string) error {
 
 		// Recursively copy subdirectories or copy files
 		if entry.IsDir() {
-			if err = copyDir(srcPath, dstPath); err != nil {
+			if err = copyDir(b, srcPath, dstPath); err != nil {
 				return err
 			}
 		} else {
-			if err = copyFile(srcPath, dstPath); err != nil {
+			if err = copyFile(b, srcPath, dstPath); err != nil {
 				return err
 			}
 		}
@@ -0,0 +1,294 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func (fs *FilesystemHandler) HandleCreateArchive(
+	ctx context.Context,
+	request mcp.CallToolRequest,
+) (*mcp.CallToolResult, error) {
+	paths, err := request.RequireStringSlice("paths")
+	if err != nil {
+		return nil, err
+	}
+	destination, err := request.RequireString("destination")
+	if err != nil {
+		return nil, err
+	}
+	include := request.GetStringSlice("include", nil)
+	exclude := request.GetStringSlice("exclude", nil)
+
+	if len(paths) == 0 {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: "Error: No files specified to archive",
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	validDest, err := fs.validatePath(destination)
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	if _, err := fs.backend.Lstat(validDest); err == nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error: Destination already exists: %s", destination),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	// The format defaults to the one implied by the destination's name
+	format := request.GetString("format", archiveFormat(validDest))
+	if format == "" {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: "Error: Cannot tell the archive format from the destination, set format to zip, tar, tar.gz or tar.zst",
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	filter, err := newArchiveFilter(include, exclude)
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	// Each source is packed under its base name
+	sources := make(map[string]string, len(paths))
+	var sourcePaths []string
+	for _, p := range paths {
+		validPath, err := fs.validatePath(p)
+		if err == nil {
+			_, err = fs.backend.Lstat(validPath)
+		}
+		if err != nil {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					mcp.TextContent{
+						Type: "text",
+						Text: fmt.Sprintf("Error: %v", err),
+					},
+				},
+				IsError: true,
+			}, nil
+		}
+		name := filepath.Base(validPath)
+		for _, other := range sources {
+			if other == name {
+				return &mcp.CallToolResult{
+					Content: []mcp.Content{
+						mcp.TextContent{
+							Type: "text",
+							Text: fmt.Sprintf("Error: More than one path is named %s in the archive", name),
+						},
+					},
+					IsError: true,
+				}, nil
+			}
+		}
+		sources[validPath] = name
+		sourcePaths = append(sourcePaths, validPath)
+	}
+
+	// Record that the archive did not exist so the creation can be undone
+	entry, err := fs.journalSnapshot(ctx, "create_archive", fmt.Sprintf("create archive %s", validDest), validDest)
+	if err != nil {
+		return journalErrorResult(err), nil
+	}
+
+	files, dirs, err := fs.writeArchive(validDest, format, sourcePaths, sources, filter)
+	if err != nil {
+		entry.discard()
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error creating archive: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	fs.journalCommit(entry)
+
+	var size int64
+	if info, err := fs.backend.Stat(validDest); err == nil {
+		size = info.Size()
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: fmt.Sprintf(
+					"Created %s archive %s with %d file(s) and %d directory(ies) (%d bytes)",
+					format, validDest, files, dirs, size,
+				),
+			},
+			mcp.EmbeddedResource{
+				Type: "resource",
+				Resource: mcp.TextResourceContents{
+					URI:      pathToResourceURI(validDest),
+					MIMEType: "text/plain",
+					Text:     fmt.Sprintf("Archive: %s (%d bytes)", validDest, size),
+				},
+			},
+		},
+	}, nil
+}
+
+// writeArchive packs sourcePaths, named by sources, into a new archive at
+// dest. A partly written archive is removed again on failure.
+func (fs *FilesystemHandler) writeArchive(
+	dest string,
+	format string,
+	sourcePaths []string,
+	sources map[string]string,
+	filter *archiveFilter,
+) (files int, dirs int, err error) {
+	if err := mkdirAll(fs.backend, filepath.Dir(dest), 0755); err != nil {
+		return 0, 0, err
+	}
+	f, err := fs.backend.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			fs.backend.Remove(dest)
+		}
+	}()
+
+	aw, err := newArchiveWriter(f, format)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// Directories are written once something in them is, so that include
+	// patterns do not leave empty directories behind
+	pending := make(map[string]os.FileInfo)
+	addDir := func(name string) error {
+		info, ok := pending[name]
+		if !ok {
+			return nil
+		}
+		delete(pending, name)
+		dirs++
+		return aw.add(name+"/", info, "", nil)
+	}
+	addParents := func(name string) error {
+		var parents []string
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			parents = append(parents, dir)
+		}
+		for i := len(parents) - 1; i >= 0; i-- {
+			if err := addDir(parents[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, source := range sourcePaths {
+		err = walk(fs.backend, source, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			// The archive may be created inside a directory it packs
+			if p == dest {
+				return nil
+			}
+			name := sources[source]
+			if rel, _ := filepath.Rel(source, p); rel != "." {
+				name = path.Join(name, filepath.ToSlash(rel))
+			}
+			if filter.excluded(name) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if info.IsDir() {
+				pending[name] = info
+				if len(filter.include) == 0 {
+					return addDir(name)
+				}
+				return nil
+			}
+			if !filter.included(name) {
+				return nil
+			}
+
+			var link string
+			switch {
+			case info.Mode()&os.ModeSymlink != 0:
+				if link, err = fs.backend.Readlink(p); err != nil {
+					return err
+				}
+			case !info.Mode().IsRegular():
+				// Devices, sockets and pipes have no content to pack
+				return nil
+			}
+			if err := addParents(name); err != nil {
+				return err
+			}
+
+			if link != "" {
+				files++
+				return aw.add(name, info, link, nil)
+			}
+			file, err := fs.backend.Open(p)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			files++
+			return aw.add(name, info, "", file)
+		})
+		if err != nil {
+			aw.Close()
+			return 0, 0, err
+		}
+	}
+	return files, dirs, aw.Close()
+}
@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateArchive(t *testing.T) {
+	tmpDir := t.TempDir()
+	allowedDirs := resolveAllowedDirs(t, tmpDir)
+	fsHandler, err := NewFilesystemHandler(allowedDirs)
+	require.NoError(t, err)
+	dir := allowedDirs[0]
+
+	build := filepath.Join(dir, "build")
+	require.NoError(t, os.MkdirAll(filepath.Join(build, "js", "vendor"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(build, "logs"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(build, "js", "app.js"), []byte("app"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(build, "js", "vendor", "lib.js"), []byte("lib"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(build, "run.sh"), []byte("#!/bin/sh\n"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(build, "logs", "build.log"), []byte("log"), 0644))
+	report := filepath.Join(dir, "report.txt")
+	require.NoError(t, os.WriteFile(report, []byte("report"), 0644))
+	modified := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	require.NoError(t, os.Chtimes(report, modified, modified))
+
+	create := func(t *testing.T, args map[string]any) *mcp.CallToolResult {
+		t.Helper()
+		req := mcp.CallToolRequest{}
+		req.Params.Arguments = args
+		res, err := fsHandler.HandleCreateArchive(context.Background(), req)
+		require.NoError(t, err)
+		return res
+	}
+
+	// tarEntries reads the names, modes and times of a tar archive
+	tarEntries := func(t *testing.T, r io.Reader) map[string]*tar.Header {
+		t.Helper()
+		entries := make(map[string]*tar.Header)
+		tr := tar.NewReader(r)
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				return entries
+			}
+			require.NoError(t, err)
+			entries[header.Name] = header
+		}
+	}
+
+	t.Run("zip", func(t *testing.T) {
+		dest := filepath.Join(dir, "handoff.zip")
+		res := create(t, map[string]any{"paths": []any{build, report}, "destination": dest})
+		require.False(t, res.IsError, "%v", res.Content)
+		assert.Contains(t, res.Content[0].(mcp.TextContent).Text, "Created zip archive")
+
+		zr, err := zip.OpenReader(dest)
+		require.NoError(t, err)
+		defer zr.Close()
+		entries := make(map[string]*zip.File)
+		for _, f := range zr.File {
+			entries[f.Name] = f
+		}
+		assert.Contains(t, entries, "build/")
+		assert.Contains(t, entries, "build/js/vendor/lib.js")
+		assert.Contains(t, entries, "build/logs/build.log")
+		require.Contains(t, entries, "report.txt")
+		assert.True(t, entries["report.txt"].Modified.Equal(modified))
+		if runtime.GOOS != "windows" {
+			assert.Equal(t, os.FileMode(0755), entries["build/run.sh"].Mode().Perm())
+		}
+
+		rc, err := entries["report.txt"].Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		require.NoError(t, err)
+		assert.Equal(t, "report", string(data))
+	})
+
+	t.Run("tar.gz with include and exclude", func(t *testing.T) {
+		dest := filepath.Join(dir, "js.tgz")
+		res := create(t, map[string]any{
+			"paths":       []any{build},
+			"destination": dest,
+			"include":     []any{"*.js"},
+			"exclude":     []any{"vendor"},
+		})
+		require.False(t, res.IsError, "%v", res.Content)
+		assert.Contains(t, res.Content[0].(mcp.TextContent).Text, "1 file(s) and 2 directory(ies)")
+
+		f, err := os.Open(dest)
+		require.NoError(t, err)
+		defer f.Close()
+		gz, err := gzip.NewReader(f)
+		require.NoError(t, err)
+		entries := tarEntries(t, gz)
+		assert.Len(t, entries, 3)
+		assert.Contains(t, entries, "build/")
+		assert.Contains(t, entries, "build/js/")
+		assert.Contains(t, entries, "build/js/app.js")
+	})
+
+	t.Run("tar.zst inside a packed directory", func(t *testing.T) {
+		dest := filepath.Join(build, "self.bin")
+		res := create(t, map[string]any{"paths": []any{build}, "destination": dest, "format": "tar.zst"})
+		require.False(t, res.IsError, "%v", res.Content)
+
+		f, err := os.Open(dest)
+		require.NoError(t, err)
+		defer f.Close()
+		zr, err := zstd.NewReader(f)
+		require.NoError(t, err)
+		defer zr.Close()
+		entries := tarEntries(t, zr)
+		assert.NotContains(t, entries, "build/self.bin")
+		require.Contains(t, entries, "build/run.sh")
+		if runtime.GOOS != "windows" {
+			assert.Equal(t, int64(0755), entries["build/run.sh"].Mode&0777)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		res := create(t, map[string]any{"paths": []any{report}, "destination": report})
+		assert.True(t, res.IsError)
+		assert.Contains(t, res.Content[0].(mcp.TextContent).Text, "already exists")
+
+		res = create(t, map[string]any{"paths": []any{report}, "destination": filepath.Join(dir, "report.rar")})
+		assert.True(t, res.IsError)
+		assert.Contains(t, res.Content[0].(mcp.TextContent).Text, "format")
+
+		res = create(t, map[string]any{"paths": []any{report}, "destination": filepath.Join(t.TempDir(), "out.zip")})
+		assert.True(t, res.IsError)
+
+		res = create(t, map[string]any{"paths": []any{filepath.Dir(dir)}, "destination": filepath.Join(dir, "all.zip")})
+		assert.True(t, res.IsError)
+		_, err := os.Stat(filepath.Join(dir, "all.zip"))
+		assert.True(t, os.IsNotExist(err))
+	})
+}
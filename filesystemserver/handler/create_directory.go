@@ -49,7 +49,7 @@ func (fs *FilesystemHandler) HandleCreateDirectory(
 	}
 
 	// Check if path already exists
-	if info, err := os.Stat(validPath); err == nil {
+	if info, err := fs.backend.Stat(validPath); err == nil {
 		if info.IsDir() {
 			resourceURI := pathToResourceURI(validPath)
 			return &mcp.CallToolResult{
@@ -80,7 +80,14 @@ func (fs *FilesystemHandler) HandleCreateDirectory(
 		}, nil
 	}
 
-	if err := os.MkdirAll(validPath, 0755); err != nil {
+	// Record which directories will be created so they can be removed on undo
+	entry, err := fs.journalSnapshot(ctx, "create_directory", fmt.Sprintf("create directory %s", validPath), validPath)
+	if err != nil {
+		return journalErrorResult(err), nil
+	}
+
+	if err := mkdirAll(fs.backend, validPath, 0755); err != nil {
+		entry.discard()
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				mcp.TextContent{
@@ -92,6 +99,8 @@ func (fs *FilesystemHandler) HandleCreateDirectory(
 		}, nil
 	}
 
+	fs.journalCommit(entry)
+
 	resourceURI := pathToResourceURI(validPath)
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{
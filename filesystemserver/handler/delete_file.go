@@ -49,7 +49,7 @@ func (fs *FilesystemHandler) HandleDeleteFile(
 	}
 
 	// Check if path exists
-	info, err := os.Stat(validPath)
+	info, err := fs.backend.Stat(validPath)
 	if os.IsNotExist(err) {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
@@ -78,22 +78,34 @@ func (fs *FilesystemHandler) HandleDeleteFile(
 		recursive = recursiveParam
 	}
 
-	// Check if it's a directory and handle accordingly
-	if info.IsDir() {
-		if !recursive {
-			return &mcp.CallToolResult{
-				Content: []mcp.Content{
-					mcp.TextContent{
-						Type: "text",
-						Text: fmt.Sprintf("Error: %s is a directory. Use recursive=true to delete directories.", path),
-					},
+	// Directories require the recursive flag
+	if info.IsDir() && !recursive {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error: %s is a directory. Use recursive=true to delete directories.", path),
 				},
-				IsError: true,
-			}, nil
-		}
+			},
+			IsError: true,
+		}, nil
+	}
+
+	// In trash mode, move the item into the trash instead of removing it
+	if fs.trash != nil {
+		return fs.moveToTrash(ctx, path, validPath, info.IsDir())
+	}
+
+	// Record the item's content so the delete can be undone
+	entry, err := fs.journalSnapshot(ctx, "delete_file", fmt.Sprintf("delete %s", validPath), validPath)
+	if err != nil {
+		return journalErrorResult(err), nil
+	}
 
+	if info.IsDir() {
 		// It's a directory and recursive is true, so remove it
-		if err := os.RemoveAll(validPath); err != nil {
+		if err := removeAll(fs.backend, validPath); err != nil {
+			entry.discard()
 			return &mcp.CallToolResult{
 				Content: []mcp.Content{
 					mcp.TextContent{
@@ -105,6 +117,8 @@ func (fs *FilesystemHandler) HandleDeleteFile(
 			}, nil
 		}
 
+		fs.journalCommit(entry)
+
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				mcp.TextContent{
@@ -116,7 +130,8 @@ func (fs *FilesystemHandler) HandleDeleteFile(
 	}
 
 	// It's a file, delete it
-	if err := os.Remove(validPath); err != nil {
+	if err := fs.backend.Remove(validPath); err != nil {
+		entry.discard()
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				mcp.TextContent{
@@ -128,6 +143,8 @@ func (fs *FilesystemHandler) HandleDeleteFile(
 		}, nil
 	}
 
+	fs.journalCommit(entry)
+
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{
 			mcp.TextContent{
@@ -137,3 +154,62 @@ func (fs *FilesystemHandler) HandleDeleteFile(
 		},
 	}, nil
 }
+
+// moveToTrash soft-deletes validPath by moving it into the trash
+func (fs *FilesystemHandler) moveToTrash(ctx context.Context, path, validPath string, isDir bool) (*mcp.CallToolResult, error) {
+	if fs.trash.contains(validPath) {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: "Error: Cannot delete items inside the trash. Use empty_trash instead.",
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	// Drop expired entries before adding new ones; failures here are not fatal
+	_, _ = fs.trash.purgeExpired()
+
+	// Record the trash entry so that undo can restore from it
+	undo, err := fs.journalBegin(ctx, "delete_file", fmt.Sprintf("delete %s (moved to trash)", validPath))
+	if err != nil {
+		return journalErrorResult(err), nil
+	}
+
+	entry, err := fs.trash.put(validPath)
+	if err != nil {
+		undo.discard()
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error moving to trash: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	undo.untrash(validPath, entry.ID)
+	fs.journalCommit(undo)
+
+	kind := "file"
+	if isDir {
+		kind = "directory"
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: fmt.Sprintf(
+					"Successfully moved %s %s to trash (id: %s). Use restore_from_trash to undo.",
+					kind,
+					path,
+					entry.ID,
+				),
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,289 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Operations of the edit_lines tool
+const (
+	EditLinesInsert  = "insert"
+	EditLinesReplace = "replace"
+	EditLinesDelete  = "delete"
+)
+
+func (fs *FilesystemHandler) HandleEditLines(
+	ctx context.Context,
+	request mcp.CallToolRequest,
+) (*mcp.CallToolResult, error) {
+	path, err := request.RequireString("path")
+	if err != nil {
+		return nil, err
+	}
+	operation, err := request.RequireString("operation")
+	if err != nil {
+		return nil, err
+	}
+
+	// insert adds content after line, replace and delete act on start..end
+	var start, end int
+	var content string
+	var numberErr error
+	switch operation {
+	case EditLinesInsert:
+		line, err := request.RequireFloat("line")
+		if err != nil {
+			return nil, err
+		}
+		numberErr = checkLineNumber("line", line)
+		start, end = int(line)+1, int(line)
+	case EditLinesReplace, EditLinesDelete:
+		startParam, err := request.RequireFloat("start")
+		if err != nil {
+			return nil, err
+		}
+		numberErr = checkLineNumber("start", startParam)
+		start = int(startParam)
+		end = start
+		if endParam, err := request.RequireFloat("end"); err == nil {
+			if numberErr == nil {
+				numberErr = checkLineNumber("end", endParam)
+			}
+			end = int(endParam)
+		}
+	default:
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error: Unknown operation %q (expected insert, replace or delete)", operation),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+	if operation != EditLinesDelete {
+		if content, err = request.RequireString("content"); err != nil {
+			return nil, err
+		}
+	}
+	if numberErr != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error: %v", numberErr),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	validPath, err := fs.validatePath(path)
+	if err == nil {
+		var info os.FileInfo
+		if info, err = fs.backend.Stat(validPath); err == nil && info.IsDir() {
+			err = fmt.Errorf("cannot edit a directory")
+		}
+	}
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	data, err := readFile(fs.backend, validPath)
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error reading file: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	// The file keeps its encoding and line endings, as with modify_file.
+	// Binary files have no lines to edit.
+	detected, ok := detectEncoding(data)
+	if !ok {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error: %s is not a text file", path),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+	enc := textEncoding{name: EncodingUTF8}
+	text := string(data)
+	if !detected.plain() {
+		if decoded, err := decodeText(data, detected); err == nil {
+			enc, text = detected, decoded
+		}
+	}
+	var endings *lineEndings
+	if text != "" {
+		e, _ := detectLineEndings(text)
+		endings = &e
+		if e.style != "" {
+			text, content = toLF(text), toLF(content)
+		}
+	}
+
+	lines := splitLines(text)
+	switch {
+	case operation == EditLinesInsert && (start < 1 || start > len(lines)+1):
+		err = fmt.Errorf("line %d is out of range, the file has %d line(s)", start-1, len(lines))
+	case operation != EditLinesInsert && (start < 1 || end < start || end > len(lines)):
+		err = fmt.Errorf("lines %d-%d are out of range, the file has %d line(s)", start, end, len(lines))
+	}
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	// The guard compares with the target lines, or for insert the line
+	// that content goes after
+	if expected, ok := request.GetArguments()["expected"].(string); ok {
+		from, to := start, end
+		if operation == EditLinesInsert {
+			from = end
+		}
+		current := ""
+		if from >= 1 {
+			current = strings.Join(lines[from-1:to], "")
+		}
+		if strings.TrimSuffix(toLF(current), "\n") != strings.TrimSuffix(toLF(expected), "\n") {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					mcp.TextContent{
+						Type: "text",
+						Text: fmt.Sprintf(
+							"Error: Lines %d-%d of %s do not contain the expected content, the file may have changed. Nothing was edited. Current content:\n%s",
+							from, to, path, current,
+						),
+					},
+				},
+				IsError: true,
+			}, nil
+		}
+	}
+
+	// Content without a final line break still makes whole lines
+	var inserted []string
+	if content != "" {
+		if !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		inserted = splitLines(content)
+	}
+	// Lines appended after a last line without a break need one in between
+	if operation == EditLinesInsert && len(inserted) > 0 && end >= 1 && end == len(lines) && !strings.HasSuffix(lines[end-1], "\n") {
+		lines[end-1] += "\n"
+	}
+	edited := strings.Join(lines[:start-1], "") + strings.Join(inserted, "") + strings.Join(lines[end:], "")
+
+	edited, err = applyLineEndings(request, edited, endings)
+	if err == nil {
+		data, err = encodeText(edited, enc)
+	}
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	// Record the original content so the edit can be undone
+	entry, err := fs.journalSnapshot(ctx, "edit_lines", fmt.Sprintf("edit lines of %s", validPath), validPath)
+	if err != nil {
+		return journalErrorResult(err), nil
+	}
+
+	if err := writeFile(fs.backend, validPath, data, 0644); err != nil {
+		entry.discard()
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error writing to file: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	fs.journalCommit(entry)
+
+	var summary string
+	switch operation {
+	case EditLinesInsert:
+		summary = fmt.Sprintf("Inserted %d line(s) after line %d", len(inserted), end)
+	case EditLinesReplace:
+		summary = fmt.Sprintf("Replaced lines %d-%d with %d line(s)", start, end, len(inserted))
+	case EditLinesDelete:
+		summary = fmt.Sprintf("Deleted lines %d-%d", start, end)
+	}
+	total := len(lines) - (end - start + 1) + len(inserted)
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: fmt.Sprintf("%s in %s, which now has %d line(s)", summary, path, total),
+			},
+			mcp.EmbeddedResource{
+				Type: "resource",
+				Resource: mcp.TextResourceContents{
+					URI:      pathToResourceURI(validPath),
+					MIMEType: "text/plain",
+					Text:     fmt.Sprintf("Edited file: %s (%d bytes)", validPath, len(data)),
+				},
+			},
+		},
+	}, nil
+}
+
+// checkLineNumber checks that the argument name is a whole number
+func checkLineNumber(name string, value float64) error {
+	if value != math.Trunc(value) || math.IsInf(value, 0) {
+		return fmt.Errorf("%s must be a whole number, got %v", name, value)
+	}
+	return nil
+}
+
+// splitLines splits text into lines that keep their line break
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEditLines(t *testing.T) {
+	tmpDir := t.TempDir()
+	allowedDirs := resolveAllowedDirs(t, tmpDir)
+	fsHandler, err := NewFilesystemHandler(allowedDirs)
+	require.NoError(t, err)
+	dir := allowedDirs[0]
+	path := filepath.Join(dir, "lines.txt")
+
+	edit := func(t *testing.T, args map[string]any) *mcp.CallToolResult {
+		t.Helper()
+		args["path"] = path
+		req := mcp.CallToolRequest{}
+		req.Params.Arguments = args
+		res, err := fsHandler.HandleEditLines(context.Background(), req)
+		require.NoError(t, err)
+		return res
+	}
+	assertContent := func(t *testing.T, want string) {
+		t.Helper()
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, want, string(content))
+	}
+
+	tests := []struct {
+		name     string
+		original string
+		args     map[string]any
+		want     string
+	}{
+		{"insert at start", "a\nb\n", map[string]any{"operation": "insert", "line": float64(0), "content": "x"}, "x\na\nb\n"},
+		{"insert in middle", "a\nb\n", map[string]any{"operation": "insert", "line": float64(1), "content": "x\ny\n"}, "a\nx\ny\nb\n"},
+		{"insert at end", "a\nb\n", map[string]any{"operation": "insert", "line": float64(2), "content": "x"}, "a\nb\nx\n"},
+		{"insert after last line without break", "a\nb", map[string]any{"operation": "insert", "line": float64(2), "content": "x"}, "a\nb\nx"},
+		{"insert into empty file", "", map[string]any{"operation": "insert", "line": float64(0), "content": "x"}, "x\n"},
+		{"replace one line", "a\nb\nc\n", map[string]any{"operation": "replace", "start": float64(2), "content": "B"}, "a\nB\nc\n"},
+		{"replace range", "a\nb\nc\nd\n", map[string]any{"operation": "replace", "start": float64(2), "end": float64(3), "content": "X\n"}, "a\nX\nd\n"},
+		{"replace with nothing", "a\nb\nc\n", map[string]any{"operation": "replace", "start": float64(2), "content": ""}, "a\nc\n"},
+		{"delete range", "a\nb\nc\nd\n", map[string]any{"operation": "delete", "start": float64(1), "end": float64(2)}, "c\nd\n"},
+		{"delete last line", "a\nb", map[string]any{"operation": "delete", "start": float64(2)}, "a"},
+		{"crlf", "a\r\nb\r\nc\r\n", map[string]any{"operation": "replace", "start": float64(2), "content": "x\ny", "expected": "b"}, "a\r\nx\r\ny\r\nc\r\n"},
+		{"expected", "a\nb\nc\n", map[string]any{"operation": "delete", "start": float64(1), "end": float64(2), "expected": "a\nb\n"}, "c\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, os.WriteFile(path, []byte(tt.original), 0644))
+			res := edit(t, tt.args)
+			require.False(t, res.IsError, res.Content[0].(mcp.TextContent).Text)
+			assertContent(t, tt.want)
+		})
+	}
+
+	t.Run("refused", func(t *testing.T) {
+		for _, args := range []map[string]any{
+			{"operation": "replace", "start": float64(2), "content": "x", "expected": "changed"},
+			{"operation": "insert", "line": float64(1), "content": "x", "expected": "b"},
+			{"operation": "delete", "start": float64(3), "end": float64(4)},
+			{"operation": "delete", "start": float64(2), "end": float64(1)},
+			{"operation": "insert", "line": float64(4), "content": "x"},
+			{"operation": "move", "start": float64(1)},
+			{"operation": "delete", "start": float64(1.5)},
+			{"operation": "replace", "start": float64(1), "end": float64(2.5), "content": "x"},
+			{"operation": "insert", "line": float64(0.5), "content": "x"},
+		} {
+			require.NoError(t, os.WriteFile(path, []byte("a\nb\nc\n"), 0644))
+			res := edit(t, args)
+			assert.True(t, res.IsError, "%v", args)
+			assertContent(t, "a\nb\nc\n")
+		}
+
+		res := edit(t, map[string]any{"operation": "replace", "start": float64(2), "content": "x", "expected": "changed"})
+		assert.Contains(t, res.Content[0].(mcp.TextContent).Text, "Current content:\nb\n")
+
+		// Binary files have no lines to edit
+		binary := []byte("a\x00b\n\x01\x02\n")
+		require.NoError(t, os.WriteFile(path, binary, 0644))
+		res = edit(t, map[string]any{"operation": "delete", "start": float64(1)})
+		assert.True(t, res.IsError)
+		assert.Contains(t, res.Content[0].(mcp.TextContent).Text, "not a text file")
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, binary, content)
+	})
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func (fs *FilesystemHandler) HandleEmptyTrash(
+	ctx context.Context,
+	request mcp.CallToolRequest,
+) (*mcp.CallToolResult, error) {
+	if fs.trash == nil {
+		return trashDisabledResult(), nil
+	}
+
+	// Remove a single entry when an id is given
+	if id, err := request.RequireString("id"); err == nil && id != "" {
+		if err := fs.trash.remove(id); err != nil {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					mcp.TextContent{
+						Type: "text",
+						Text: fmt.Sprintf("Error: %v", err),
+					},
+				},
+				IsError: true,
+			}, nil
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Permanently deleted trash entry %s", id),
+				},
+			},
+		}, nil
+	}
+
+	// Extract older_than_hours parameter (optional, default: remove everything)
+	var cutoff time.Time
+	if hours, err := request.RequireFloat("older_than_hours"); err == nil {
+		if hours < 0 {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					mcp.TextContent{
+						Type: "text",
+						Text: "Error: older_than_hours cannot be negative",
+					},
+				},
+				IsError: true,
+			}, nil
+		}
+		cutoff = time.Now().Add(-time.Duration(hours * float64(time.Hour)))
+	}
+
+	removed, err := fs.trash.purge(cutoff)
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error emptying trash after removing %d item(s): %v", removed, err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: fmt.Sprintf("Permanently deleted %d item(s) from trash", removed),
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,239 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// Supported text encodings
+const (
+	EncodingUTF8        = "utf-8"
+	EncodingUTF16LE     = "utf-16le"
+	EncodingUTF16BE     = "utf-16be"
+	EncodingLatin1      = "iso-8859-1"
+	EncodingWindows1252 = "windows-1252"
+)
+
+var textEncodings = map[string]encoding.Encoding{
+	EncodingUTF8:        unicode.UTF8,
+	EncodingUTF16LE:     unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM),
+	EncodingUTF16BE:     unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM),
+	EncodingLatin1:      charmap.ISO8859_1,
+	EncodingWindows1252: charmap.Windows1252,
+}
+
+// encodingBOMs are the byte order marks of the encodings that have one
+var encodingBOMs = map[string][]byte{
+	EncodingUTF8:    {0xEF, 0xBB, 0xBF},
+	EncodingUTF16LE: {0xFF, 0xFE},
+	EncodingUTF16BE: {0xFE, 0xFF},
+}
+
+// encodingAliases maps other common names to the supported encodings
+var encodingAliases = map[string]string{
+	"utf8":      EncodingUTF8,
+	"utf16le":   EncodingUTF16LE,
+	"utf16be":   EncodingUTF16BE,
+	"latin1":    EncodingLatin1,
+	"latin-1":   EncodingLatin1,
+	"iso8859-1": EncodingLatin1,
+	"cp1252":    EncodingWindows1252,
+}
+
+// maxEncodingSample bounds how much of a file is looked at to tell UTF-16
+// without a byte order mark
+const maxEncodingSample = 4096
+
+// textEncoding describes how the text of a file is stored
+type textEncoding struct {
+	name string
+	bom  bool
+}
+
+func (e textEncoding) String() string {
+	if e.bom {
+		return e.name + " with BOM"
+	}
+	return e.name
+}
+
+// plain reports whether the text is UTF-8 without a BOM, which is what tools
+// read and write without transcoding
+func (e textEncoding) plain() bool {
+	return e.name == EncodingUTF8 && !e.bom
+}
+
+// detectEncoding guesses the encoding of content from its byte order mark
+// or, failing that, from its bytes. ok is false for content that does not
+// look like text in any supported encoding.
+func detectEncoding(content []byte) (enc textEncoding, ok bool) {
+	for _, name := range []string{EncodingUTF8, EncodingUTF16LE, EncodingUTF16BE} {
+		if bytes.HasPrefix(content, encodingBOMs[name]) {
+			return textEncoding{name: name, bom: true}, true
+		}
+	}
+	if name := guessUTF16(content); name != "" {
+		text, err := textEncodings[name].NewDecoder().Bytes(content)
+		if err == nil && !hasControlChars(text) {
+			return textEncoding{name: name}, true
+		}
+	}
+	if hasControlChars(content) {
+		return textEncoding{}, false
+	}
+	if utf8.Valid(content) {
+		return textEncoding{name: EncodingUTF8}, true
+	}
+	// Windows-1252 uses the C1 range, where Latin-1 has only control codes
+	for _, b := range content {
+		if b >= 0x80 && b <= 0x9F {
+			return textEncoding{name: EncodingWindows1252}, true
+		}
+	}
+	return textEncoding{name: EncodingLatin1}, true
+}
+
+// detectTruncatedEncoding is detectEncoding for content cut off at an
+// arbitrary byte, where the last UTF-8 character may be incomplete. Such
+// content is only taken for a legacy encoding if the bytes before that
+// character are not UTF-8 either.
+func detectTruncatedEncoding(content []byte) (enc textEncoding, ok bool) {
+	enc, ok = detectEncoding(content)
+	if ok && (enc.name == EncodingLatin1 || enc.name == EncodingWindows1252) {
+		if trimmed := trimPartialRune(content); len(trimmed) < len(content) && utf8.Valid(trimmed) {
+			return textEncoding{name: EncodingUTF8}, true
+		}
+	}
+	return enc, ok
+}
+
+// isUTF16 reports whether enc is one of the UTF-16 encodings
+func isUTF16(enc textEncoding) bool {
+	return enc.name == EncodingUTF16LE || enc.name == EncodingUTF16BE
+}
+
+// guessUTF16 tells UTF-16 without a byte order mark by the zero bytes of
+// mostly ASCII text, returning its encoding or ""
+func guessUTF16(content []byte) string {
+	sample := content[:min(len(content), maxEncodingSample)]
+	if len(sample) < 2 || len(sample)%2 != 0 {
+		return ""
+	}
+	var evenZeros, oddZeros int
+	for i, b := range sample {
+		if b != 0 {
+			continue
+		}
+		if i%2 == 0 {
+			evenZeros++
+		} else {
+			oddZeros++
+		}
+	}
+	pairs := len(sample) / 2
+	switch {
+	case oddZeros*2 >= pairs && evenZeros*10 < pairs:
+		return EncodingUTF16LE
+	case evenZeros*2 >= pairs && oddZeros*10 < pairs:
+		return EncodingUTF16BE
+	}
+	return ""
+}
+
+// hasControlChars reports whether text contains control characters that do
+// not occur in text files
+func hasControlChars(text []byte) bool {
+	for _, b := range text {
+		if b < 0x20 && b != '\t' && b != '\n' && b != '\r' && b != '\f' && b != '\v' && b != 0x1B {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeText transcodes content stored in enc to UTF-8, without the BOM
+func decodeText(content []byte, enc textEncoding) (string, error) {
+	if enc.bom {
+		content = bytes.TrimPrefix(content, encodingBOMs[enc.name])
+	}
+	if enc.name == EncodingUTF8 {
+		return string(content), nil
+	}
+	text, err := textEncodings[enc.name].NewDecoder().Bytes(content)
+	if err != nil {
+		return "", fmt.Errorf("error decoding %s: %w", enc.name, err)
+	}
+	return string(text), nil
+}
+
+// encodeText transcodes text to enc, adding the BOM if enc has one
+func encodeText(text string, enc textEncoding) ([]byte, error) {
+	var content []byte
+	if enc.bom {
+		content = append(content, encodingBOMs[enc.name]...)
+	}
+	if enc.name == EncodingUTF8 {
+		return append(content, text...), nil
+	}
+	encoded, err := textEncodings[enc.name].NewEncoder().Bytes([]byte(text))
+	if err != nil {
+		return nil, fmt.Errorf("the content cannot be encoded as %s: %w", enc.name, err)
+	}
+	return append(content, encoded...), nil
+}
+
+// decodeFileText returns content as UTF-8 text along with its encoding. ok
+// is false for content that is not text according to mimeType, except for
+// UTF-16 without a BOM, which MIME detection takes for binary.
+func decodeFileText(content []byte, mimeType string) (text string, enc textEncoding, ok bool) {
+	enc, detected := detectEncoding(content)
+	if !isTextFile(mimeType) && !(detected && isUTF16(enc)) {
+		return "", enc, false
+	}
+	if detected && !enc.plain() {
+		if text, err := decodeText(content, enc); err == nil {
+			return text, enc, true
+		}
+	}
+	return string(content), textEncoding{name: EncodingUTF8}, true
+}
+
+// transcodedMimeType is the MIME type of text transcoded to UTF-8
+func transcodedMimeType(mimeType string) string {
+	mimeType = strings.TrimSpace(strings.Split(mimeType, ";")[0])
+	if !isTextFile(mimeType) {
+		mimeType = "text/plain"
+	}
+	return mimeType + "; charset=utf-8"
+}
+
+// requestEncoding returns the encoding to write with, which is current
+// unless the request sets encoding or bom. A changed encoding has no BOM
+// unless bom asks for one.
+func requestEncoding(request mcp.CallToolRequest, current textEncoding) (textEncoding, error) {
+	enc := current
+	if name := request.GetString("encoding", ""); name != "" {
+		name = strings.ToLower(name)
+		if alias, ok := encodingAliases[name]; ok {
+			name = alias
+		}
+		if _, ok := textEncodings[name]; !ok {
+			return enc, fmt.Errorf("unsupported encoding %q (expected utf-8, utf-16le, utf-16be, iso-8859-1 or windows-1252)", name)
+		}
+		if name != current.name {
+			enc = textEncoding{name: name}
+		}
+	}
+	enc.bom = request.GetBool("bom", enc.bom)
+	if _, ok := encodingBOMs[enc.name]; enc.bom && !ok {
+		return enc, fmt.Errorf("%s has no byte order mark", enc.name)
+	}
+	return enc, nil
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectEncoding(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		want    textEncoding
+		ok      bool
+	}{
+		{"ascii", []byte("hello\n"), textEncoding{name: EncodingUTF8}, true},
+		{"utf-8", []byte("héllo\n"), textEncoding{name: EncodingUTF8}, true},
+		{"utf-8 bom", []byte("\xEF\xBB\xBFhello"), textEncoding{name: EncodingUTF8, bom: true}, true},
+		{"utf-16le bom", []byte("\xFF\xFEh\x00i\x00"), textEncoding{name: EncodingUTF16LE, bom: true}, true},
+		{"utf-16be bom", []byte("\xFE\xFF\x00h\x00i"), textEncoding{name: EncodingUTF16BE, bom: true}, true},
+		{"utf-16le", []byte("h\x00e\x00l\x00l\x00o\x00\n\x00"), textEncoding{name: EncodingUTF16LE}, true},
+		{"utf-16be", []byte("\x00h\x00e\x00l\x00l\x00o\x00\n"), textEncoding{name: EncodingUTF16BE}, true},
+		{"latin-1", []byte("caf\xE9\n"), textEncoding{name: EncodingLatin1}, true},
+		{"windows-1252", []byte("\x93quoted\x94 caf\xE9\n"), textEncoding{name: EncodingWindows1252}, true},
+		{"binary", []byte("\x7FELF\x02\x01\x01\x00\x00\x00\x00\x00\x03\x00"), textEncoding{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enc, ok := detectEncoding(tt.content)
+			assert.Equal(t, tt.ok, ok)
+			if tt.ok {
+				assert.Equal(t, tt.want, enc)
+			}
+		})
+	}
+}
+
+func TestEncodingRoundTrip(t *testing.T) {
+	text := "naïve café\n"
+	for _, enc := range []textEncoding{
+		{name: EncodingUTF8, bom: true},
+		{name: EncodingUTF16LE, bom: true},
+		{name: EncodingUTF16BE},
+		{name: EncodingLatin1},
+		{name: EncodingWindows1252},
+	} {
+		content, err := encodeText(text, enc)
+		require.NoError(t, err, enc.String())
+		decoded, err := decodeText(content, enc)
+		require.NoError(t, err, enc.String())
+		assert.Equal(t, text, decoded, enc.String())
+	}
+
+	_, err := encodeText("snowman ☃", textEncoding{name: EncodingLatin1})
+	assert.Error(t, err)
+}
+
+func TestEncodingPreservation(t *testing.T) {
+	tmpDir := t.TempDir()
+	allowedDirs := resolveAllowedDirs(t, tmpDir)
+	fsHandler, err := NewFilesystemHandler(allowedDirs)
+	require.NoError(t, err)
+	dir := allowedDirs[0]
+
+	call := func(t *testing.T, handle func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error), args map[string]any) *mcp.CallToolResult {
+		t.Helper()
+		req := mcp.CallToolRequest{}
+		req.Params.Arguments = args
+		res, err := handle(context.Background(), req)
+		require.NoError(t, err)
+		return res
+	}
+
+	t.Run("read transcodes", func(t *testing.T) {
+		path := filepath.Join(dir, "utf16.txt")
+		require.NoError(t, os.WriteFile(path, []byte("\xFF\xFEh\x00\xE9\x00\n\x00"), 0644))
+		res := call(t, fsHandler.HandleReadFile, map[string]any{"path": path})
+		require.False(t, res.IsError)
+		require.Len(t, res.Content, 2)
+		assert.Equal(t, "hé\n", res.Content[0].(mcp.TextContent).Text)
+		assert.Contains(t, res.Content[1].(mcp.TextContent).Text, "utf-16le with BOM")
+
+		path = filepath.Join(dir, "nobom.txt")
+		require.NoError(t, os.WriteFile(path, []byte("h\x00e\x00l\x00l\x00o\x00\n\x00"), 0644))
+		res = call(t, fsHandler.HandleReadFile, map[string]any{"path": path})
+		require.False(t, res.IsError)
+		assert.Equal(t, "hello\n", res.Content[0].(mcp.TextContent).Text)
+
+		path = filepath.Join(dir, "plain.txt")
+		require.NoError(t, os.WriteFile(path, []byte("plain\n"), 0644))
+		res = call(t, fsHandler.HandleReadFile, map[string]any{"path": path})
+		require.False(t, res.IsError)
+		assert.Len(t, res.Content, 1)
+	})
+
+	t.Run("modify keeps encoding", func(t *testing.T) {
+		path := filepath.Join(dir, "latin1.txt")
+		require.NoError(t, os.WriteFile(path, []byte("caf\xE9 au lait\n"), 0644))
+		res := call(t, fsHandler.HandleModifyFile, map[string]any{"path": path, "find": "café", "replace": "thé"})
+		require.False(t, res.IsError, res.Content[0].(mcp.TextContent).Text)
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("th\xE9 au lait\n"), content)
+
+		res = call(t, fsHandler.HandleModifyFile, map[string]any{"path": path, "find": "thé", "replace": "☃"})
+		assert.True(t, res.IsError)
+		assert.Contains(t, res.Content[0].(mcp.TextContent).Text, "cannot be encoded as iso-8859-1")
+
+		res = call(t, fsHandler.HandleModifyFile, map[string]any{"path": path, "find": "thé", "replace": "☃", "encoding": "utf-8"})
+		require.False(t, res.IsError)
+		content, err = os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "☃ au lait\n", string(content))
+	})
+
+	t.Run("write keeps encoding and bom", func(t *testing.T) {
+		path := filepath.Join(dir, "bom.txt")
+		require.NoError(t, os.WriteFile(path, []byte("\xFF\xFEo\x00l\x00d\x00"), 0644))
+		res := call(t, fsHandler.HandleWriteFile, map[string]any{"path": path, "content": "new"})
+		require.False(t, res.IsError)
+		assert.Contains(t, res.Content[0].(mcp.TextContent).Text, "utf-16le with BOM")
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("\xFF\xFEn\x00e\x00w\x00"), content)
+
+		res = call(t, fsHandler.HandleWriteFile, map[string]any{"path": path, "content": "new", "encoding": "utf8", "bom": true})
+		require.False(t, res.IsError)
+		content, err = os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("\xEF\xBB\xBFnew"), content)
+
+		res = call(t, fsHandler.HandleWriteFile, map[string]any{"path": path, "content": "new", "encoding": "ebcdic"})
+		assert.True(t, res.IsError)
+		res = call(t, fsHandler.HandleWriteFile, map[string]any{"path": path, "content": "new", "encoding": "latin1", "bom": true})
+		assert.True(t, res.IsError)
+	})
+}
@@ -0,0 +1,350 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func (fs *FilesystemHandler) HandleExtractArchive(
+	ctx context.Context,
+	request mcp.CallToolRequest,
+) (*mcp.CallToolResult, error) {
+	archivePath, err := request.RequireString("path")
+	if err != nil {
+		return nil, err
+	}
+	destination, err := request.RequireString("destination")
+	if err != nil {
+		return nil, err
+	}
+
+	validArchive, err := fs.validatePath(archivePath)
+	if err == nil {
+		var info os.FileInfo
+		if info, err = fs.backend.Stat(validArchive); err == nil && info.IsDir() {
+			err = fmt.Errorf("%s is a directory", validArchive)
+		}
+	}
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	// The format defaults to the one implied by the archive's name
+	format := request.GetString("format", archiveFormat(validArchive))
+	if format == "" {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: "Error: Cannot tell the archive format from its name, set format to zip, tar, tar.gz or tar.zst",
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	validDest, err := fs.validatePath(destination)
+	if err == nil {
+		if info, statErr := fs.backend.Stat(validDest); statErr == nil && !info.IsDir() {
+			err = fmt.Errorf("destination %s is not a directory", validDest)
+		}
+	}
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	// Check every entry before anything is written
+	created, err := fs.checkArchive(validArchive, format, validDest)
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error: Refusing to extract %s: %v. Nothing was extracted.", validArchive, err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	// Record what did not exist so the extraction can be undone
+	entry, err := fs.journalSnapshot(ctx, "extract_archive",
+		fmt.Sprintf("extract %s to %s", validArchive, validDest), created...)
+	if err != nil {
+		return journalErrorResult(err), nil
+	}
+
+	report, err := fs.extractArchive(validArchive, format, validDest)
+	// A failed extraction may have written files, which undo removes again
+	fs.journalCommit(entry)
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error extracting %s: %v\n\n%s", validArchive, err, report),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: report.String(),
+			},
+		},
+	}, nil
+}
+
+// extractReport lists what an extraction wrote
+type extractReport struct {
+	source, dest       string
+	files, dirs, links []string
+	skipped            []string
+	size               int64
+}
+
+func (r *extractReport) String() string {
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf(
+		"Extracted %d file(s) (%d bytes), %d directory(ies) and %d symlink(s) from %s to %s\n",
+		len(r.files), r.size, len(r.dirs), len(r.links), r.source, r.dest,
+	))
+	for _, file := range r.files {
+		result.WriteString("\n[FILE] " + file)
+	}
+	for _, link := range r.links {
+		result.WriteString("\n[LINK] " + link)
+	}
+	for _, skipped := range r.skipped {
+		result.WriteString("\n[SKIPPED] " + skipped + " (unsupported entry type)")
+	}
+	return result.String()
+}
+
+// archiveEntryName returns the cleaned name of entry, rejecting names that
+// are absolute or leave the destination and symlinks pointing outside it
+func archiveEntryName(entry archiveEntry) (string, error) {
+	name := strings.TrimSuffix(strings.ReplaceAll(entry.name, "\\", "/"), "/")
+	if name == "" || path.IsAbs(name) || !filepath.IsLocal(filepath.FromSlash(name)) {
+		return "", fmt.Errorf("entry %q has an absolute or unsafe path", entry.name)
+	}
+	name = path.Clean(name)
+
+	if entry.mode&os.ModeSymlink != 0 {
+		link := strings.ReplaceAll(entry.link, "\\", "/")
+		target := path.Join(path.Dir(name), link)
+		if link == "" || path.IsAbs(link) || filepath.VolumeName(filepath.FromSlash(link)) != "" ||
+			!filepath.IsLocal(filepath.FromSlash(target)) {
+			return "", fmt.Errorf("symlink %q points outside the destination (%s)", entry.name, entry.link)
+		}
+	}
+	return name, nil
+}
+
+// checkArchive validates every entry of the archive against the limits and
+// the destination without writing anything. It returns the paths that the
+// extraction creates.
+func (fs *FilesystemHandler) checkArchive(archivePath, format, dest string) ([]string, error) {
+	var entries int
+	var size int64
+	var created []string
+	seen := make(map[string]bool)
+	err := readArchive(fs.backend, archivePath, format, fs.limits.MaxExtractSize, func(entry archiveEntry, r io.Reader) error {
+		entries++
+		if entries > fs.limits.MaxExtractEntries {
+			return fmt.Errorf("the archive has more than %d entries", fs.limits.MaxExtractEntries)
+		}
+		if entry.mode.IsRegular() {
+			size += entry.size
+			if size > fs.limits.MaxExtractSize {
+				return fmt.Errorf("the archive unpacks to more than %d bytes", fs.limits.MaxExtractSize)
+			}
+		}
+
+		name, err := archiveEntryName(entry)
+		if err != nil {
+			return err
+		}
+		if entry.mode&os.ModeIrregular != 0 {
+			return nil
+		}
+		if seen[name] && !entry.mode.IsDir() {
+			return fmt.Errorf("entry %q appears more than once", entry.name)
+		}
+		seen[name] = true
+
+		// The name is local, so the target is inside the destination until
+		// symlinks come into play, which extractTarget checks for
+		target := filepath.Join(dest, filepath.FromSlash(name))
+		info, err := fs.backend.Lstat(target)
+		switch {
+		case os.IsNotExist(err):
+			if missing := topmostMissing(fs.backend, target); !slices.Contains(created, missing) {
+				created = append(created, missing)
+			}
+		case err != nil:
+			return err
+		case !entry.mode.IsDir() || !info.IsDir():
+			return fmt.Errorf("%s already exists", target)
+		}
+		return nil
+	})
+	return created, err
+}
+
+// extractArchive writes the entries of the archive below dest. Symlinks are
+// created last so that no entry is written through one, and directory modes
+// and times are set after their content is written.
+func (fs *FilesystemHandler) extractArchive(archivePath, format, dest string) (*extractReport, error) {
+	report := &extractReport{source: archivePath, dest: dest}
+	var links, dirs []archiveEntry
+	remaining := fs.limits.MaxExtractSize
+
+	err := readArchive(fs.backend, archivePath, format, fs.limits.MaxExtractSize, func(entry archiveEntry, r io.Reader) error {
+		name, err := archiveEntryName(entry)
+		if err != nil {
+			return err
+		}
+		entry.name = name
+
+		switch {
+		case entry.mode&os.ModeIrregular != 0:
+			report.skipped = append(report.skipped, name)
+			return nil
+		case entry.mode&os.ModeSymlink != 0:
+			links = append(links, entry)
+			return nil
+		}
+
+		target, err := fs.extractTarget(dest, name)
+		if err != nil {
+			return err
+		}
+		if entry.mode.IsDir() {
+			if err := fs.backend.Mkdir(target, 0755); err != nil && !os.IsExist(err) {
+				return err
+			}
+			dirs = append(dirs, entry)
+			report.dirs = append(report.dirs, target)
+			return nil
+		}
+
+		f, err := fs.backend.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return err
+		}
+		// The declared sizes may lie, so the limit is enforced while copying
+		n, err := io.CopyN(f, r, remaining+1)
+		if closeErr := f.Close(); err == nil || err == io.EOF {
+			err = closeErr
+		}
+		remaining -= n
+		report.files = append(report.files, fmt.Sprintf("%s (%d bytes)", target, n))
+		report.size += n
+		if err != nil {
+			return err
+		}
+		if remaining < 0 {
+			return fmt.Errorf("the archive unpacks to more than %d bytes", fs.limits.MaxExtractSize)
+		}
+		if err := fs.backend.Chmod(target, entry.mode.Perm()); err != nil {
+			return err
+		}
+		return chtimes(fs.backend, target, entry.modified)
+	})
+	if err != nil {
+		return report, err
+	}
+
+	for _, link := range links {
+		target, err := fs.extractTarget(dest, link.name)
+		if err != nil {
+			return report, err
+		}
+		if err := fs.backend.Symlink(filepath.FromSlash(link.link), target); err != nil {
+			return report, err
+		}
+		// Links through other links can still resolve outside the destination
+		if real, err := evalSymlinks(fs.backend, target); err == nil && real != dest && !isAncestor(dest, real) {
+			fs.backend.Remove(target)
+			return report, fmt.Errorf("symlink %s resolves outside the destination (%s)", link.name, real)
+		}
+		report.links = append(report.links, fmt.Sprintf("%s -> %s", target, link.link))
+	}
+
+	// Deepest first, as read-only directories would prevent writing children
+	for i := len(dirs) - 1; i >= 0; i-- {
+		target := filepath.Join(dest, filepath.FromSlash(dirs[i].name))
+		if err := fs.backend.Chmod(target, dirs[i].mode.Perm()); err != nil {
+			return report, err
+		}
+		_ = chtimes(fs.backend, target, dirs[i].modified)
+	}
+	return report, nil
+}
+
+// extractTarget creates the missing parents of the entry name below dest
+// and returns its validated path. Parents must be real directories, so no
+// entry is written through a symlink.
+func (fs *FilesystemHandler) extractTarget(dest, name string) (string, error) {
+	if err := mkdirAll(fs.backend, dest, 0755); err != nil {
+		return "", err
+	}
+	dir := dest
+	if parent := path.Dir(name); parent != "." {
+		for _, part := range strings.Split(parent, "/") {
+			dir = filepath.Join(dir, part)
+			info, err := fs.backend.Lstat(dir)
+			if os.IsNotExist(err) {
+				if err := fs.backend.Mkdir(dir, 0755); err != nil {
+					return "", err
+				}
+				continue
+			}
+			if err != nil {
+				return "", err
+			}
+			if !info.IsDir() {
+				return "", fmt.Errorf("%s is not a directory", dir)
+			}
+		}
+	}
+
+	target, err := fs.validatePath(filepath.Join(dest, filepath.FromSlash(name)))
+	if err != nil {
+		return "", err
+	}
+	if target != dest && !isAncestor(dest, target) {
+		return "", errors.New("entry " + name + " resolves outside the destination")
+	}
+	return target, nil
+}
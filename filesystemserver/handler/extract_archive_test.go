@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tarEntry describes an entry of a tar archive built by a test
+type tarEntry struct {
+	name, content, link string
+	typeflag            byte
+}
+
+func writeTestTar(t *testing.T, path string, entries ...tarEntry) {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.content)), Linkname: e.link, Typeflag: e.typeflag}
+		if header.Typeflag == 0 {
+			header.Typeflag = tar.TypeReg
+		}
+		require.NoError(t, tw.WriteHeader(header))
+		_, err := tw.Write([]byte(e.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0644))
+}
+
+func TestExtractArchive(t *testing.T) {
+	tmpDir := t.TempDir()
+	allowedDirs := resolveAllowedDirs(t, tmpDir)
+	fsHandler, err := NewFilesystemHandler(allowedDirs)
+	require.NoError(t, err)
+	dir := allowedDirs[0]
+
+	extract := func(t *testing.T, args map[string]any) *mcp.CallToolResult {
+		t.Helper()
+		req := mcp.CallToolRequest{}
+		req.Params.Arguments = args
+		res, err := fsHandler.HandleExtractArchive(context.Background(), req)
+		require.NoError(t, err)
+		return res
+	}
+	refused := func(t *testing.T, archive, dest, reason string) {
+		t.Helper()
+		res := extract(t, map[string]any{"path": archive, "destination": dest})
+		require.True(t, res.IsError)
+		text := res.Content[0].(mcp.TextContent).Text
+		assert.Contains(t, text, reason)
+		assert.Contains(t, text, "Nothing was extracted")
+		_, err := os.Stat(dest)
+		assert.True(t, os.IsNotExist(err), "destination must not be created")
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		src := filepath.Join(dir, "src")
+		require.NoError(t, os.MkdirAll(filepath.Join(src, "bin"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(src, "bin", "tool"), []byte("#!/bin/sh\n"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(src, "data.txt"), []byte("data"), 0644))
+		modified := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+		require.NoError(t, os.Chtimes(filepath.Join(src, "data.txt"), modified, modified))
+
+		for _, archive := range []string{"src.zip", "src.tar.gz", "src.tar.zst"} {
+			archivePath := filepath.Join(dir, archive)
+			req := mcp.CallToolRequest{}
+			req.Params.Arguments = map[string]any{"paths": []any{src}, "destination": archivePath}
+			res, err := fsHandler.HandleCreateArchive(context.Background(), req)
+			require.NoError(t, err)
+			require.False(t, res.IsError, "%v", res.Content)
+
+			dest := filepath.Join(dir, "out-"+archive)
+			res = extract(t, map[string]any{"path": archivePath, "destination": dest})
+			require.False(t, res.IsError, "%v", res.Content)
+			text := res.Content[0].(mcp.TextContent).Text
+			assert.Contains(t, text, "Extracted 2 file(s) (14 bytes), 2 directory(ies)")
+			assert.Contains(t, text, "[FILE] "+filepath.Join(dest, "src", "data.txt")+" (4 bytes)")
+
+			data, err := os.ReadFile(filepath.Join(dest, "src", "data.txt"))
+			require.NoError(t, err)
+			assert.Equal(t, "data", string(data))
+			info, err := os.Stat(filepath.Join(dest, "src", "data.txt"))
+			require.NoError(t, err)
+			assert.True(t, info.ModTime().Equal(modified), archive)
+			if runtime.GOOS != "windows" {
+				info, err = os.Stat(filepath.Join(dest, "src", "bin", "tool"))
+				require.NoError(t, err)
+				assert.Equal(t, os.FileMode(0755), info.Mode().Perm(), archive)
+			}
+
+			// Extracting again would overwrite the files
+			res = extract(t, map[string]any{"path": archivePath, "destination": dest})
+			assert.True(t, res.IsError)
+			assert.Contains(t, res.Content[0].(mcp.TextContent).Text, "already exists")
+		}
+	})
+
+	t.Run("zip slip", func(t *testing.T) {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		w, err := zw.Create("ok.txt")
+		require.NoError(t, err)
+		w.Write([]byte("ok"))
+		w, err = zw.Create("../../evil.txt")
+		require.NoError(t, err)
+		w.Write([]byte("evil"))
+		require.NoError(t, zw.Close())
+		archive := filepath.Join(dir, "slip.zip")
+		require.NoError(t, os.WriteFile(archive, buf.Bytes(), 0644))
+
+		refused(t, archive, filepath.Join(dir, "slip"), "unsafe path")
+	})
+
+	t.Run("absolute path", func(t *testing.T) {
+		archive := filepath.Join(dir, "abs.tar")
+		writeTestTar(t, archive, tarEntry{name: "/tmp/evil.txt", content: "evil"})
+		refused(t, archive, filepath.Join(dir, "abs"), "unsafe path")
+	})
+
+	t.Run("escaping symlink", func(t *testing.T) {
+		archive := filepath.Join(dir, "link.tar")
+		writeTestTar(t, archive, tarEntry{name: "sub/link", link: "../../outside", typeflag: tar.TypeSymlink})
+		refused(t, archive, filepath.Join(dir, "link"), "points outside the destination")
+
+		archive = filepath.Join(dir, "abslink.tar")
+		writeTestTar(t, archive, tarEntry{name: "link", link: "/etc", typeflag: tar.TypeSymlink})
+		refused(t, archive, filepath.Join(dir, "abslink"), "points outside the destination")
+	})
+
+	t.Run("symlink chain resolving outside", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("creating symlinks needs privileges on Windows")
+		}
+		archive := filepath.Join(dir, "chain.tar")
+		writeTestTar(t, archive,
+			tarEntry{name: "sub/here", link: "..", typeflag: tar.TypeSymlink},
+			tarEntry{name: "sub/up", link: "here/..", typeflag: tar.TypeSymlink},
+		)
+		res := extract(t, map[string]any{"path": archive, "destination": filepath.Join(dir, "chain")})
+		require.True(t, res.IsError)
+		assert.Contains(t, res.Content[0].(mcp.TextContent).Text, "resolves outside the destination")
+		_, err := os.Lstat(filepath.Join(dir, "chain", "sub", "up"))
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("limits", func(t *testing.T) {
+		archive := filepath.Join(dir, "big.tar")
+		writeTestTar(t, archive,
+			tarEntry{name: "a.txt", content: "aaaa"},
+			tarEntry{name: "b.txt", content: "bbbb"},
+			tarEntry{name: "c.txt", content: "cccc"},
+		)
+
+		fsHandler.SetLimits(Limits{MaxExtractEntries: 2})
+		refused(t, archive, filepath.Join(dir, "big"), "more than 2 entries")
+		fsHandler.SetLimits(Limits{MaxExtractEntries: MAX_EXTRACT_ENTRIES, MaxExtractSize: 10})
+		refused(t, archive, filepath.Join(dir, "big"), "more than 10 bytes")
+		fsHandler.SetLimits(DefaultLimits())
+
+		res := extract(t, map[string]any{"path": archive, "destination": filepath.Join(dir, "big")})
+		assert.False(t, res.IsError, "%v", res.Content)
+
+		// The zstd window may not exceed what the archive is allowed to unpack to
+		tarData, err := os.ReadFile(archive)
+		require.NoError(t, err)
+		var compressed bytes.Buffer
+		zw, err := zstd.NewWriter(&compressed, zstd.WithWindowSize(1<<20))
+		require.NoError(t, err)
+		_, err = zw.Write(tarData)
+		require.NoError(t, err)
+		// Flushing makes it a streamed frame that declares its window size
+		require.NoError(t, zw.Flush())
+		require.NoError(t, zw.Close())
+		windowed := filepath.Join(dir, "window.tar.zst")
+		require.NoError(t, os.WriteFile(windowed, compressed.Bytes(), 0644))
+
+		fsHandler.SetLimits(Limits{MaxExtractSize: 64 << 10})
+		refused(t, windowed, filepath.Join(dir, "window"), "window size exceeded")
+		fsHandler.SetLimits(DefaultLimits())
+
+		res = extract(t, map[string]any{"path": windowed, "destination": filepath.Join(dir, "window")})
+		assert.False(t, res.IsError, "%v", res.Content)
+	})
+
+	t.Run("outside the allowed directories", func(t *testing.T) {
+		archive := filepath.Join(dir, "big.tar")
+		res := extract(t, map[string]any{"path": archive, "destination": filepath.Join(t.TempDir(), "out")})
+		assert.True(t, res.IsError)
+		assert.Contains(t, res.Content[0].(mcp.TextContent).Text, "access denied")
+	})
+}
@@ -66,7 +66,7 @@ func (fs *FilesystemHandler) HandleGetFileInfo(
 	// Get MIME type for files
 	mimeType := "directory"
 	if info.IsFile {
-		mimeType = detectMimeType(validPath)
+		mimeType = fs.detectMimeType(validPath)
 	}
 
 	resourceURI := pathToResourceURI(validPath)
@@ -114,28 +114,32 @@ func (fs *FilesystemHandler) HandleGetFileInfo(
 }
 
 func (fs *FilesystemHandler) getFileStats(path string) (FileInfo, error) {
-	info, err := os.Stat(path)
+	info, err := fs.backend.Stat(path)
 	if err != nil {
 		return FileInfo{}, err
 	}
 
+	stats := FileInfo{
+		Size:        info.Size(),
+		Modified:    info.ModTime(),
+		Accessed:    info.ModTime(),
+		IsDirectory: info.IsDir(),
+		IsFile:      !info.IsDir(),
+		Permissions: fmt.Sprintf("%o", info.Mode().Perm()),
+	}
+	// Other backends only record the modification time
+	if !fs.localDisk() {
+		return stats, nil
+	}
+
 	timespec, err := times.Stat(path)
 	if err != nil {
 		return FileInfo{}, fmt.Errorf("failed to get file times: %w", err)
 	}
-
-	createdTime := time.Time{}
 	if timespec.HasBirthTime() {
-		createdTime = timespec.BirthTime()
+		stats.Created = timespec.BirthTime()
 	}
-
-	return FileInfo{
-		Size:        info.Size(),
-		Created:     createdTime,
-		Modified:    timespec.ModTime(),
-		Accessed:    timespec.AccessTime(),
-		IsDirectory: info.IsDir(),
-		IsFile:      !info.IsDir(),
-		Permissions: fmt.Sprintf("%o", info.Mode().Perm()),
-	}, nil
+	stats.Modified = timespec.ModTime()
+	stats.Accessed = timespec.AccessTime()
+	return stats, nil
 }
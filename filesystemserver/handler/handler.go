@@ -2,24 +2,68 @@ package handler
 
 import (
 	"fmt"
-	"os"
+	"net/url"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 )
 
 type FilesystemHandler struct {
+	// allowedDirs end with a separator and can change at runtime
 	allowedDirs []string
+	// dirOrigins records how each of allowedDirs was added
+	dirOrigins map[string]string
+	dirsMu     sync.RWMutex
+	backend    Backend
+	limits     Limits
+	// trash is set when soft delete is enabled
+	trash *trash
+	// journal is set when the undo journal is enabled
+	journal *journal
+	// checkpoints is set when workspace checkpoints are enabled
+	checkpoints *checkpointStore
+	// overlay is set when changes are kept in a copy-on-write overlay
+	overlay *OverlayBackend
+	// readOnly is set when only the tools that do not change files are served
+	readOnly bool
+	// enabledTools, if any, are the only tools served and disabledTools are
+	// never served
+	enabledTools, disabledTools []string
+	// directoryAdmin is set when the allowed directories can be changed
+	// through the tools
+	directoryAdmin bool
 }
 
 func NewFilesystemHandler(allowedDirs []string) (*FilesystemHandler, error) {
-	// Normalize and validate directories
-	normalized := make([]string, 0, len(allowedDirs))
-	for _, dir := range allowedDirs {
+	return NewFilesystemHandlerWithBackend(allowedDirs, OSBackend{})
+}
+
+// NewFilesystemHandlerWithBackend creates a handler that serves the allowed
+// directories from backend instead of the local disk
+func NewFilesystemHandlerWithBackend(allowedDirs []string, backend Backend) (*FilesystemHandler, error) {
+	normalized, err := normalizeDirs(backend, allowedDirs)
+	if err != nil {
+		return nil, err
+	}
+	return &FilesystemHandler{
+		allowedDirs: normalized,
+		dirOrigins:  dirOrigins(normalized, DirOriginConfig),
+		backend:     backend,
+		limits:      DefaultLimits(),
+	}, nil
+}
+
+// normalizeDirs makes dirs absolute and checks that they are directories
+func normalizeDirs(backend Backend, dirs []string) ([]string, error) {
+	normalized := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
 		abs, err := filepath.Abs(dir)
 		if err != nil {
 			return nil, fmt.Errorf("failed to resolve path %s: %w", dir, err)
 		}
 
-		info, err := os.Stat(abs)
+		info, err := backend.Stat(abs)
 		if err != nil {
 			return nil, fmt.Errorf(
 				"failed to access directory %s: %w",
@@ -35,12 +79,103 @@ func NewFilesystemHandler(allowedDirs []string) (*FilesystemHandler, error) {
 		// For example, /tmp/foo should not match /tmp/foobar
 		normalized = append(normalized, filepath.Clean(abs)+string(filepath.Separator))
 	}
-	return &FilesystemHandler{
-		allowedDirs: normalized,
-	}, nil
+	return normalized, nil
+}
+
+// Backend returns the storage the handler serves files from
+func (fs *FilesystemHandler) Backend() Backend {
+	return fs.backend
+}
+
+// localDisk reports whether the handler serves files from the local disk.
+// Trash, journal and checkpoints keep their data on disk and need it.
+func (fs *FilesystemHandler) localDisk() bool {
+	_, ok := fs.backend.(OSBackend)
+	return ok
+}
+
+// TrashEnabled reports whether delete_file moves items to the trash
+func (fs *FilesystemHandler) TrashEnabled() bool {
+	return fs.trash != nil
+}
+
+// JournalEnabled reports whether mutating operations are journaled for undo
+func (fs *FilesystemHandler) JournalEnabled() bool {
+	return fs.journal != nil
+}
+
+// CheckpointsEnabled reports whether workspace checkpoints are enabled
+func (fs *FilesystemHandler) CheckpointsEnabled() bool {
+	return fs.checkpoints != nil
+}
+
+// Limits returns the size and result limits in effect
+func (fs *FilesystemHandler) Limits() Limits {
+	return fs.limits
+}
+
+// SetLimits changes the size and result limits. Zero fields keep their
+// current value.
+func (fs *FilesystemHandler) SetLimits(limits Limits) {
+	if limits.MaxInlineSize > 0 {
+		fs.limits.MaxInlineSize = limits.MaxInlineSize
+	}
+	if limits.MaxBase64Size > 0 {
+		fs.limits.MaxBase64Size = limits.MaxBase64Size
+	}
+	if limits.MaxSearchResults > 0 {
+		fs.limits.MaxSearchResults = limits.MaxSearchResults
+	}
+	if limits.MaxSearchableSize > 0 {
+		fs.limits.MaxSearchableSize = limits.MaxSearchableSize
+	}
+	if limits.MaxBatchOperations > 0 {
+		fs.limits.MaxBatchOperations = limits.MaxBatchOperations
+	}
+	if limits.MaxExtractSize > 0 {
+		fs.limits.MaxExtractSize = limits.MaxExtractSize
+	}
+	if limits.MaxExtractEntries > 0 {
+		fs.limits.MaxExtractEntries = limits.MaxExtractEntries
+	}
 }
 
-// pathToResourceURI converts a file path to a resource URI
+// pathToResourceURI converts a file path to a percent-encoded resource URI
 func pathToResourceURI(path string) string {
-	return "file://" + path
+	slashed := filepath.ToSlash(path)
+	// C:/src becomes file:///C:/src
+	if !strings.HasPrefix(slashed, "/") {
+		slashed = "/" + slashed
+	}
+	u := url.URL{Scheme: "file", Path: slashed}
+	return u.String()
+}
+
+// ResourceURIToPath converts a file:// URI to a local path. The query of the
+// URI is ignored.
+func ResourceURIToPath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("invalid URI %s: %w", uri, err)
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("unsupported URI scheme: %s", uri)
+	}
+	path := u.Path
+	switch {
+	case u.Host == "" || u.Host == "localhost":
+	case len(u.Host) == 2 && u.Host[1] == ':':
+		// file://C:/src
+		path = u.Host + path
+	default:
+		return "", fmt.Errorf("unsupported URI host %q", u.Host)
+	}
+	// file:///C:/src has the path /C:/src
+	if len(path) >= 3 && path[0] == '/' && path[2] == ':' && runtime.GOOS == "windows" {
+		path = path[1:]
+	}
+	if path == "" {
+		return "", fmt.Errorf("URI has no path: %s", uri)
+	}
+	return filepath.FromSlash(path), nil
 }
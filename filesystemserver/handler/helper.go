@@ -23,7 +23,7 @@ func (fs *FilesystemHandler) isPathInAllowedDirs(path string) bool {
 	// and not a prefix match (e.g., /tmp/foo should not match /tmp/foobar)
 	if !strings.HasSuffix(absPath, string(filepath.Separator)) {
 		// If it's a file, we need to check its directory
-		if info, err := os.Stat(absPath); err == nil && !info.IsDir() {
+		if info, err := fs.backend.Stat(absPath); err == nil && !info.IsDir() {
 			absPath = filepath.Dir(absPath) + string(filepath.Separator)
 		} else {
 			absPath = absPath + string(filepath.Separator)
@@ -31,7 +31,7 @@ func (fs *FilesystemHandler) isPathInAllowedDirs(path string) bool {
 	}
 
 	// Check if the path is within any of the allowed directories
-	for _, dir := range fs.allowedDirs {
+	for _, dir := range fs.dirs() {
 		if strings.HasPrefix(absPath, dir) {
 			return true
 		}
@@ -55,14 +55,14 @@ func (fs *FilesystemHandler) validatePath(requestedPath string) (string, error)
 	}
 
 	// Handle symlinks
-	realPath, err := filepath.EvalSymlinks(abs)
+	realPath, err := evalSymlinks(fs.backend, abs)
 	if err != nil {
 		if !os.IsNotExist(err) {
 			return "", err
 		}
 		// For new files, check parent directory
 		parent := filepath.Dir(abs)
-		realParent, err := filepath.EvalSymlinks(parent)
+		realParent, err := evalSymlinks(fs.backend, parent)
 		if err != nil {
 			return "", fmt.Errorf("parent directory does not exist: %s", parent)
 		}
@@ -86,9 +86,14 @@ func (fs *FilesystemHandler) validatePath(requestedPath string) (string, error)
 }
 
 // detectMimeType tries to determine the MIME type of a file
-func detectMimeType(path string) string {
+func (fs *FilesystemHandler) detectMimeType(path string) string {
 	// Use mimetype library for more accurate detection
-	mtype, err := mimetype.DetectFile(path)
+	var mtype *mimetype.MIME
+	file, err := fs.backend.Open(path)
+	if err == nil {
+		mtype, err = mimetype.DetectReader(file)
+		file.Close()
+	}
 	if err != nil {
 		// Fallback to extension-based detection if file can't be read
 		ext := filepath.Ext(path)
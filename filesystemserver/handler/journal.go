@@ -0,0 +1,501 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// Name of the file describing a committed journal entry
+	journalEntryFile = "entry.json"
+	// Directory inside a journal entry holding content snapshots
+	journalBlobDir = "blobs"
+)
+
+// Inverse operations recorded in the undo journal
+const (
+	// Remove the path that the operation created
+	stepRemove = "remove"
+	// Replace the path with the snapshot stored in From
+	stepRestore = "restore"
+	// Move the path back from From
+	stepMove = "move"
+	// Restore the path from the trash entry with ID From
+	stepUntrash = "untrash"
+)
+
+// journal records how to reverse every mutating operation so that a session
+// can be rolled back with undo_last or undo_to.
+type journal struct {
+	dir        string
+	maxEntries int
+	nextID     int
+	mu         sync.Mutex
+}
+
+// journalStep is one inverse operation. Steps are applied in order on undo.
+type journalStep struct {
+	Op   string `json:"op"`
+	Path string `json:"path"`
+	From string `json:"from,omitempty"`
+}
+
+// journalEntry is an operation that is being or has been recorded
+type journalEntry struct {
+	JournalEntry
+	Steps []journalStep `json:"steps"`
+
+	dir string
+	// backend holds the paths of the steps; snapshots are kept on disk in dir
+	backend Backend
+}
+
+// EnableJournal records an undo journal in dir for every mutating tool.
+// At most maxEntries entries are kept; older entries are discarded.
+func (fs *FilesystemHandler) EnableJournal(dir string, maxEntries int) error {
+	if !fs.localDisk() {
+		return fmt.Errorf("the undo journal requires the local disk backend")
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve journal directory %s: %w", dir, err)
+	}
+	if err := os.MkdirAll(abs, 0700); err != nil {
+		return fmt.Errorf("failed to create journal directory %s: %w", abs, err)
+	}
+	realDir, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return fmt.Errorf("failed to resolve journal directory %s: %w", abs, err)
+	}
+	// Tools must not be able to edit the entries that undo replays
+	if err := fs.checkAllowedOverlap(realDir); err != nil {
+		return fmt.Errorf("journal directory %s %w", abs, err)
+	}
+	abs = filepath.Clean(realDir)
+
+	j := &journal{
+		dir:        abs,
+		maxEntries: maxEntries,
+		nextID:     1,
+	}
+
+	// Continue numbering after entries from a previous session and drop
+	// entries that were never committed
+	dirEntries, err := os.ReadDir(abs)
+	if err != nil {
+		return fmt.Errorf("failed to read journal directory %s: %w", abs, err)
+	}
+	for _, dirEntry := range dirEntries {
+		id, err := strconv.Atoi(dirEntry.Name())
+		if err != nil || !dirEntry.IsDir() {
+			continue
+		}
+		entryDir := filepath.Join(abs, dirEntry.Name())
+		if _, err := os.Stat(filepath.Join(entryDir, journalEntryFile)); err != nil {
+			os.RemoveAll(entryDir)
+			continue
+		}
+		if id >= j.nextID {
+			j.nextID = id + 1
+		}
+	}
+
+	fs.journal = j
+	return nil
+}
+
+// journalSuppressedKey marks a context whose operations are recorded by the
+// caller, e.g. as part of a batch, and must not be journaled individually
+type journalSuppressedKey struct{}
+
+// withoutJournal returns a context in which operations are not journaled
+func withoutJournal(ctx context.Context) context.Context {
+	return context.WithValue(ctx, journalSuppressedKey{}, true)
+}
+
+// journalBegin starts recording an operation. It returns nil when the journal
+// is disabled; all journalEntry methods are safe to call on nil.
+func (fs *FilesystemHandler) journalBegin(ctx context.Context, tool, summary string) (*journalEntry, error) {
+	if fs.journal == nil || ctx.Value(journalSuppressedKey{}) != nil {
+		return nil, nil
+	}
+	entry, err := fs.journal.begin(tool, summary)
+	if err != nil {
+		return nil, err
+	}
+	entry.backend = fs.backend
+	return entry, nil
+}
+
+// journalCommit makes a recorded operation available for undo
+func (fs *FilesystemHandler) journalCommit(e *journalEntry) {
+	if e == nil {
+		return
+	}
+	// The operation already succeeded, so a journal failure must not turn it
+	// into an error; the entry is simply not undoable.
+	if err := fs.journal.commit(e); err != nil {
+		e.discard()
+	}
+}
+
+// newScratchEntry creates an entry in a temporary directory for callers that
+// need to roll back operations on b while the journal is disabled. It must
+// be discarded when no longer needed.
+func newScratchEntry(b Backend, tool, summary string) (*journalEntry, error) {
+	dir, err := os.MkdirTemp("", "mcp-filesystem-"+tool+"-")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Mkdir(filepath.Join(dir, journalBlobDir), 0700); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	return &journalEntry{
+		JournalEntry: JournalEntry{
+			Tool:    tool,
+			Summary: summary,
+		},
+		dir:     dir,
+		backend: b,
+	}, nil
+}
+
+func (j *journal) begin(tool, summary string) (*journalEntry, error) {
+	j.mu.Lock()
+	id := j.nextID
+	j.nextID++
+	j.mu.Unlock()
+
+	entryDir := filepath.Join(j.dir, formatJournalID(id))
+	if err := os.MkdirAll(filepath.Join(entryDir, journalBlobDir), 0700); err != nil {
+		return nil, err
+	}
+
+	return &journalEntry{
+		JournalEntry: JournalEntry{
+			ID:      id,
+			Tool:    tool,
+			Summary: summary,
+		},
+		dir: entryDir,
+	}, nil
+}
+
+// snapshot records how to bring path back to its current state. An existing
+// path is copied into the entry; a missing path is recorded for removal,
+// together with any missing parent directories the operation may create.
+func (e *journalEntry) snapshot(path string) error {
+	if e == nil {
+		return nil
+	}
+
+	_, err := e.backend.Lstat(path)
+	if os.IsNotExist(err) {
+		e.Steps = append(e.Steps, journalStep{Op: stepRemove, Path: topmostMissing(e.backend, path)})
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	blob := strconv.Itoa(len(e.Steps))
+	blobPath := filepath.Join(e.dir, journalBlobDir, blob)
+	if err := copyTree(e.backend, path, OSBackend{}, blobPath); err != nil {
+		return fmt.Errorf("failed to snapshot %s: %w", path, err)
+	}
+
+	e.Steps = append(e.Steps, journalStep{Op: stepRestore, Path: path, From: blob})
+	return nil
+}
+
+// moveBack records that the item now at from must be moved back to path
+func (e *journalEntry) moveBack(path, from string) {
+	if e == nil {
+		return
+	}
+	e.Steps = append(e.Steps, journalStep{Op: stepMove, Path: path, From: from})
+}
+
+// removeOnUndo records that path was created by the operation
+func (e *journalEntry) removeOnUndo(path string) {
+	if e == nil {
+		return
+	}
+	e.Steps = append(e.Steps, journalStep{Op: stepRemove, Path: path})
+}
+
+// untrash records that path can be restored from the trash entry trashID
+func (e *journalEntry) untrash(path, trashID string) {
+	if e == nil {
+		return
+	}
+	e.Steps = append(e.Steps, journalStep{Op: stepUntrash, Path: path, From: trashID})
+}
+
+// discard drops an entry whose operation did not happen
+func (e *journalEntry) discard() {
+	if e == nil {
+		return
+	}
+	os.RemoveAll(e.dir)
+}
+
+func (j *journal) commit(e *journalEntry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	e.Time = time.Now().UTC()
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(e.dir, journalEntryFile), data, 0600); err != nil {
+		return err
+	}
+
+	// Drop the oldest entries beyond the limit
+	if j.maxEntries > 0 {
+		entries, err := j.entries()
+		if err != nil {
+			return nil
+		}
+		for len(entries) > j.maxEntries {
+			os.RemoveAll(entries[len(entries)-1].dir)
+			entries = entries[:len(entries)-1]
+		}
+	}
+	return nil
+}
+
+// list returns the committed entries, newest first
+func (j *journal) list() ([]*journalEntry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.entries()
+}
+
+// entries reads every committed entry, newest first; the caller must hold j.mu
+func (j *journal) entries() ([]*journalEntry, error) {
+	dirEntries, err := os.ReadDir(j.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*journalEntry, 0, len(dirEntries))
+	for _, dirEntry := range dirEntries {
+		if _, err := strconv.Atoi(dirEntry.Name()); err != nil || !dirEntry.IsDir() {
+			continue
+		}
+		entryDir := filepath.Join(j.dir, dirEntry.Name())
+		data, err := os.ReadFile(filepath.Join(entryDir, journalEntryFile))
+		if err != nil {
+			// Not committed yet
+			continue
+		}
+		var entry journalEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			continue
+		}
+		entry.dir = entryDir
+		result = append(result, &entry)
+	}
+
+	sort.Slice(result, func(i, k int) bool {
+		return result[i].ID > result[k].ID
+	})
+	return result, nil
+}
+
+// undo reverses committed entries, newest first, until stop returns true.
+// It returns the entries that were undone.
+func (j *journal) undo(fs *FilesystemHandler, stop func(*journalEntry) bool) ([]*journalEntry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entries, err := j.entries()
+	if err != nil {
+		return nil, err
+	}
+
+	// Refuse the whole undo if any step leaves the allowed directories, which
+	// may have changed since the entries were recorded
+	for i, entry := range entries {
+		if stop(entry) {
+			entries = entries[:i]
+			break
+		}
+		if err := entry.checkPaths(fs); err != nil {
+			return nil, fmt.Errorf("cannot undo #%d (%s): %w", entry.ID, entry.Tool, err)
+		}
+	}
+
+	var undone []*journalEntry
+	for _, entry := range entries {
+		entry.backend = fs.backend
+		if err := entry.apply(fs); err != nil {
+			return undone, fmt.Errorf("failed to undo #%d (%s): %w", entry.ID, entry.Tool, err)
+		}
+		os.RemoveAll(entry.dir)
+		undone = append(undone, entry)
+	}
+	return undone, nil
+}
+
+// checkPaths returns an error if a step of the entry touches a path outside
+// the allowed directories, directly or through a symbolic link
+func (e *journalEntry) checkPaths(fs *FilesystemHandler) error {
+	for _, step := range e.Steps {
+		if step.Op == stepRestore && !validJournalBlob(step.From) {
+			return fmt.Errorf("invalid snapshot name %q", step.From)
+		}
+		paths := []string{step.Path}
+		if step.Op == stepMove {
+			paths = append(paths, step.From)
+		}
+		for _, path := range paths {
+			if !filepath.IsAbs(path) || !fs.isPathInAllowedDirs(path) {
+				return fmt.Errorf("access denied - path outside allowed directories: %s", path)
+			}
+			// The step acts on path itself, so only its existing ancestors
+			// are resolved
+			parent := filepath.Dir(path)
+			for {
+				if _, err := fs.backend.Lstat(parent); err == nil || filepath.Dir(parent) == parent {
+					break
+				}
+				parent = filepath.Dir(parent)
+			}
+			realParent, err := evalSymlinks(fs.backend, parent)
+			if err != nil {
+				return err
+			}
+			if !fs.isPathInAllowedDirs(realParent) {
+				return fmt.Errorf("access denied - symlink target outside allowed directories: %s", path)
+			}
+		}
+	}
+	return nil
+}
+
+// validJournalBlob reports whether name is a snapshot of the entry itself
+// rather than a path that leads out of its directory
+func validJournalBlob(name string) bool {
+	return name != "" && name != "." && name != ".." && filepath.Base(name) == name
+}
+
+// apply runs the inverse steps of an entry
+func (e *journalEntry) apply(fs *FilesystemHandler) error {
+	return e.applySteps(fs, e.Steps)
+}
+
+// applySteps runs the given inverse steps, whose snapshots belong to e, on
+// the backend of e
+func (e *journalEntry) applySteps(fs *FilesystemHandler, steps []journalStep) error {
+	_, local := e.backend.(OSBackend)
+	for _, step := range steps {
+		switch step.Op {
+		case stepRemove:
+			if err := removeAll(e.backend, step.Path); err != nil {
+				return err
+			}
+		case stepRestore:
+			if !validJournalBlob(step.From) {
+				return fmt.Errorf("invalid snapshot name %q", step.From)
+			}
+			if err := removeAll(e.backend, step.Path); err != nil {
+				return err
+			}
+			if err := mkdirAll(e.backend, filepath.Dir(step.Path), 0755); err != nil {
+				return err
+			}
+			blob := filepath.Join(e.dir, journalBlobDir, step.From)
+			if local {
+				if err := movePath(blob, step.Path); err != nil {
+					return err
+				}
+			} else if err := copyTree(OSBackend{}, blob, e.backend, step.Path); err != nil {
+				return err
+			}
+		case stepMove:
+			if err := mkdirAll(e.backend, filepath.Dir(step.Path), 0755); err != nil {
+				return err
+			}
+			if local {
+				if err := movePath(step.From, step.Path); err != nil {
+					return err
+				}
+			} else if err := e.backend.Rename(step.From, step.Path); err != nil {
+				return err
+			}
+		case stepUntrash:
+			if fs.trash == nil {
+				return fmt.Errorf("trash is not enabled, cannot restore %s", step.Path)
+			}
+			if err := fs.trash.restore(step.From, step.Path); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown journal step: %s", step.Op)
+		}
+	}
+	return nil
+}
+
+// topmostMissing returns the highest ancestor of path (or path itself) that
+// does not exist yet, i.e. the first directory an operation would create
+func topmostMissing(b Backend, path string) string {
+	for {
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		if _, err := b.Lstat(parent); err == nil {
+			return path
+		}
+		path = parent
+	}
+}
+
+// formatJournalID zero-pads IDs so that entries sort naturally on disk
+func formatJournalID(id int) string {
+	return fmt.Sprintf("%08d", id)
+}
+
+// journalSnapshot starts recording an operation that modifies paths and
+// snapshots each of them. It returns nil when the journal is disabled.
+func (fs *FilesystemHandler) journalSnapshot(ctx context.Context, tool, summary string, paths ...string) (*journalEntry, error) {
+	entry, err := fs.journalBegin(ctx, tool, summary)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		if err := entry.snapshot(path); err != nil {
+			entry.discard()
+			return nil, err
+		}
+	}
+	return entry, nil
+}
+
+// journalErrorResult is returned when an operation cannot be recorded
+func journalErrorResult(err error) *mcp.CallToolResult {
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: fmt.Sprintf("Error recording undo journal: %v", err),
+			},
+		},
+		IsError: true,
+	}
+}
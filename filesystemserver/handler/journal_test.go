@@ -0,0 +1,315 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUndoJournal(t *testing.T) {
+	// Setup a temporary directory for the test and a separate journal directory
+	tmpDir := t.TempDir()
+
+	allowedDirs := resolveAllowedDirs(t, tmpDir)
+	fsHandler, err := NewFilesystemHandler(allowedDirs)
+	require.NoError(t, err)
+	require.NoError(t, fsHandler.EnableJournal(t.TempDir(), 0))
+
+	ctx := context.Background()
+
+	call := func(t *testing.T, handle func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error), args map[string]interface{}) {
+		t.Helper()
+		req := mcp.CallToolRequest{
+			Params: mcp.CallToolParams{
+				Arguments: args,
+			},
+		}
+		res, err := handle(ctx, req)
+		require.NoError(t, err)
+		require.False(t, res.IsError, "%v", res.Content)
+	}
+
+	undoLast := func(t *testing.T) {
+		t.Helper()
+		call(t, fsHandler.HandleUndoLast, map[string]interface{}{})
+	}
+
+	t.Run("undo write of a new file", func(t *testing.T) {
+		filePath := filepath.Join(tmpDir, "new.txt")
+		call(t, fsHandler.HandleWriteFile, map[string]interface{}{"path": filePath, "content": "hello"})
+
+		undoLast(t)
+
+		_, err := os.Stat(filePath)
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("undo overwrite and modify", func(t *testing.T) {
+		filePath := filepath.Join(tmpDir, "existing.txt")
+		require.NoError(t, os.WriteFile(filePath, []byte("original"), 0644))
+
+		call(t, fsHandler.HandleWriteFile, map[string]interface{}{"path": filePath, "content": "overwritten"})
+		call(t, fsHandler.HandleModifyFile, map[string]interface{}{"path": filePath, "find": "over", "replace": "re"})
+
+		undoLast(t)
+		content, err := os.ReadFile(filePath)
+		require.NoError(t, err)
+		assert.Equal(t, "overwritten", string(content))
+
+		undoLast(t)
+		content, err = os.ReadFile(filePath)
+		require.NoError(t, err)
+		assert.Equal(t, "original", string(content))
+	})
+
+	t.Run("undo move", func(t *testing.T) {
+		source := filepath.Join(tmpDir, "move_source.txt")
+		destination := filepath.Join(tmpDir, "moved", "dest.txt")
+		require.NoError(t, os.WriteFile(source, []byte("moving"), 0644))
+
+		call(t, fsHandler.HandleMoveFile, map[string]interface{}{"source": source, "destination": destination})
+
+		undoLast(t)
+
+		content, err := os.ReadFile(source)
+		require.NoError(t, err)
+		assert.Equal(t, "moving", string(content))
+
+		// The directory created for the destination is removed as well
+		_, err = os.Stat(filepath.Join(tmpDir, "moved"))
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("undo copy over an existing file", func(t *testing.T) {
+		source := filepath.Join(tmpDir, "copy_source.txt")
+		destination := filepath.Join(tmpDir, "copy_dest.txt")
+		require.NoError(t, os.WriteFile(source, []byte("new"), 0644))
+		require.NoError(t, os.WriteFile(destination, []byte("old"), 0644))
+
+		call(t, fsHandler.HandleCopyFile, map[string]interface{}{"source": source, "destination": destination})
+
+		undoLast(t)
+
+		content, err := os.ReadFile(destination)
+		require.NoError(t, err)
+		assert.Equal(t, "old", string(content))
+	})
+
+	t.Run("undo delete of a directory", func(t *testing.T) {
+		dirPath := filepath.Join(tmpDir, "deleted_dir")
+		require.NoError(t, os.Mkdir(dirPath, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dirPath, "file.txt"), []byte("inside"), 0644))
+
+		call(t, fsHandler.HandleDeleteFile, map[string]interface{}{"path": dirPath, "recursive": true})
+
+		undoLast(t)
+
+		content, err := os.ReadFile(filepath.Join(dirPath, "file.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "inside", string(content))
+	})
+
+	t.Run("undo to checkpoint", func(t *testing.T) {
+		entries, err := fsHandler.journal.list()
+		require.NoError(t, err)
+		checkpoint := 0
+		if len(entries) > 0 {
+			checkpoint = entries[0].ID
+		}
+
+		dirPath := filepath.Join(tmpDir, "session")
+		filePath := filepath.Join(dirPath, "file.txt")
+		call(t, fsHandler.HandleCreateDirectory, map[string]interface{}{"path": dirPath})
+		call(t, fsHandler.HandleWriteFile, map[string]interface{}{"path": filePath, "content": "one"})
+		call(t, fsHandler.HandleWriteFile, map[string]interface{}{"path": filePath, "content": "two"})
+
+		call(t, fsHandler.HandleUndoTo, map[string]interface{}{"checkpoint": float64(checkpoint)})
+
+		_, err = os.Stat(dirPath)
+		assert.True(t, os.IsNotExist(err))
+
+		entries, err = fsHandler.journal.list()
+		require.NoError(t, err)
+		for _, entry := range entries {
+			assert.LessOrEqual(t, entry.ID, checkpoint)
+		}
+	})
+
+	t.Run("failed operations are not journaled", func(t *testing.T) {
+		before, err := fsHandler.journal.list()
+		require.NoError(t, err)
+
+		req := mcp.CallToolRequest{
+			Params: mcp.CallToolParams{
+				Arguments: map[string]interface{}{
+					"path": filepath.Join(tmpDir, "missing.txt"),
+				},
+			},
+		}
+		res, err := fsHandler.HandleDeleteFile(ctx, req)
+		require.NoError(t, err)
+		require.True(t, res.IsError)
+
+		after, err := fsHandler.journal.list()
+		require.NoError(t, err)
+		assert.Len(t, after, len(before))
+	})
+}
+
+func TestUndoJournalWithTrash(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	allowedDirs := resolveAllowedDirs(t, tmpDir)
+	fsHandler, err := NewFilesystemHandler(allowedDirs)
+	require.NoError(t, err)
+	require.NoError(t, fsHandler.EnableTrash(t.TempDir(), 0))
+	require.NoError(t, fsHandler.EnableJournal(t.TempDir(), 0))
+
+	ctx := context.Background()
+
+	filePath := filepath.Join(tmpDir, "trashed.txt")
+	require.NoError(t, os.WriteFile(filePath, []byte("data"), 0644))
+
+	req := mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Arguments: map[string]interface{}{
+				"path": filePath,
+			},
+		},
+	}
+	res, err := fsHandler.HandleDeleteFile(ctx, req)
+	require.NoError(t, err)
+	require.False(t, res.IsError)
+
+	res, err = fsHandler.HandleUndoLast(ctx, mcp.CallToolRequest{})
+	require.NoError(t, err)
+	require.False(t, res.IsError)
+
+	content, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(content))
+
+	entries, err := fsHandler.trash.list()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestUndoJournalLimit(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	allowedDirs := resolveAllowedDirs(t, tmpDir)
+	fsHandler, err := NewFilesystemHandler(allowedDirs)
+	require.NoError(t, err)
+	require.NoError(t, fsHandler.EnableJournal(t.TempDir(), 2))
+
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		req := mcp.CallToolRequest{
+			Params: mcp.CallToolParams{
+				Arguments: map[string]interface{}{
+					"path":    filepath.Join(tmpDir, name),
+					"content": name,
+				},
+			},
+		}
+		res, err := fsHandler.HandleWriteFile(context.Background(), req)
+		require.NoError(t, err)
+		require.False(t, res.IsError)
+	}
+
+	entries, err := fsHandler.journal.list()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, 3, entries[0].ID)
+	assert.Equal(t, 2, entries[1].ID)
+}
+
+func TestUndoJournalOutsideAllowedDirs(t *testing.T) {
+	allowedDirs := resolveAllowedDirs(t, t.TempDir(), t.TempDir())
+	fsHandler, err := NewFilesystemHandler(allowedDirs)
+	require.NoError(t, err)
+	require.NoError(t, fsHandler.EnableJournal(t.TempDir(), 0))
+
+	undoLast := func(t *testing.T) *mcp.CallToolResult {
+		t.Helper()
+		res, err := fsHandler.HandleUndoLast(context.Background(), mcp.CallToolRequest{})
+		require.NoError(t, err)
+		return res
+	}
+
+	t.Run("directory no longer allowed", func(t *testing.T) {
+		filePath := filepath.Join(allowedDirs[1], "new.txt")
+		res := callTool(t, fsHandler.HandleWriteFile, map[string]any{"path": filePath, "content": "hello"})
+		require.False(t, res.IsError, res.Content)
+
+		require.NoError(t, fsHandler.SetAllowedDirectories(allowedDirs[:1], "test"))
+		defer func() { require.NoError(t, fsHandler.SetAllowedDirectories(allowedDirs, "test")) }()
+		res = undoLast(t)
+		require.True(t, res.IsError)
+		assert.Contains(t, res.Content[0].(mcp.TextContent).Text, "outside allowed directories")
+		_, err := os.Stat(filePath)
+		assert.NoError(t, err)
+	})
+
+	t.Run("parent replaced by a symlink", func(t *testing.T) {
+		dir := filepath.Join(allowedDirs[0], "sub")
+		require.NoError(t, os.Mkdir(dir, 0755))
+		filePath := filepath.Join(dir, "new.txt")
+		res := callTool(t, fsHandler.HandleWriteFile, map[string]any{"path": filePath, "content": "hello"})
+		require.False(t, res.IsError, res.Content)
+
+		outside := t.TempDir()
+		outsideFile := filepath.Join(outside, "new.txt")
+		require.NoError(t, os.WriteFile(outsideFile, []byte("keep"), 0644))
+		require.NoError(t, os.RemoveAll(dir))
+		require.NoError(t, os.Symlink(outside, dir))
+
+		res = undoLast(t)
+		require.True(t, res.IsError)
+		_, err := os.Stat(outsideFile)
+		assert.NoError(t, err)
+	})
+
+	t.Run("snapshot outside the entry", func(t *testing.T) {
+		filePath := filepath.Join(allowedDirs[0], "edited.txt")
+		require.NoError(t, os.WriteFile(filePath, []byte("original"), 0644))
+		res := callTool(t, fsHandler.HandleWriteFile, map[string]any{"path": filePath, "content": "changed"})
+		require.False(t, res.IsError, res.Content)
+
+		secret := filepath.Join(t.TempDir(), "secret")
+		require.NoError(t, os.WriteFile(secret, []byte("secret"), 0644))
+		entries, err := fsHandler.journal.list()
+		require.NoError(t, err)
+		entry := entries[0]
+		require.Equal(t, stepRestore, entry.Steps[0].Op)
+		entry.Steps[0].From, err = filepath.Rel(filepath.Join(entry.dir, journalBlobDir), secret)
+		require.NoError(t, err)
+		data, err := json.Marshal(entry)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(entry.dir, journalEntryFile), data, 0600))
+
+		res = undoLast(t)
+		require.True(t, res.IsError)
+		assert.Contains(t, res.Content[0].(mcp.TextContent).Text, "invalid snapshot name")
+		content, err := os.ReadFile(filePath)
+		require.NoError(t, err)
+		assert.Equal(t, "changed", string(content))
+	})
+}
+
+func TestEnableJournalInsideAllowedDir(t *testing.T) {
+	allowedDirs := resolveAllowedDirs(t, t.TempDir())
+	fsHandler, err := NewFilesystemHandler(allowedDirs)
+	require.NoError(t, err)
+
+	err = fsHandler.EnableJournal(filepath.Join(allowedDirs[0], ".journal"), 0)
+	assert.ErrorContains(t, err, "overlaps the allowed directory")
+	err = fsHandler.EnableJournal(filepath.Dir(allowedDirs[0]), 0)
+	assert.ErrorContains(t, err, "overlaps the allowed directory")
+	assert.Nil(t, fsHandler.journal)
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Line ending styles
+const (
+	LineEndingLF   = "lf"
+	LineEndingCRLF = "crlf"
+)
+
+// lineEndings describes how the lines of a text end. The style is "" when
+// the text mixes both.
+type lineEndings struct {
+	style        string
+	finalNewline bool
+}
+
+// detectLineEndings returns the line endings of text. ok is false for text
+// without line breaks, which has no convention to keep.
+func detectLineEndings(text string) (endings lineEndings, ok bool) {
+	lines := strings.Count(text, "\n")
+	if lines == 0 {
+		return lineEndings{}, false
+	}
+	crlf := strings.Count(text, "\r\n")
+	switch crlf {
+	case 0:
+		endings.style = LineEndingLF
+	case lines:
+		endings.style = LineEndingCRLF
+	}
+	endings.finalNewline = strings.HasSuffix(text, "\n")
+	return endings, true
+}
+
+// toLF converts CRLF line endings in text to LF
+func toLF(text string) string {
+	return strings.ReplaceAll(text, "\r\n", "\n")
+}
+
+// applyLineEndings gives text the line endings of the file it replaces,
+// described by current if it has any, or those the request sets with
+// line_endings and final_newline
+func applyLineEndings(request mcp.CallToolRequest, text string, current *lineEndings) (string, error) {
+	var want lineEndings
+	if current != nil {
+		want = *current
+	}
+	switch style := strings.ToLower(request.GetString("line_endings", "")); style {
+	case "":
+	case LineEndingLF, LineEndingCRLF:
+		want.style = style
+	default:
+		return "", fmt.Errorf("unsupported line endings %q (expected lf or crlf)", style)
+	}
+
+	switch want.style {
+	case LineEndingLF:
+		text = toLF(text)
+	case LineEndingCRLF:
+		text = strings.ReplaceAll(toLF(text), "\n", "\r\n")
+	}
+
+	// New files end as their content does unless final_newline is set
+	if _, ok := request.GetArguments()["final_newline"]; ok {
+		want.finalNewline = request.GetBool("final_newline", false)
+	} else if current == nil || text == "" {
+		return text, nil
+	}
+	switch hasNewline := strings.HasSuffix(text, "\n"); {
+	case want.finalNewline && !hasNewline:
+		if want.style == LineEndingCRLF {
+			text += "\r\n"
+		} else {
+			text += "\n"
+		}
+	case !want.finalNewline && hasNewline:
+		text = strings.TrimSuffix(strings.TrimSuffix(text, "\n"), "\r")
+	}
+	return text, nil
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectLineEndings(t *testing.T) {
+	tests := []struct {
+		text string
+		want lineEndings
+		ok   bool
+	}{
+		{"a\nb\n", lineEndings{style: LineEndingLF, finalNewline: true}, true},
+		{"a\r\nb", lineEndings{style: LineEndingCRLF}, true},
+		{"a\r\nb\n", lineEndings{finalNewline: true}, true},
+		{"single line", lineEndings{}, false},
+		{"", lineEndings{}, false},
+	}
+	for _, tt := range tests {
+		endings, ok := detectLineEndings(tt.text)
+		assert.Equal(t, tt.ok, ok, "%q", tt.text)
+		assert.Equal(t, tt.want, endings, "%q", tt.text)
+	}
+}
+
+func TestLineEndingPreservation(t *testing.T) {
+	tmpDir := t.TempDir()
+	allowedDirs := resolveAllowedDirs(t, tmpDir)
+	fsHandler, err := NewFilesystemHandler(allowedDirs)
+	require.NoError(t, err)
+	dir := allowedDirs[0]
+
+	call := func(t *testing.T, handle func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error), args map[string]any) {
+		t.Helper()
+		req := mcp.CallToolRequest{}
+		req.Params.Arguments = args
+		res, err := handle(context.Background(), req)
+		require.NoError(t, err)
+		require.False(t, res.IsError, res.Content[0].(mcp.TextContent).Text)
+	}
+	assertContent := func(t *testing.T, path, want string) {
+		t.Helper()
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, want, string(content))
+	}
+
+	t.Run("modify crlf file", func(t *testing.T) {
+		path := filepath.Join(dir, "crlf.txt")
+		require.NoError(t, os.WriteFile(path, []byte("one\r\ntwo\r\nthree\r\n"), 0644))
+		call(t, fsHandler.HandleModifyFile, map[string]any{"path": path, "find": "one\ntwo", "replace": "1\n2\n2.5"})
+		assertContent(t, path, "1\r\n2\r\n2.5\r\nthree\r\n")
+	})
+
+	t.Run("modify keeps final newline", func(t *testing.T) {
+		path := filepath.Join(dir, "final.txt")
+		require.NoError(t, os.WriteFile(path, []byte("a\nb\n"), 0644))
+		call(t, fsHandler.HandleModifyFile, map[string]any{"path": path, "find": "b\n", "replace": "c"})
+		assertContent(t, path, "a\nc\n")
+	})
+
+	t.Run("modify mixed file", func(t *testing.T) {
+		path := filepath.Join(dir, "mixed.txt")
+		require.NoError(t, os.WriteFile(path, []byte("a\r\nb\nc\n"), 0644))
+		call(t, fsHandler.HandleModifyFile, map[string]any{"path": path, "find": "b", "replace": "B"})
+		assertContent(t, path, "a\r\nB\nc\n")
+
+		call(t, fsHandler.HandleModifyFile, map[string]any{"path": path, "find": "B", "replace": "b", "line_endings": "lf"})
+		assertContent(t, path, "a\nb\nc\n")
+	})
+
+	t.Run("write keeps line endings", func(t *testing.T) {
+		path := filepath.Join(dir, "write.txt")
+		require.NoError(t, os.WriteFile(path, []byte("old\r\nlines\r\n"), 0644))
+		call(t, fsHandler.HandleWriteFile, map[string]any{"path": path, "content": "new\nlines\n"})
+		assertContent(t, path, "new\r\nlines\r\n")
+
+		call(t, fsHandler.HandleWriteFile, map[string]any{"path": path, "content": "new\nlines\n", "line_endings": "lf", "final_newline": false})
+		assertContent(t, path, "new\nlines")
+	})
+
+	t.Run("write keeps the final newline of the content", func(t *testing.T) {
+		path := filepath.Join(dir, "final_write.txt")
+		require.NoError(t, os.WriteFile(path, []byte("a\nb\n"), 0644))
+		call(t, fsHandler.HandleWriteFile, map[string]any{"path": path, "content": "a\nc"})
+		assertContent(t, path, "a\nc")
+
+		call(t, fsHandler.HandleWriteFile, map[string]any{"path": path, "content": "a\nd\n"})
+		assertContent(t, path, "a\nd\n")
+
+		call(t, fsHandler.HandleWriteFile, map[string]any{"path": path, "content": "a\ne", "final_newline": true})
+		assertContent(t, path, "a\ne\n")
+	})
+
+	t.Run("new files are written as given", func(t *testing.T) {
+		path := filepath.Join(dir, "new.txt")
+		call(t, fsHandler.HandleWriteFile, map[string]any{"path": path, "content": "a\r\nb"})
+		assertContent(t, path, "a\r\nb")
+
+		path = filepath.Join(dir, "new2.txt")
+		call(t, fsHandler.HandleWriteFile, map[string]any{"path": path, "content": "a\nb", "line_endings": "crlf", "final_newline": true})
+		assertContent(t, path, "a\r\nb\r\n")
+	})
+}
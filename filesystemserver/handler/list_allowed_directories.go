@@ -3,7 +3,6 @@ package handler
 import (
 	"context"
 	"fmt"
-	"path/filepath"
 	"strings"
 
 	"github.com/mark3labs/mcp-go/mcp"
@@ -13,18 +12,14 @@ func (fs *FilesystemHandler) HandleListAllowedDirectories(
 	ctx context.Context,
 	request mcp.CallToolRequest,
 ) (*mcp.CallToolResult, error) {
-	// Remove the trailing separator for display purposes
-	displayDirs := make([]string, len(fs.allowedDirs))
-	for i, dir := range fs.allowedDirs {
-		displayDirs[i] = strings.TrimSuffix(dir, string(filepath.Separator))
-	}
+	dirs := fs.AllowedDirectoryInfo()
 
 	var result strings.Builder
 	result.WriteString("Allowed directories:\n\n")
 
-	for _, dir := range displayDirs {
-		resourceURI := pathToResourceURI(dir)
-		result.WriteString(fmt.Sprintf("%s (%s)\n", dir, resourceURI))
+	for _, dir := range dirs {
+		resourceURI := pathToResourceURI(dir.Path)
+		result.WriteString(fmt.Sprintf("%s (%s) [access: %s, origin: %s]\n", dir.Path, resourceURI, dir.Access, dir.Origin))
 	}
 
 	return &mcp.CallToolResult{
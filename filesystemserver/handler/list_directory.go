@@ -37,6 +37,11 @@ func (fs *FilesystemHandler) HandleListDirectory(
 		path = cwd
 	}
 
+	// Paths like bundle.zip!/dir point into an archive
+	if archivePath, name, ok := splitArchivePath(path); ok {
+		return fs.listArchiveDirectory(archivePath, name)
+	}
+
 	validPath, err := fs.validatePath(path)
 	if err != nil {
 		return &mcp.CallToolResult{
@@ -51,7 +56,7 @@ func (fs *FilesystemHandler) HandleListDirectory(
 	}
 
 	// Check if it's a directory
-	info, err := os.Stat(validPath)
+	info, err := fs.backend.Stat(validPath)
 	if err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
@@ -76,7 +81,7 @@ func (fs *FilesystemHandler) HandleListDirectory(
 		}, nil
 	}
 
-	entries, err := os.ReadDir(validPath)
+	entries, err := fs.backend.ReadDir(validPath)
 	if err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func (fs *FilesystemHandler) HandleListTrash(
+	ctx context.Context,
+	request mcp.CallToolRequest,
+) (*mcp.CallToolResult, error) {
+	if fs.trash == nil {
+		return trashDisabledResult(), nil
+	}
+
+	// Drop expired entries so they are not offered for restore
+	_, _ = fs.trash.purgeExpired()
+
+	entries, err := fs.trash.list()
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error reading trash: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	if len(entries) == 0 {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: "Trash is empty",
+				},
+			},
+		}, nil
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Trash contains %d item(s):\n\n", len(entries)))
+
+	for _, entry := range entries {
+		if entry.IsDirectory {
+			result.WriteString(fmt.Sprintf("[DIR]  %s - %s (deleted %s)\n",
+				entry.ID, entry.OriginalPath, entry.DeletedAt.Format(time.RFC3339)))
+		} else {
+			result.WriteString(fmt.Sprintf("[FILE] %s - %s (%d bytes, deleted %s)\n",
+				entry.ID, entry.OriginalPath, entry.Size, entry.DeletedAt.Format(time.RFC3339)))
+		}
+	}
+
+	if fs.trash.retention > 0 {
+		result.WriteString(fmt.Sprintf("\nItems are permanently removed after %s.", fs.trash.retention))
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: result.String(),
+			},
+		},
+	}, nil
+}
+
+// trashDisabledResult is returned by the trash tools when soft delete is off
+func trashDisabledResult() *mcp.CallToolResult {
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: "Error: Trash is not enabled on this server",
+			},
+		},
+		IsError: true,
+	}
+}
@@ -75,7 +75,7 @@ func (fs *FilesystemHandler) HandleModifyFile(
 	}
 
 	// Check if it's a directory
-	if info, err := os.Stat(validPath); err == nil && info.IsDir() {
+	if info, err := fs.backend.Stat(validPath); err == nil && info.IsDir() {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				mcp.TextContent{
@@ -88,7 +88,7 @@ func (fs *FilesystemHandler) HandleModifyFile(
 	}
 
 	// Check if file exists
-	if _, err := os.Stat(validPath); os.IsNotExist(err) {
+	if _, err := fs.backend.Stat(validPath); os.IsNotExist(err) {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				mcp.TextContent{
@@ -101,7 +101,7 @@ func (fs *FilesystemHandler) HandleModifyFile(
 	}
 
 	// Read file content
-	content, err := os.ReadFile(validPath)
+	content, err := readFile(fs.backend, validPath)
 	if err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
@@ -114,7 +114,38 @@ func (fs *FilesystemHandler) HandleModifyFile(
 		}, nil
 	}
 
+	// Text that is not UTF-8 is edited as UTF-8 and written back in its
+	// encoding unless the request sets another one
+	current := textEncoding{name: EncodingUTF8}
 	originalContent := string(content)
+	if enc, ok := detectEncoding(content); ok && !enc.plain() {
+		if text, err := decodeText(content, enc); err == nil {
+			current, originalContent = enc, text
+		}
+	}
+	enc, err := requestEncoding(request, current)
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	// Files with consistent line endings are edited with LF, so that find
+	// and replace match whichever ones they use, and converted back after
+	var endings *lineEndings
+	if e, ok := detectLineEndings(originalContent); ok {
+		endings = &e
+		if e.style != "" {
+			originalContent, find, replace = toLF(originalContent), toLF(find), toLF(replace)
+		}
+	}
+
 	modifiedContent := ""
 	replacementCount := 0
 
@@ -161,8 +192,32 @@ func (fs *FilesystemHandler) HandleModifyFile(
 		}
 	}
 
+	modifiedContent, err = applyLineEndings(request, modifiedContent, endings)
+	var data []byte
+	if err == nil {
+		data, err = encodeText(modifiedContent, enc)
+	}
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	// Record the original content so the modification can be undone
+	entry, err := fs.journalSnapshot(ctx, "modify_file", fmt.Sprintf("modify %s", validPath), validPath)
+	if err != nil {
+		return journalErrorResult(err), nil
+	}
+
 	// Write modified content back to file
-	if err := os.WriteFile(validPath, []byte(modifiedContent), 0644); err != nil {
+	if err := writeFile(fs.backend, validPath, data, 0644); err != nil {
+		entry.discard()
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				mcp.TextContent{
@@ -174,11 +229,13 @@ func (fs *FilesystemHandler) HandleModifyFile(
 		}, nil
 	}
 
+	fs.journalCommit(entry)
+
 	// Create response
 	resourceURI := pathToResourceURI(validPath)
 
 	// Get file info for the response
-	info, err := os.Stat(validPath)
+	info, err := fs.backend.Stat(validPath)
 	if err != nil {
 		// File was written but we couldn't get info
 		return &mcp.CallToolResult{
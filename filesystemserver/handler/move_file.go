@@ -72,7 +72,7 @@ func (fs *FilesystemHandler) HandleMoveFile(
 	}
 
 	// Check if source exists
-	if _, err := os.Stat(validSource); os.IsNotExist(err) {
+	if _, err := fs.backend.Stat(validSource); os.IsNotExist(err) {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				mcp.TextContent{
@@ -99,8 +99,14 @@ func (fs *FilesystemHandler) HandleMoveFile(
 		}, nil
 	}
 
+	// Remember whether the destination directory is created by this move
+	createdDir := ""
+	if _, err := fs.backend.Stat(validDestDir); os.IsNotExist(err) {
+		createdDir = topmostMissing(fs.backend, validDestDir)
+	}
+
 	// Create parent directory for destination if it doesn't exist
-	if err := os.MkdirAll(validDestDir, 0755); err != nil {
+	if err := mkdirAll(fs.backend, validDestDir, 0755); err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				mcp.TextContent{
@@ -126,7 +132,22 @@ func (fs *FilesystemHandler) HandleMoveFile(
 		}, nil
 	}
 
-	if err := os.Rename(validSource, validDest); err != nil {
+	// Record how to move the item back and restore anything it replaces
+	entry, err := fs.journalBegin(ctx, "move_file", fmt.Sprintf("move %s to %s", validSource, validDest))
+	if err == nil {
+		entry.moveBack(validSource, validDest)
+		err = entry.snapshot(validDest)
+	}
+	if err != nil {
+		entry.discard()
+		return journalErrorResult(err), nil
+	}
+	if createdDir != "" {
+		entry.removeOnUndo(createdDir)
+	}
+
+	if err := fs.backend.Rename(validSource, validDest); err != nil {
+		entry.discard()
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				mcp.TextContent{
@@ -138,6 +159,8 @@ func (fs *FilesystemHandler) HandleMoveFile(
 		}, nil
 	}
 
+	fs.journalCommit(entry)
+
 	resourceURI := pathToResourceURI(validDest)
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{
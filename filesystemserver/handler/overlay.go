@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// EnableOverlay puts a copy-on-write overlay on top of the allowed
+// directories. Changes are kept in dir until they are committed or discarded.
+func (fs *FilesystemHandler) EnableOverlay(dir string) error {
+	if fs.trash != nil || fs.journal != nil || fs.checkpoints != nil {
+		return errors.New("the overlay cannot be combined with the trash, undo journal or checkpoints")
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve overlay directory %s: %w", dir, err)
+	}
+	// The overlay must not be reachable through the tools
+	for _, allowed := range fs.dirs() {
+		if strings.HasPrefix(abs+string(filepath.Separator), allowed) {
+			return fmt.Errorf("overlay directory %s is inside the allowed directory %s", abs, allowed)
+		}
+	}
+
+	overlay, err := NewOverlayBackend(fs.backend, abs)
+	if err != nil {
+		return fmt.Errorf("failed to create overlay directory %s: %w", abs, err)
+	}
+	fs.backend = overlay
+	fs.overlay = overlay
+	return nil
+}
+
+// OverlayEnabled reports whether changes are kept in a copy-on-write overlay
+func (fs *FilesystemHandler) OverlayEnabled() bool {
+	return fs.overlay != nil
+}
+
+func overlayDisabledResult() *mcp.CallToolResult {
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: "Error: The overlay is not enabled on this server",
+			},
+		},
+		IsError: true,
+	}
+}
+
+// overlayChanges lists the overlay's changes, limited to the path argument
+// of request if given
+func (fs *FilesystemHandler) overlayChanges(request mcp.CallToolRequest) ([]OverlayChange, *mcp.CallToolResult) {
+	changes, err := fs.overlay.Changes()
+	if err != nil {
+		return nil, &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error reading overlay: %v", err),
+				},
+			},
+			IsError: true,
+		}
+	}
+
+	path := request.GetString("path", "")
+	if path == "" {
+		return changes, nil
+	}
+	validPath, err := fs.validatePath(path)
+	if err != nil {
+		return nil, &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error: %v", err),
+				},
+			},
+			IsError: true,
+		}
+	}
+
+	var filtered []OverlayChange
+	for _, change := range changes {
+		if change.Path == validPath || strings.HasPrefix(change.Path, validPath+string(filepath.Separator)) {
+			filtered = append(filtered, change)
+		}
+	}
+	return filtered, nil
+}
+
+// formatOverlayChanges renders changes one per line, e.g. "M /src/main.go"
+func formatOverlayChanges(changes []OverlayChange) string {
+	var result strings.Builder
+	for _, change := range changes {
+		marker := "M"
+		switch change.Status {
+		case "added":
+			marker = "A"
+		case "removed":
+			marker = "D"
+		}
+		suffix := ""
+		if change.Type == checkpointTypeDir {
+			suffix = string(filepath.Separator)
+		}
+		result.WriteString(fmt.Sprintf("%s %s%s\n", marker, change.Path, suffix))
+	}
+	return result.String()
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func (fs *FilesystemHandler) HandleOverlayCommit(
+	ctx context.Context,
+	request mcp.CallToolRequest,
+) (*mcp.CallToolResult, error) {
+	if fs.overlay == nil {
+		return overlayDisabledResult(), nil
+	}
+
+	changes, err := fs.overlay.Commit()
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error committing overlay: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: fmt.Sprintf(
+					"Committed %d change(s) to the allowed directories:\n\n%s",
+					len(changes),
+					formatOverlayChanges(changes),
+				),
+			},
+		},
+	}, nil
+}
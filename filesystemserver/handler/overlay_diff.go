@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func (fs *FilesystemHandler) HandleOverlayDiff(
+	ctx context.Context,
+	request mcp.CallToolRequest,
+) (*mcp.CallToolResult, error) {
+	if fs.overlay == nil {
+		return overlayDisabledResult(), nil
+	}
+
+	changes, errResult := fs.overlayChanges(request)
+	if errResult != nil {
+		return errResult, nil
+	}
+
+	if len(changes) == 0 {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: "No changes in the overlay",
+				},
+			},
+		}, nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: fmt.Sprintf(
+					"%d change(s) in the overlay:\n\n%s",
+					len(changes),
+					formatOverlayChanges(changes),
+				),
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+func (fs *FilesystemHandler) HandleOverlayExport(
+	ctx context.Context,
+	request mcp.CallToolRequest,
+) (*mcp.CallToolResult, error) {
+	if fs.overlay == nil {
+		return overlayDisabledResult(), nil
+	}
+
+	changes, errResult := fs.overlayChanges(request)
+	if errResult != nil {
+		return errResult, nil
+	}
+
+	var patch strings.Builder
+	for _, change := range changes {
+		if change.Type == checkpointTypeDir {
+			continue
+		}
+		diff, err := fs.overlayFileDiff(change)
+		if err != nil {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					mcp.TextContent{
+						Type: "text",
+						Text: fmt.Sprintf("Error comparing %s: %v", change.Path, err),
+					},
+				},
+				IsError: true,
+			}, nil
+		}
+		patch.WriteString(diff)
+	}
+
+	if int64(patch.Len()) > fs.limits.MaxInlineSize {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf(
+						"Error: The patch is %d bytes, more than the limit of %d bytes. Export a smaller directory with the path argument.",
+						patch.Len(),
+						fs.limits.MaxInlineSize,
+					),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	if patch.Len() == 0 {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: "No file changes in the overlay",
+				},
+			},
+		}, nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: patch.String(),
+			},
+		},
+	}, nil
+}
+
+// overlayFileDiff renders the change of a file or symlink as a unified diff
+// that applies with "patch -p1" from the file system root
+func (fs *FilesystemHandler) overlayFileDiff(change OverlayChange) (string, error) {
+	oldName := "a" + filepath.ToSlash(change.Path)
+	newName := "b" + filepath.ToSlash(change.Path)
+
+	var before, after []byte
+	var err error
+	if change.Status != "added" {
+		if before, err = overlayContent(fs.overlay.lower, change.Path); err != nil {
+			return "", err
+		}
+	} else {
+		oldName = "/dev/null"
+	}
+	if change.Status != "removed" {
+		if after, err = overlayContent(fs.overlay, change.Path); err != nil {
+			return "", err
+		}
+	} else {
+		newName = "/dev/null"
+	}
+
+	if isBinary(before) || isBinary(after) {
+		return fmt.Sprintf("Binary files %s and %s differ\n", oldName, newName), nil
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(before)),
+		B:        difflib.SplitLines(string(after)),
+		FromFile: oldName,
+		ToFile:   newName,
+		Context:  3,
+	})
+}
+
+// overlayContent returns the content of a file, or the target of a symlink
+func overlayContent(b Backend, path string) ([]byte, error) {
+	info, err := b.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := b.Readlink(path)
+		return []byte(target), err
+	}
+	return readFile(b, path)
+}
+
+// isBinary reports whether data does not look like text
+func isBinary(data []byte) bool {
+	return bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data)
+}
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverlay(t *testing.T) {
+	// The lower directory is resolved so that paths compare equal on macOS
+	lowerDir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	overlayDir := t.TempDir()
+
+	writeLower := func(name, content string) {
+		path := filepath.Join(lowerDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	readLower := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(lowerDir, name))
+		require.NoError(t, err)
+		return string(data)
+	}
+	writeLower("notes.txt", "one\n")
+	writeLower("src/main.go", "package main\n")
+	writeLower("src/util.go", "package main\n")
+
+	fsHandler, err := NewFilesystemHandler([]string{lowerDir})
+	require.NoError(t, err)
+	require.NoError(t, fsHandler.EnableOverlay(overlayDir))
+	path := func(name string) string {
+		return filepath.Join(lowerDir, filepath.FromSlash(name))
+	}
+	text := func(result *mcp.CallToolResult) string {
+		return result.Content[0].(mcp.TextContent).Text
+	}
+
+	t.Run("changes do not reach the lower directory", func(t *testing.T) {
+		result := callTool(t, fsHandler.HandleWriteFile, map[string]any{"path": path("notes.txt"), "content": "two\n"})
+		require.False(t, result.IsError, result.Content)
+		result = callTool(t, fsHandler.HandleWriteFile, map[string]any{"path": path("todo.txt"), "content": "milk\n"})
+		require.False(t, result.IsError, result.Content)
+		result = callTool(t, fsHandler.HandleDeleteFile, map[string]any{"path": path("src/util.go")})
+		require.False(t, result.IsError, result.Content)
+		result = callTool(t, fsHandler.HandleMoveFile, map[string]any{"source": path("src/main.go"), "destination": path("main.go")})
+		require.False(t, result.IsError, result.Content)
+		result = callTool(t, fsHandler.HandleCreateDirectory, map[string]any{"path": path("docs")})
+		require.False(t, result.IsError, result.Content)
+
+		result = callTool(t, fsHandler.HandleReadFile, map[string]any{"path": path("notes.txt")})
+		assert.Equal(t, "two\n", text(result))
+		assert.Equal(t, "one\n", readLower("notes.txt"))
+		_, err := os.Stat(path("todo.txt"))
+		assert.True(t, os.IsNotExist(err))
+		_, err = os.Stat(path("src/util.go"))
+		assert.NoError(t, err)
+
+		entries, err := fsHandler.backend.ReadDir(path("src"))
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+		entries, err = fsHandler.backend.ReadDir(lowerDir)
+		require.NoError(t, err)
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		assert.Equal(t, []string{"docs", "main.go", "notes.txt", "src", "todo.txt"}, names)
+	})
+
+	t.Run("diff and export", func(t *testing.T) {
+		result := callTool(t, fsHandler.HandleOverlayDiff, map[string]any{})
+		require.False(t, result.IsError, result.Content)
+		assert.Contains(t, text(result), "A "+path("docs")+string(filepath.Separator))
+		assert.Contains(t, text(result), "A "+path("main.go"))
+		assert.Contains(t, text(result), "M "+path("notes.txt"))
+		assert.Contains(t, text(result), "D "+path("src/main.go"))
+		assert.Contains(t, text(result), "D "+path("src/util.go"))
+		assert.Contains(t, text(result), "A "+path("todo.txt"))
+
+		result = callTool(t, fsHandler.HandleOverlayDiff, map[string]any{"path": path("src")})
+		require.False(t, result.IsError, result.Content)
+		assert.Contains(t, text(result), "2 change(s)")
+
+		result = callTool(t, fsHandler.HandleOverlayExport, map[string]any{})
+		require.False(t, result.IsError, result.Content)
+		patch := text(result)
+		assert.Contains(t, patch, "--- a"+filepath.ToSlash(path("notes.txt")))
+		assert.Contains(t, patch, "-one\n+two\n")
+		assert.Contains(t, patch, "--- /dev/null\n+++ b"+filepath.ToSlash(path("todo.txt")))
+		assert.Contains(t, patch, "+++ /dev/null")
+	})
+
+	t.Run("commit applies the changes", func(t *testing.T) {
+		result := callTool(t, fsHandler.HandleOverlayCommit, map[string]any{})
+		require.False(t, result.IsError, result.Content)
+		assert.Contains(t, text(result), "Committed 6 change(s)")
+
+		assert.Equal(t, "two\n", readLower("notes.txt"))
+		assert.Equal(t, "milk\n", readLower("todo.txt"))
+		assert.Equal(t, "package main\n", readLower("main.go"))
+		_, err := os.Stat(path("src/util.go"))
+		assert.True(t, os.IsNotExist(err))
+		info, err := os.Stat(path("docs"))
+		require.NoError(t, err)
+		assert.True(t, info.IsDir())
+
+		result = callTool(t, fsHandler.HandleOverlayDiff, map[string]any{})
+		assert.Equal(t, "No changes in the overlay", text(result))
+	})
+
+	t.Run("a recreated directory hides the old contents", func(t *testing.T) {
+		writeLower("lib/a.go", "package lib\n")
+		result := callTool(t, fsHandler.HandleDeleteFile, map[string]any{"path": path("lib"), "recursive": true})
+		require.False(t, result.IsError, result.Content)
+		result = callTool(t, fsHandler.HandleCreateDirectory, map[string]any{"path": path("lib")})
+		require.False(t, result.IsError, result.Content)
+
+		entries, err := fsHandler.backend.ReadDir(path("lib"))
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+
+		result = callTool(t, fsHandler.HandleOverlayDiff, map[string]any{})
+		assert.Contains(t, text(result), "D "+path("lib/a.go"))
+	})
+
+	t.Run("discard throws the changes away", func(t *testing.T) {
+		result := callTool(t, fsHandler.HandleOverlayDiscard, map[string]any{})
+		require.False(t, result.IsError, result.Content)
+
+		entries, err := fsHandler.backend.ReadDir(path("lib"))
+		require.NoError(t, err)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, "package lib\n", readLower("lib/a.go"))
+	})
+
+	t.Run("invalid configurations", func(t *testing.T) {
+		other, err := NewFilesystemHandler([]string{lowerDir})
+		require.NoError(t, err)
+		assert.Error(t, other.EnableOverlay(filepath.Join(lowerDir, ".overlay")))
+
+		require.NoError(t, other.EnableTrash(t.TempDir(), 0))
+		assert.Error(t, other.EnableOverlay(t.TempDir()))
+	})
+}
@@ -0,0 +1,301 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// maxPromptResources bounds how many files a prompt embeds
+	maxPromptResources = 10
+	// maxPromptUsages bounds how many matches find_usages lists
+	maxPromptUsages = 100
+)
+
+// summaryFiles are the files that usually tell what a directory is about,
+// embedded by summarize_directory when present at its top level
+var summaryFiles = []string{
+	"readme", "readme.md", "readme.txt", "readme.rst",
+	"go.mod", "package.json", "cargo.toml", "pyproject.toml", "setup.py",
+	"pom.xml", "build.gradle", "gemfile", "composer.json",
+	"makefile", "dockerfile", "docker-compose.yml",
+}
+
+// HandleSummarizeDirectoryPrompt asks for a summary of a directory, embedding
+// its tree and the files that describe it
+func (fs *FilesystemHandler) HandleSummarizeDirectoryPrompt(
+	ctx context.Context,
+	request mcp.GetPromptRequest,
+) (*mcp.GetPromptResult, error) {
+	path := request.Params.Arguments["path"]
+	if path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+	depth := 2
+	if value := request.Params.Arguments["depth"]; value != "" {
+		var err error
+		if depth, err = strconv.Atoi(value); err != nil || depth < 1 {
+			return nil, fmt.Errorf("invalid depth %q", value)
+		}
+	}
+
+	validPath, err := fs.validatePath(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := fs.backend.Stat(validPath)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", validPath)
+	}
+
+	tree, err := fs.buildTree(validPath, depth, 0, false)
+	if err != nil {
+		return nil, fmt.Errorf("error building directory tree: %w", err)
+	}
+	jsonData, err := json.MarshalIndent(tree, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []mcp.PromptMessage{
+		{
+			Role: mcp.RoleUser,
+			Content: mcp.TextContent{
+				Type: "text",
+				Text: fmt.Sprintf(
+					"Summarize the directory %s. Explain what it contains and what it is for, "+
+						"describe how it is organized and point out its most important files and "+
+						"subdirectories. Its tree (max depth: %d) and the files describing it follow.",
+					validPath, depth,
+				),
+			},
+		},
+		{
+			Role: mcp.RoleUser,
+			Content: mcp.EmbeddedResource{
+				Type: "resource",
+				Resource: mcp.TextResourceContents{
+					URI:      pathToResourceURI(validPath),
+					MIMEType: "application/json",
+					Text:     string(jsonData),
+				},
+			},
+		},
+	}
+
+	var files []string
+	if entries, err := fs.backend.ReadDir(validPath); err == nil {
+		for _, entry := range entries {
+			if !entry.IsDir() && slices.Contains(summaryFiles, strings.ToLower(entry.Name())) {
+				files = append(files, filepath.Join(validPath, entry.Name()))
+			}
+		}
+	}
+	messages = append(messages, fs.promptResources(ctx, files)...)
+
+	return &mcp.GetPromptResult{
+		Description: fmt.Sprintf("Summary of %s", validPath),
+		Messages:    messages,
+	}, nil
+}
+
+// HandleReviewChangesPrompt asks for a review of the changes made since a
+// checkpoint, embedding their diff and the changed files
+func (fs *FilesystemHandler) HandleReviewChangesPrompt(
+	ctx context.Context,
+	request mcp.GetPromptRequest,
+) (*mcp.GetPromptResult, error) {
+	name := request.Params.Arguments["checkpoint"]
+	if name == "" {
+		return nil, fmt.Errorf("checkpoint is required")
+	}
+	if fs.checkpoints == nil {
+		return nil, fmt.Errorf("checkpoints are not enabled on this server")
+	}
+
+	manifest, err := fs.checkpoint(name)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := fs.checkpoints.diff(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("error comparing with checkpoint: %w", err)
+	}
+	if len(changes) == 0 {
+		return nil, fmt.Errorf("no changes in %s since checkpoint %s", manifest.Root, name)
+	}
+
+	// The diff is cut at the inline size so that the prompt stays readable
+	var diff strings.Builder
+	var files []string
+	truncated := false
+	for _, change := range changes {
+		fileDiff, err := fs.checkpoints.fileDiff(fs.backend, manifest, change)
+		if err != nil {
+			return nil, fmt.Errorf("error comparing %s: %w", change.Path, err)
+		}
+		if truncated || int64(diff.Len()+len(fileDiff)) > fs.limits.MaxInlineSize {
+			truncated = true
+		} else {
+			diff.WriteString(fileDiff)
+		}
+		if fileDiff != "" && change.Status != "removed" {
+			files = append(files, filepath.Join(manifest.Root, filepath.FromSlash(change.Path)))
+		}
+	}
+	if truncated {
+		diff.WriteString("\n(diff truncated, use checkpoint_diff and read_file for the remaining files)\n")
+	}
+
+	messages := []mcp.PromptMessage{
+		{
+			Role: mcp.RoleUser,
+			Content: mcp.TextContent{
+				Type: "text",
+				Text: fmt.Sprintf(
+					"Review the changes made in %s since checkpoint %s (taken %s). Check them for "+
+						"bugs, unintended edits and missing pieces, and say whether they are ready "+
+						"to keep or should be reverted with checkpoint_restore.\n\n"+
+						"%d change(s):\n\n%s\nDiff:\n\n%s",
+					manifest.Root, name, manifest.Created.Format("2006-01-02 15:04:05"),
+					len(changes), formatCheckpointChanges(changes), diff.String(),
+				),
+			},
+		},
+	}
+	messages = append(messages, fs.promptResources(ctx, files)...)
+
+	return &mcp.GetPromptResult{
+		Description: fmt.Sprintf("Review of the changes since checkpoint %s", name),
+		Messages:    messages,
+	}, nil
+}
+
+// HandleFindUsagesPrompt asks for an explanation of where and how a symbol
+// is used, embedding the matching lines and files
+func (fs *FilesystemHandler) HandleFindUsagesPrompt(
+	ctx context.Context,
+	request mcp.GetPromptRequest,
+) (*mcp.GetPromptResult, error) {
+	symbol := request.Params.Arguments["symbol"]
+	if symbol == "" {
+		return nil, fmt.Errorf("symbol is required")
+	}
+	path := request.Params.Arguments["path"]
+	if path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+
+	validPath, err := fs.validatePath(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := fs.backend.Stat(validPath)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", validPath)
+	}
+
+	results, err := searchWithinFiles(validPath, symbol, 0, maxPromptUsages, fs)
+	if err != nil {
+		return nil, fmt.Errorf("error searching within files: %w", err)
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no usages of %q found in %s", symbol, validPath)
+	}
+
+	var usages strings.Builder
+	var files []string
+	for _, result := range results {
+		usages.WriteString(fmt.Sprintf("%s:%d: %s\n", result.FilePath, result.LineNumber, strings.TrimSpace(result.LineContent)))
+		if !slices.Contains(files, result.FilePath) {
+			files = append(files, result.FilePath)
+		}
+	}
+	if len(results) == maxPromptUsages {
+		usages.WriteString(fmt.Sprintf("(stopped after %d matches)\n", maxPromptUsages))
+	}
+
+	messages := []mcp.PromptMessage{
+		{
+			Role: mcp.RoleUser,
+			Content: mcp.TextContent{
+				Type: "text",
+				Text: fmt.Sprintf(
+					"Find and explain the usages of %q in %s. Tell where it is defined, group "+
+						"the places that use it by purpose and explain what each of them relies on. "+
+						"Mention usages that look wrong or inconsistent.\n\n"+
+						"%d match(es) in %d file(s):\n\n%s",
+					symbol, validPath, len(results), len(files), usages.String(),
+				),
+			},
+		},
+	}
+	messages = append(messages, fs.promptResources(ctx, files)...)
+
+	return &mcp.GetPromptResult{
+		Description: fmt.Sprintf("Usages of %q in %s", symbol, validPath),
+		Messages:    messages,
+	}, nil
+}
+
+// promptResources embeds the first maxPromptResources of files as they are
+// served by the file:///{+path} resource template, up to the inline size in
+// total; the files past it are linked instead. Files that cannot be read
+// are left out.
+func (fs *FilesystemHandler) promptResources(ctx context.Context, files []string) []mcp.PromptMessage {
+	var messages []mcp.PromptMessage
+	var total int64
+	for _, file := range files {
+		if len(messages) == maxPromptResources {
+			break
+		}
+		uri := pathToResourceURI(file)
+		var content mcp.Content
+		if total < fs.limits.MaxInlineSize {
+			request := mcp.ReadResourceRequest{}
+			request.Params.URI = uri
+			contents, err := fs.HandleReadResource(ctx, request)
+			if err != nil || len(contents) == 0 {
+				continue
+			}
+			var size int64
+			var mimeType string
+			switch resource := contents[0].(type) {
+			case mcp.TextResourceContents:
+				size, mimeType = int64(len(resource.Text)), resource.MIMEType
+			case mcp.BlobResourceContents:
+				size, mimeType = int64(len(resource.Blob)), resource.MIMEType
+			}
+			if total+size <= fs.limits.MaxInlineSize {
+				total += size
+				content = mcp.EmbeddedResource{
+					Type:     "resource",
+					Resource: contents[0],
+				}
+			} else {
+				total = fs.limits.MaxInlineSize
+				content = mcp.NewResourceLink(uri, filepath.Base(file), "", mimeType)
+			}
+		} else {
+			content = mcp.NewResourceLink(uri, filepath.Base(file), "", fs.detectMimeType(file))
+		}
+		messages = append(messages, mcp.PromptMessage{
+			Role:    mcp.RoleUser,
+			Content: content,
+		})
+	}
+	return messages
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrompts(t *testing.T) {
+	tmpDir := t.TempDir()
+	allowedDirs := resolveAllowedDirs(t, tmpDir)
+	fsHandler, err := NewFilesystemHandler(allowedDirs)
+	require.NoError(t, err)
+	require.NoError(t, fsHandler.EnableCheckpoints(t.TempDir()))
+
+	ctx := context.Background()
+	workspace := filepath.Join(allowedDirs[0], "workspace")
+	require.NoError(t, os.MkdirAll(filepath.Join(workspace, "src"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(workspace, "src", "main.go"), []byte("package main\n\nfunc run() {}\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(workspace, "README.md"), []byte("# Workspace\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(workspace, "notes.txt"), []byte("call run() first\n"), 0644))
+
+	get := func(t *testing.T, handle func(context.Context, mcp.GetPromptRequest) (*mcp.GetPromptResult, error), args map[string]string) (*mcp.GetPromptResult, error) {
+		t.Helper()
+		req := mcp.GetPromptRequest{}
+		req.Params.Arguments = args
+		return handle(ctx, req)
+	}
+	resourceURIs := func(result *mcp.GetPromptResult) []string {
+		var uris []string
+		for _, message := range result.Messages {
+			if embedded, ok := message.Content.(mcp.EmbeddedResource); ok {
+				uris = append(uris, embedded.Resource.(mcp.TextResourceContents).URI)
+			}
+		}
+		return uris
+	}
+
+	t.Run("summarize directory", func(t *testing.T) {
+		result, err := get(t, fsHandler.HandleSummarizeDirectoryPrompt, map[string]string{"path": workspace})
+		require.NoError(t, err)
+		assert.Contains(t, result.Messages[0].Content.(mcp.TextContent).Text, "Summarize the directory "+workspace)
+		tree := result.Messages[1].Content.(mcp.EmbeddedResource).Resource.(mcp.TextResourceContents)
+		assert.Equal(t, "application/json", tree.MIMEType)
+		assert.Contains(t, tree.Text, "main.go")
+		assert.Equal(t, []string{pathToResourceURI(workspace), pathToResourceURI(filepath.Join(workspace, "README.md"))},
+			resourceURIs(result))
+
+		_, err = get(t, fsHandler.HandleSummarizeDirectoryPrompt, map[string]string{"path": filepath.Join(workspace, "README.md")})
+		assert.Error(t, err)
+		_, err = get(t, fsHandler.HandleSummarizeDirectoryPrompt, map[string]string{"path": filepath.Dir(allowedDirs[0])})
+		assert.Error(t, err)
+	})
+
+	t.Run("find usages", func(t *testing.T) {
+		result, err := get(t, fsHandler.HandleFindUsagesPrompt, map[string]string{"symbol": "run(", "path": workspace})
+		require.NoError(t, err)
+		text := result.Messages[0].Content.(mcp.TextContent).Text
+		assert.Contains(t, text, "2 match(es) in 2 file(s)")
+		assert.Contains(t, text, filepath.Join(workspace, "src", "main.go")+":3: func run() {}")
+		assert.Len(t, resourceURIs(result), 2)
+
+		_, err = get(t, fsHandler.HandleFindUsagesPrompt, map[string]string{"symbol": "missing", "path": workspace})
+		assert.Error(t, err)
+
+		// Files past the inline size in total are linked instead of embedded
+		fsHandler.SetLimits(Limits{MaxInlineSize: 40})
+		defer fsHandler.SetLimits(DefaultLimits())
+		result, err = get(t, fsHandler.HandleFindUsagesPrompt, map[string]string{"symbol": "run(", "path": workspace})
+		require.NoError(t, err)
+		require.Len(t, result.Messages, 3)
+		assert.Len(t, resourceURIs(result), 1)
+		link, ok := result.Messages[2].Content.(mcp.ResourceLink)
+		require.True(t, ok, "%T", result.Messages[2].Content)
+		assert.Equal(t, "resource_link", link.Type)
+		assert.NotContains(t, resourceURIs(result), link.URI)
+	})
+
+	t.Run("review changes", func(t *testing.T) {
+		req := mcp.CallToolRequest{}
+		req.Params.Arguments = map[string]any{"name": "before", "path": workspace}
+		res, err := fsHandler.HandleCheckpointCreate(ctx, req)
+		require.NoError(t, err)
+		require.False(t, res.IsError)
+
+		_, err = get(t, fsHandler.HandleReviewChangesPrompt, map[string]string{"checkpoint": "before"})
+		assert.Error(t, err, "no changes yet")
+
+		require.NoError(t, os.WriteFile(filepath.Join(workspace, "src", "main.go"), []byte("package main\n\nfunc run() { panic(1) }\n"), 0644))
+		require.NoError(t, os.Remove(filepath.Join(workspace, "notes.txt")))
+		require.NoError(t, os.WriteFile(filepath.Join(workspace, "new.txt"), []byte("added\n"), 0644))
+
+		result, err := get(t, fsHandler.HandleReviewChangesPrompt, map[string]string{"checkpoint": "before"})
+		require.NoError(t, err)
+		text := result.Messages[0].Content.(mcp.TextContent).Text
+		assert.Contains(t, text, "3 change(s)")
+		assert.Contains(t, text, "-func run() {}\n+func run() { panic(1) }\n")
+		assert.Contains(t, text, "--- a/notes.txt\n+++ /dev/null\n")
+		assert.Contains(t, text, "+++ b/new.txt\n")
+		assert.Equal(t, []string{
+			pathToResourceURI(filepath.Join(workspace, "new.txt")),
+			pathToResourceURI(filepath.Join(workspace, "src", "main.go")),
+		}, resourceURIs(result))
+
+		completion, err := fsHandler.CompletePromptArgument(ctx, "review_changes",
+			mcp.CompleteArgument{Name: "checkpoint", Value: "be"}, mcp.CompleteContext{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"before"}, completion.Values)
+
+		// Checkpoints of directories that are no longer allowed are refused
+		require.NoError(t, fsHandler.SetAllowedDirectories(resolveAllowedDirs(t, t.TempDir()), "test"))
+		defer func() { require.NoError(t, fsHandler.SetAllowedDirectories(allowedDirs, "test")) }()
+		_, err = get(t, fsHandler.HandleReviewChangesPrompt, map[string]string{"checkpoint": "before"})
+		assert.ErrorContains(t, err, "outside allowed directories")
+	})
+}
@@ -36,6 +36,11 @@ func (fs *FilesystemHandler) HandleReadFile(
 		path = cwd
 	}
 
+	// Paths like bundle.zip!/dir/file.txt point into an archive
+	if archivePath, name, ok := splitArchivePath(path); ok {
+		return fs.readArchiveFile(archivePath, name)
+	}
+
 	validPath, err := fs.validatePath(path)
 	if err != nil {
 		return &mcp.CallToolResult{
@@ -50,7 +55,7 @@ func (fs *FilesystemHandler) HandleReadFile(
 	}
 
 	// Check if it's a directory
-	info, err := os.Stat(validPath)
+	info, err := fs.backend.Stat(validPath)
 	if err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
@@ -85,10 +90,10 @@ func (fs *FilesystemHandler) HandleReadFile(
 	}
 
 	// Determine MIME type
-	mimeType := detectMimeType(validPath)
+	mimeType := fs.detectMimeType(validPath)
 
 	// Check file size
-	if info.Size() > MAX_INLINE_SIZE {
+	if info.Size() > fs.limits.MaxInlineSize {
 		// File is too large to inline, return a resource reference
 		resourceURI := pathToResourceURI(validPath)
 		return &mcp.CallToolResult{
@@ -110,7 +115,7 @@ func (fs *FilesystemHandler) HandleReadFile(
 	}
 
 	// Read file content
-	content, err := os.ReadFile(validPath)
+	content, err := readFile(fs.backend, validPath)
 	if err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
@@ -123,20 +128,27 @@ func (fs *FilesystemHandler) HandleReadFile(
 		}, nil
 	}
 
-	// Check if it's a text file
-	if isTextFile(mimeType) {
+	// Check if it's a text file, transcoding text that is not UTF-8
+	if text, enc, ok := decodeFileText(content, mimeType); ok {
 		// It's a text file, return as text
-		return &mcp.CallToolResult{
+		result := &mcp.CallToolResult{
 			Content: []mcp.Content{
 				mcp.TextContent{
 					Type: "text",
-					Text: string(content),
+					Text: text,
 				},
 			},
-		}, nil
+		}
+		if !enc.plain() {
+			result.Content = append(result.Content, mcp.TextContent{
+				Type: "text",
+				Text: fmt.Sprintf("Encoding: %s (transcoded to UTF-8)", enc),
+			})
+		}
+		return result, nil
 	} else if isImageFile(mimeType) {
 		// It's an image file, return as image content
-		if info.Size() <= MAX_BASE64_SIZE {
+		if info.Size() <= fs.limits.MaxBase64Size {
 			return &mcp.CallToolResult{
 				Content: []mcp.Content{
 					mcp.TextContent{
@@ -174,7 +186,7 @@ func (fs *FilesystemHandler) HandleReadFile(
 		// It's another type of binary file
 		resourceURI := pathToResourceURI(validPath)
 
-		if info.Size() <= MAX_BASE64_SIZE {
+		if info.Size() <= fs.limits.MaxBase64Size {
 			// Small enough for base64 encoding
 			return &mcp.CallToolResult{
 				Content: []mcp.Content{
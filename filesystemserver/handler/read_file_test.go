@@ -68,3 +68,24 @@ func TestReadfile_NoAccess(t *testing.T) {
 	assert.True(t, result.IsError)
 	assert.Contains(t, fmt.Sprint(result.Content[0]), "access denied - path outside allowed directories")
 }
+
+func TestReadfile_InlineSizeLimit(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "test"), []byte("more than sixteen bytes"), 0644)
+	require.NoError(t, err)
+
+	handler, err := NewFilesystemHandler(resolveAllowedDirs(t, dir))
+	require.NoError(t, err)
+	handler.SetLimits(Limits{MaxInlineSize: 16})
+	assert.Equal(t, MAX_SEARCH_RESULTS, handler.Limits().MaxSearchResults)
+
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "read_file"
+	request.Params.Arguments = map[string]any{
+		"path": filepath.Join(dir, "test"),
+	}
+
+	result, err := handler.HandleReadFile(context.Background(), request)
+	require.NoError(t, err)
+	assert.Contains(t, result.Content[0].(mcp.TextContent).Text, "too large to display inline")
+}
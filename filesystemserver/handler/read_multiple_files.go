@@ -71,7 +71,7 @@ func (fs *FilesystemHandler) HandleReadMultipleFiles(
 		}
 
 		// Check if it's a directory
-		info, err := os.Stat(validPath)
+		info, err := fs.backend.Stat(validPath)
 		if err != nil {
 			results = append(results, mcp.TextContent{
 				Type: "text",
@@ -91,10 +91,10 @@ func (fs *FilesystemHandler) HandleReadMultipleFiles(
 		}
 
 		// Determine MIME type
-		mimeType := detectMimeType(validPath)
+		mimeType := fs.detectMimeType(validPath)
 
 		// Check file size
-		if info.Size() > MAX_INLINE_SIZE {
+		if info.Size() > fs.limits.MaxInlineSize {
 			// File is too large to inline, return a resource reference
 			resourceURI := pathToResourceURI(validPath)
 			results = append(results, mcp.TextContent{
@@ -106,7 +106,7 @@ func (fs *FilesystemHandler) HandleReadMultipleFiles(
 		}
 
 		// Read file content
-		content, err := os.ReadFile(validPath)
+		content, err := readFile(fs.backend, validPath)
 		if err != nil {
 			results = append(results, mcp.TextContent{
 				Type: "text",
@@ -115,22 +115,27 @@ func (fs *FilesystemHandler) HandleReadMultipleFiles(
 			continue
 		}
 
-		// Add file header
+		// Add file header, naming the encoding of text that is not UTF-8
+		text, enc, isText := decodeFileText(content, mimeType)
+		header := fmt.Sprintf("--- File: %s ---", path)
+		if isText && !enc.plain() {
+			header = fmt.Sprintf("--- File: %s (%s, transcoded to UTF-8) ---", path, enc)
+		}
 		results = append(results, mcp.TextContent{
 			Type: "text",
-			Text: fmt.Sprintf("--- File: %s ---", path),
+			Text: header,
 		})
 
 		// Check if it's a text file
-		if isTextFile(mimeType) {
+		if isText {
 			// It's a text file, return as text
 			results = append(results, mcp.TextContent{
 				Type: "text",
-				Text: string(content),
+				Text: text,
 			})
 		} else if isImageFile(mimeType) {
 			// It's an image file, return as image content
-			if info.Size() <= MAX_BASE64_SIZE {
+			if info.Size() <= fs.limits.MaxBase64Size {
 				results = append(results, mcp.TextContent{
 					Type: "text",
 					Text: fmt.Sprintf("Image file: %s (%s, %d bytes)", path, mimeType, info.Size()),
@@ -153,7 +158,7 @@ func (fs *FilesystemHandler) HandleReadMultipleFiles(
 			// It's another type of binary file
 			resourceURI := pathToResourceURI(validPath)
 
-			if info.Size() <= MAX_BASE64_SIZE {
+			if info.Size() <= fs.limits.MaxBase64Size {
 				// Small enough for base64 encoding
 				results = append(results, mcp.TextContent{
 					Type: "text",
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func (fs *FilesystemHandler) HandleRemoveAllowedDirectory(
+	ctx context.Context,
+	request mcp.CallToolRequest,
+) (*mcp.CallToolResult, error) {
+	if !fs.directoryAdmin {
+		return directoryAdminDisabledResult(), nil
+	}
+
+	path, err := request.RequireString("path")
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := fs.RemoveAllowedDirectory(path)
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+	notifyAllowedDirectoriesChanged(ctx)
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: fmt.Sprintf("Removed allowed directory %s", dir),
+			},
+		},
+	}, nil
+}
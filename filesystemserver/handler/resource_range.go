@@ -0,0 +1,364 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"golang.org/x/text/transform"
+)
+
+// resourceRange is the part of a file addressed by the query of a resource
+// URI: ?offset=N&length=M selects bytes and ?lines=A-B selects lines
+type resourceRange struct {
+	offset, length int64
+	// firstLine and lastLine are 1-based; lastLine 0 reads to the end
+	firstLine, lastLine int
+}
+
+// parseResourceRange returns the range in query, or nil if it has none
+func parseResourceRange(query url.Values) (*resourceRange, error) {
+	if len(query) == 0 {
+		return nil, nil
+	}
+	r := &resourceRange{length: -1}
+	for name := range query {
+		switch name {
+		case "offset", "length", "lines":
+		default:
+			return nil, fmt.Errorf("unknown query parameter %q (expected offset, length or lines)", name)
+		}
+	}
+
+	if lines := query.Get("lines"); lines != "" {
+		if query.Has("offset") || query.Has("length") {
+			return nil, errors.New("lines cannot be combined with offset or length")
+		}
+		first, last, found := strings.Cut(lines, "-")
+		var err error
+		if r.firstLine, err = strconv.Atoi(first); err != nil || r.firstLine < 1 {
+			return nil, fmt.Errorf("invalid lines %q (expected e.g. 10-20 or 10-)", lines)
+		}
+		r.lastLine = r.firstLine
+		if found {
+			r.lastLine = 0
+			if last != "" {
+				if r.lastLine, err = strconv.Atoi(last); err != nil || r.lastLine < r.firstLine {
+					return nil, fmt.Errorf("invalid lines %q (expected e.g. 10-20 or 10-)", lines)
+				}
+			}
+		}
+		return r, nil
+	}
+
+	var err error
+	if value := query.Get("offset"); value != "" {
+		if r.offset, err = strconv.ParseInt(value, 10, 64); err != nil || r.offset < 0 {
+			return nil, fmt.Errorf("invalid offset %q", value)
+		}
+	}
+	if value := query.Get("length"); value != "" {
+		if r.length, err = strconv.ParseInt(value, 10, 64); err != nil || r.length < 1 {
+			return nil, fmt.Errorf("invalid length %q", value)
+		}
+	}
+	return r, nil
+}
+
+// readResourceRange returns the part of the file at path selected by r.
+// Chunks are limited to the inline size for text and the base64 size for
+// binary files; _meta tells the client where the next chunk starts. Offsets
+// count bytes of the file as stored, while text is decoded from the encoding
+// detected at the start of the file.
+func (fs *FilesystemHandler) readResourceRange(
+	uri string,
+	path string,
+	info os.FileInfo,
+	r *resourceRange,
+) ([]mcp.ResourceContents, error) {
+	mimeType := fs.detectMimeType(path)
+	text := isTextFile(mimeType)
+	base, _, _ := strings.Cut(uri, "?")
+
+	enc, detected, err := sampleEncoding(fs.backend, path)
+	if err != nil {
+		return nil, err
+	}
+	// MIME detection takes UTF-16 without a BOM for binary
+	if detected && isUTF16(enc) {
+		text = true
+	}
+	if !text || !detected {
+		enc = textEncoding{name: EncodingUTF8}
+	}
+
+	file, err := fs.backend.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if r.firstLine > 0 {
+		if !text {
+			return nil, fmt.Errorf("line ranges need a text file, %s is %s", path, mimeType)
+		}
+		var reader io.Reader = file
+		if enc.bom {
+			if _, err := io.CopyN(io.Discard, file, int64(len(encodingBOMs[enc.name]))); err != nil {
+				return nil, err
+			}
+		}
+		if enc.name != EncodingUTF8 {
+			reader = transform.NewReader(file, textEncodings[enc.name].NewDecoder())
+		}
+		return fs.readLineRange(uri, base, reader, mimeType, enc, r)
+	}
+
+	limit := fs.limits.MaxBase64Size
+	if text {
+		limit = fs.limits.MaxInlineSize
+	}
+	length := r.length
+	if length < 0 || length > limit {
+		length = limit
+	}
+	if r.offset > info.Size() {
+		return nil, fmt.Errorf("offset %d is beyond the end of the file (%d bytes)", r.offset, info.Size())
+	}
+	if text && isUTF16(enc) {
+		if r.offset%2 != 0 {
+			return nil, fmt.Errorf("offset %d splits a character of this %s file (expected an even offset)", r.offset, enc.name)
+		}
+		length -= length % 2
+		if length == 0 {
+			length = 2
+		}
+	}
+
+	if seeker, ok := file.(io.Seeker); ok {
+		_, err = seeker.Seek(r.offset, io.SeekStart)
+	} else {
+		_, err = io.CopyN(io.Discard, file, r.offset)
+	}
+	if err != nil {
+		return nil, err
+	}
+	content := make([]byte, length)
+	n, err := io.ReadFull(file, content)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	content = content[:n]
+
+	// The start of the file looked like UTF-8 but this part does not, not
+	// counting a character cut off at its end
+	if text && enc.plain() && !utf8.Valid(content) {
+		if chunkEnc, ok := detectTruncatedEncoding(content); ok && !isUTF16(chunkEnc) && !chunkEnc.plain() {
+			enc = chunkEnc
+		}
+	}
+
+	// End text chunks on a character boundary so that the next one starts on one
+	end := r.offset + int64(n)
+	if text && end < info.Size() {
+		content = trimPartialCharacter(content, enc)
+		end = r.offset + int64(len(content))
+	}
+
+	meta := map[string]any{
+		"offset": r.offset,
+		"length": len(content),
+		"size":   info.Size(),
+	}
+	if end < info.Size() {
+		meta["next"] = fmt.Sprintf("%s?offset=%d&length=%d", base, end, length)
+	}
+
+	if text {
+		// Only the first chunk starts with the byte order mark
+		chunkEnc := enc
+		chunkEnc.bom = enc.bom && r.offset == 0
+		if decoded, err := decodeText(content, chunkEnc); err == nil && utf8.ValidString(decoded) {
+			if !enc.plain() {
+				meta["encoding"] = enc.String()
+			}
+			return []mcp.ResourceContents{
+				mcp.TextResourceContents{
+					Meta:     meta,
+					URI:      uri,
+					MIMEType: mimeType,
+					Text:     decoded,
+				},
+			}, nil
+		}
+	}
+	return []mcp.ResourceContents{
+		mcp.BlobResourceContents{
+			Meta:     meta,
+			URI:      uri,
+			MIMEType: mimeType,
+			Blob:     base64.StdEncoding.EncodeToString(content),
+		},
+	}, nil
+}
+
+// readLineRange returns lines r.firstLine to r.lastLine of the decoded text
+// in file, stopping early at the inline size limit. A first line longer than
+// the limit is cut at it and marked as truncated.
+func (fs *FilesystemHandler) readLineRange(
+	uri string,
+	base string,
+	file io.Reader,
+	mimeType string,
+	enc textEncoding,
+	r *resourceRange,
+) ([]mcp.ResourceContents, error) {
+	reader := bufio.NewReader(file)
+	limit := fs.limits.MaxInlineSize
+	var result strings.Builder
+	line := 0
+	lastRead := 0
+	more := false
+	truncated := false
+	for {
+		// Lines before the range are skipped without keeping them
+		var keep int64
+		if line+1 >= r.firstLine {
+			keep = limit
+		}
+		content, size, err := readLine(reader, keep)
+		if size > 0 {
+			line++
+			if line >= r.firstLine {
+				if r.lastLine > 0 && line > r.lastLine {
+					more = true
+					break
+				}
+				if int64(result.Len())+size > limit {
+					if lastRead > 0 {
+						more = true
+						break
+					}
+					content = string(trimPartialRune([]byte(content)))
+					truncated = true
+				}
+				result.WriteString(content)
+				lastRead = line
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if lastRead == 0 {
+		return nil, fmt.Errorf("line %d is beyond the end of the file (%d lines)", r.firstLine, line)
+	}
+
+	meta := map[string]any{
+		"startLine": r.firstLine,
+		"endLine":   lastRead,
+	}
+	if truncated {
+		meta["truncated"] = true
+	}
+	if !enc.plain() {
+		meta["encoding"] = enc.String()
+	}
+	// Only offer the next chunk if the requested range was cut short
+	if more && (r.lastLine == 0 || lastRead < r.lastLine) {
+		next := fmt.Sprintf("%s?lines=%d-", base, lastRead+1)
+		if r.lastLine > 0 {
+			next += strconv.Itoa(r.lastLine)
+		}
+		meta["next"] = next
+	}
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			Meta:     meta,
+			URI:      uri,
+			MIMEType: mimeType,
+			Text:     result.String(),
+		},
+	}, nil
+}
+
+// readLine reads the next line from reader, including its line break, and
+// returns at most its first keep bytes along with the size of the whole line
+func readLine(reader *bufio.Reader, keep int64) (string, int64, error) {
+	var line strings.Builder
+	var size int64
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		size += int64(len(chunk))
+		if room := keep - int64(line.Len()); room > 0 {
+			if int64(len(chunk)) > room {
+				chunk = chunk[:room]
+			}
+			line.Write(chunk)
+		}
+		if err != bufio.ErrBufferFull {
+			return line.String(), size, err
+		}
+	}
+}
+
+// sampleEncoding detects the encoding of the file at path from its start
+func sampleEncoding(b Backend, path string) (textEncoding, bool, error) {
+	file, err := b.Open(path)
+	if err != nil {
+		return textEncoding{}, false, err
+	}
+	defer file.Close()
+	sample, err := io.ReadAll(io.LimitReader(file, maxEncodingSample))
+	if err != nil {
+		return textEncoding{}, false, err
+	}
+	enc, ok := detectTruncatedEncoding(sample)
+	return enc, ok, nil
+}
+
+// trimPartialCharacter drops an incomplete character of enc at the end of data
+func trimPartialCharacter(data []byte, enc textEncoding) []byte {
+	switch {
+	case enc.name == EncodingUTF8:
+		return trimPartialRune(data)
+	case isUTF16(enc):
+		data = data[:len(data)-len(data)%2]
+		if len(data) < 2 {
+			return data
+		}
+		unit := uint16(data[len(data)-2])<<8 | uint16(data[len(data)-1])
+		if enc.name == EncodingUTF16LE {
+			unit = uint16(data[len(data)-1])<<8 | uint16(data[len(data)-2])
+		}
+		// A high surrogate needs the low one that follows it
+		if unit >= 0xD800 && unit <= 0xDBFF {
+			return data[:len(data)-2]
+		}
+	}
+	return data
+}
+
+// trimPartialRune drops an incomplete UTF-8 sequence at the end of data
+func trimPartialRune(data []byte) []byte {
+	for i := 1; i <= utf8.UTFMax && i <= len(data); i++ {
+		if utf8.RuneStart(data[len(data)-i]) {
+			if !utf8.FullRune(data[len(data)-i:]) {
+				return data[:len(data)-i]
+			}
+			break
+		}
+	}
+	return data
+}
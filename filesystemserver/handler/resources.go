@@ -4,8 +4,11 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"mime"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/mark3labs/mcp-go/mcp"
@@ -18,13 +21,19 @@ func (fs *FilesystemHandler) HandleReadResource(
 ) ([]mcp.ResourceContents, error) {
 	uri := request.Params.URI
 
-	// Check if it's a file:// URI
-	if !strings.HasPrefix(uri, "file://") {
-		return nil, fmt.Errorf("unsupported URI scheme: %s", uri)
+	// Extract the path from the file:// URI
+	path, err := ResourceURIToPath(uri)
+	if err != nil {
+		return nil, err
 	}
 
-	// Extract the path from the URI
-	path := strings.TrimPrefix(uri, "file://")
+	// URIs like file:///bundle.zip!/dir point into an archive
+	if archivePath, name, ok := splitArchivePath(path); ok {
+		if u, err := url.Parse(uri); err == nil && u.RawQuery != "" {
+			return nil, fmt.Errorf("ranges cannot be read from inside archives")
+		}
+		return fs.readArchiveResource(uri, archivePath, name)
+	}
 
 	// Validate the path
 	validPath, err := fs.validatePath(path)
@@ -33,14 +42,30 @@ func (fs *FilesystemHandler) HandleReadResource(
 	}
 
 	// Get file info
-	fileInfo, err := os.Stat(validPath)
+	fileInfo, err := fs.backend.Stat(validPath)
 	if err != nil {
 		return nil, err
 	}
 
+	// Read part of the file if the URI has a range query
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	part, err := parseResourceRange(u.Query())
+	if err != nil {
+		return nil, err
+	}
+	if part != nil {
+		if fileInfo.IsDir() {
+			return nil, fmt.Errorf("ranges can only be read from files, %s is a directory", validPath)
+		}
+		return fs.readResourceRange(uri, validPath, fileInfo, part)
+	}
+
 	// If it's a directory, return a listing
 	if fileInfo.IsDir() {
-		entries, err := os.ReadDir(validPath)
+		entries, err := fs.backend.ReadDir(validPath)
 		if err != nil {
 			return nil, err
 		}
@@ -75,39 +100,45 @@ func (fs *FilesystemHandler) HandleReadResource(
 	}
 
 	// It's a file, determine how to handle it
-	mimeType := detectMimeType(validPath)
+	mimeType := fs.detectMimeType(validPath)
 
 	// Check file size
-	if fileInfo.Size() > MAX_INLINE_SIZE {
+	if fileInfo.Size() > fs.limits.MaxInlineSize {
 		// File is too large to inline, return a reference instead
 		return []mcp.ResourceContents{
 			mcp.TextResourceContents{
 				URI:      uri,
 				MIMEType: "text/plain",
-				Text:     fmt.Sprintf("File is too large to display inline (%d bytes). Use the read_file tool to access specific portions.", fileInfo.Size()),
+				Text: fmt.Sprintf(
+					"File is too large to display inline (%d bytes). Read it in pieces with %s?offset=0&length=%d or %s?lines=1-100.",
+					fileInfo.Size(), uri, fs.limits.MaxInlineSize, uri,
+				),
 			},
 		}, nil
 	}
 
 	// Read the file content
-	content, err := os.ReadFile(validPath)
+	content, err := readFile(fs.backend, validPath)
 	if err != nil {
 		return nil, err
 	}
 
 	// Handle based on content type
-	if isTextFile(mimeType) {
-		// It's a text file, return as text
+	if text, enc, ok := decodeFileText(content, mimeType); ok {
+		// It's a text file, return as text, which is UTF-8 once transcoded
+		if !enc.plain() {
+			mimeType = transcodedMimeType(mimeType)
+		}
 		return []mcp.ResourceContents{
 			mcp.TextResourceContents{
 				URI:      uri,
 				MIMEType: mimeType,
-				Text:     string(content),
+				Text:     text,
 			},
 		}, nil
 	} else {
 		// It's a binary file
-		if fileInfo.Size() <= MAX_BASE64_SIZE {
+		if fileInfo.Size() <= fs.limits.MaxBase64Size {
 			// Small enough for base64 encoding
 			return []mcp.ResourceContents{
 				mcp.BlobResourceContents{
@@ -122,9 +153,78 @@ func (fs *FilesystemHandler) HandleReadResource(
 				mcp.TextResourceContents{
 					URI:      uri,
 					MIMEType: "text/plain",
-					Text:     fmt.Sprintf("Binary file (%s, %d bytes). Use the read_file tool to access specific portions.", mimeType, fileInfo.Size()),
+					Text: fmt.Sprintf(
+						"Binary file (%s, %d bytes) is too large to return at once. Read it in pieces with %s?offset=0&length=%d.",
+						mimeType, fileInfo.Size(), uri, fs.limits.MaxBase64Size,
+					),
 				},
 			}, nil
 		}
 	}
-}
\ No newline at end of file
+}
+
+// HandleListResources lists the files under the allowed directories, one
+// page at a time. It replaces the result of resources/list and is registered
+// as a server.OnAfterListResourcesFunc hook.
+func (fs *FilesystemHandler) HandleListResources(
+	ctx context.Context,
+	id any,
+	request *mcp.ListResourcesRequest,
+	result *mcp.ListResourcesResult,
+) {
+	offset := 0
+	if cursor := request.Params.Cursor; cursor != "" {
+		decoded, err := base64.StdEncoding.DecodeString(string(cursor))
+		if err == nil {
+			offset, err = strconv.Atoi(string(decoded))
+		}
+		if err != nil || offset < 0 {
+			// An invalid cursor lists nothing
+			result.Resources = []mcp.Resource{}
+			result.NextCursor = ""
+			return
+		}
+	}
+
+	resources := []mcp.Resource{}
+	seen := make(map[string]bool)
+	index := 0
+	more := false
+	for _, dir := range fs.AllowedDirectories() {
+		err := walk(fs.backend, dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				// Skip unreadable entries
+				return nil
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			// Symlinks are left out since they may point outside
+			if !info.Mode().IsRegular() || seen[path] {
+				return nil
+			}
+			seen[path] = true
+			if index++; index <= offset {
+				return nil
+			}
+			if len(resources) == RESOURCE_PAGE_SIZE {
+				more = true
+				return filepath.SkipAll
+			}
+			resource := mcp.NewResource(pathToResourceURI(path), path)
+			resource.MIMEType = mime.TypeByExtension(filepath.Ext(path))
+			resources = append(resources, resource)
+			return nil
+		})
+		if err != nil || more {
+			break
+		}
+	}
+
+	result.Resources = resources
+	result.NextCursor = ""
+	if more {
+		next := strconv.Itoa(offset + len(resources))
+		result.NextCursor = mcp.Cursor(base64.StdEncoding.EncodeToString([]byte(next)))
+	}
+}
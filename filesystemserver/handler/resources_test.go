@@ -0,0 +1,303 @@
+package handler
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResourceURIs(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	path := filepath.Join(dir, "notes 100%.txt")
+	require.NoError(t, os.WriteFile(path, []byte("hello"), 0644))
+
+	fsHandler, err := NewFilesystemHandler([]string{dir})
+	require.NoError(t, err)
+
+	uri := pathToResourceURI(path)
+	assert.Contains(t, uri, "notes%20100%25.txt")
+	decoded, err := ResourceURIToPath(uri)
+	require.NoError(t, err)
+	assert.Equal(t, path, decoded)
+
+	request := mcp.ReadResourceRequest{}
+	request.Params.URI = uri
+	contents, err := fsHandler.HandleReadResource(context.Background(), request)
+	require.NoError(t, err)
+	require.Len(t, contents, 1)
+	assert.Equal(t, "hello", contents[0].(mcp.TextResourceContents).Text)
+
+	_, err = ResourceURIToPath("https://example.com/notes.txt")
+	assert.Error(t, err)
+}
+
+func TestHandleListResources(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0755))
+	for i := range RESOURCE_PAGE_SIZE + 20 {
+		name := filepath.Join(dir, fmt.Sprintf("file%03d.txt", i))
+		if i%2 == 0 {
+			name = filepath.Join(dir, "sub", fmt.Sprintf("file%03d.txt", i))
+		}
+		require.NoError(t, os.WriteFile(name, []byte("x"), 0644))
+	}
+
+	fsHandler, err := NewFilesystemHandler([]string{dir})
+	require.NoError(t, err)
+
+	list := func(cursor mcp.Cursor) *mcp.ListResourcesResult {
+		request := &mcp.ListResourcesRequest{}
+		request.Params.Cursor = cursor
+		result := &mcp.ListResourcesResult{}
+		fsHandler.HandleListResources(context.Background(), 1, request, result)
+		return result
+	}
+
+	first := list("")
+	require.Len(t, first.Resources, RESOURCE_PAGE_SIZE)
+	require.NotEmpty(t, first.NextCursor)
+	assert.Equal(t, pathToResourceURI(filepath.Join(dir, "file001.txt")), first.Resources[0].URI)
+	assert.Contains(t, first.Resources[0].MIMEType, "text/plain")
+
+	second := list(first.NextCursor)
+	assert.Len(t, second.Resources, 20)
+	assert.Empty(t, second.NextCursor)
+
+	seen := make(map[string]bool)
+	for _, resource := range append(first.Resources, second.Resources...) {
+		assert.False(t, seen[resource.URI], "listed twice: %s", resource.URI)
+		seen[resource.URI] = true
+	}
+
+	assert.Empty(t, list("not a cursor").Resources)
+}
+
+func TestCompleteResourceArgument(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "src"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "setup.py"), []byte(""), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte(""), 0644))
+
+	fsHandler, err := NewFilesystemHandler([]string{dir})
+	require.NoError(t, err)
+
+	complete := func(value string) []string {
+		completion, err := fsHandler.CompleteResourceArgument(context.Background(), "file:///{+path}",
+			mcp.CompleteArgument{Name: "path", Value: value}, mcp.CompleteContext{})
+		require.NoError(t, err)
+		return completion.Values
+	}
+	// The template variable has no leading slash
+	templateValue := func(path string) string {
+		return filepath.ToSlash(path)[1:]
+	}
+	if filepath.VolumeName(dir) != "" {
+		templateValue = filepath.ToSlash
+	}
+
+	assert.Equal(t, []string{templateValue(dir) + "/"}, complete(templateValue(dir)[:2]))
+	assert.Equal(t, []string{templateValue(dir) + "/setup.py", templateValue(dir) + "/src/"},
+		complete(templateValue(dir)+"/s"))
+	assert.Len(t, complete(templateValue(dir)+"/"), 3)
+	assert.Empty(t, complete(templateValue(filepath.Dir(dir))+"/other/"))
+}
+
+func TestReadResourceRange(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	textPath := filepath.Join(dir, "log.txt")
+	var lines []string
+	for i := 1; i <= 50; i++ {
+		lines = append(lines, fmt.Sprintf("line %02d", i))
+	}
+	require.NoError(t, os.WriteFile(textPath, []byte(strings.Join(lines, "\n")+"\n"), 0644))
+	binPath := filepath.Join(dir, "data.bin")
+	binary := make([]byte, 300)
+	for i := range binary {
+		binary[i] = byte(i)
+	}
+	require.NoError(t, os.WriteFile(binPath, binary, 0644))
+	utf16Path := filepath.Join(dir, "utf16.txt")
+	utf16Content, err := encodeText("héllo\nwörld\n", textEncoding{name: EncodingUTF16LE, bom: true})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(utf16Path, utf16Content, 0644))
+	latin1Path := filepath.Join(dir, "latin1.txt")
+	require.NoError(t, os.WriteFile(latin1Path, []byte("caf\xe9\nna\xefve\n"), 0644))
+	boundaryPath := filepath.Join(dir, "boundary.txt")
+	require.NoError(t, os.WriteFile(boundaryPath, []byte(strings.Repeat("a", 4095)+"é tail\n"), 0644))
+	shortPath := filepath.Join(dir, "short.txt")
+	require.NoError(t, os.WriteFile(shortPath, []byte("abcé def\n"), 0644))
+	longPath := filepath.Join(dir, "long.txt")
+	require.NoError(t, os.WriteFile(longPath, []byte(strings.Repeat("é", 80)+"\nshort\n"), 0644))
+
+	fsHandler, err := NewFilesystemHandler([]string{dir})
+	require.NoError(t, err)
+	fsHandler.SetLimits(Limits{MaxInlineSize: 100, MaxBase64Size: 128})
+
+	read := func(uri string) (mcp.ResourceContents, error) {
+		request := mcp.ReadResourceRequest{}
+		request.Params.URI = uri
+		contents, err := fsHandler.HandleReadResource(context.Background(), request)
+		if err != nil {
+			return nil, err
+		}
+		require.Len(t, contents, 1)
+		return contents[0], nil
+	}
+	textURI := pathToResourceURI(textPath)
+	binURI := pathToResourceURI(binPath)
+
+	t.Run("too large files point to ranges", func(t *testing.T) {
+		contents, err := read(textURI)
+		require.NoError(t, err)
+		assert.Contains(t, contents.(mcp.TextResourceContents).Text, textURI+"?offset=0&length=100")
+	})
+
+	t.Run("byte ranges", func(t *testing.T) {
+		contents, err := read(textURI + "?offset=8&length=16")
+		require.NoError(t, err)
+		text := contents.(mcp.TextResourceContents)
+		assert.Equal(t, "line 02\nline 03\n", text.Text)
+		assert.Equal(t, textURI+"?offset=24&length=16", text.Meta["next"])
+
+		// Chunks are capped at the limit
+		contents, err = read(textURI + "?offset=0")
+		require.NoError(t, err)
+		assert.Len(t, contents.(mcp.TextResourceContents).Text, 100)
+
+		contents, err = read(textURI + "?offset=392")
+		require.NoError(t, err)
+		text = contents.(mcp.TextResourceContents)
+		assert.Equal(t, "line 50\n", text.Text)
+		assert.NotContains(t, text.Meta, "next")
+	})
+
+	t.Run("binary chunks", func(t *testing.T) {
+		var data []byte
+		uri := binURI + "?offset=0"
+		for uri != "" {
+			contents, err := read(uri)
+			require.NoError(t, err)
+			blob := contents.(mcp.BlobResourceContents)
+			chunk, err := base64.StdEncoding.DecodeString(blob.Blob)
+			require.NoError(t, err)
+			assert.LessOrEqual(t, len(chunk), 128)
+			data = append(data, chunk...)
+			uri, _ = blob.Meta["next"].(string)
+		}
+		assert.Equal(t, binary, data)
+	})
+
+	t.Run("line ranges", func(t *testing.T) {
+		contents, err := read(textURI + "?lines=3-4")
+		require.NoError(t, err)
+		text := contents.(mcp.TextResourceContents)
+		assert.Equal(t, "line 03\nline 04\n", text.Text)
+		assert.NotContains(t, text.Meta, "next")
+
+		// Open ranges stop at the limit and continue where they stopped
+		contents, err = read(textURI + "?lines=40-")
+		require.NoError(t, err)
+		text = contents.(mcp.TextResourceContents)
+		assert.Equal(t, strings.Join(lines[39:50], "\n")+"\n", text.Text)
+
+		contents, err = read(textURI + "?lines=1-20")
+		require.NoError(t, err)
+		text = contents.(mcp.TextResourceContents)
+		assert.Equal(t, 12, text.Meta["endLine"])
+		assert.Equal(t, textURI+"?lines=13-20", text.Meta["next"])
+	})
+
+	t.Run("ranges of other encodings are decoded", func(t *testing.T) {
+		uri := pathToResourceURI(utf16Path)
+		contents, err := read(uri + "?lines=2-2")
+		require.NoError(t, err)
+		text := contents.(mcp.TextResourceContents)
+		assert.Equal(t, "wörld\n", text.Text)
+		assert.Equal(t, "utf-16le with BOM", text.Meta["encoding"])
+
+		// The byte order mark is dropped and chunks keep whole characters
+		contents, err = read(uri + "?offset=0&length=9")
+		require.NoError(t, err)
+		text = contents.(mcp.TextResourceContents)
+		assert.Equal(t, "hél", text.Text)
+		assert.Equal(t, uri+"?offset=8&length=8", text.Meta["next"])
+		contents, err = read(uri + "?offset=8&length=8")
+		require.NoError(t, err)
+		assert.Equal(t, "lo\nw", contents.(mcp.TextResourceContents).Text)
+		_, err = read(uri + "?offset=3")
+		assert.Error(t, err)
+
+		uri = pathToResourceURI(latin1Path)
+		contents, err = read(uri + "?lines=2")
+		require.NoError(t, err)
+		assert.Equal(t, "naïve\n", contents.(mcp.TextResourceContents).Text)
+		contents, err = read(uri + "?offset=0&length=4")
+		require.NoError(t, err)
+		assert.Equal(t, "café", contents.(mcp.TextResourceContents).Text)
+	})
+
+	t.Run("characters split by the sample or chunk stay UTF-8", func(t *testing.T) {
+		// The encoding sample ends inside the é
+		uri := pathToResourceURI(boundaryPath)
+		contents, err := read(uri + "?lines=1")
+		require.NoError(t, err)
+		text := contents.(mcp.TextResourceContents)
+		assert.NotContains(t, text.Meta, "encoding")
+		contents, err = read(uri + "?offset=4090&length=20")
+		require.NoError(t, err)
+		text = contents.(mcp.TextResourceContents)
+		assert.Equal(t, "aaaaaé tail\n", text.Text)
+		assert.NotContains(t, text.Meta, "encoding")
+
+		// The chunk ends inside the é
+		uri = pathToResourceURI(shortPath)
+		contents, err = read(uri + "?offset=0&length=4")
+		require.NoError(t, err)
+		text = contents.(mcp.TextResourceContents)
+		assert.Equal(t, "abc", text.Text)
+		assert.Equal(t, uri+"?offset=3&length=4", text.Meta["next"])
+		contents, err = read(uri + "?offset=3&length=4")
+		require.NoError(t, err)
+		assert.Equal(t, "é d", contents.(mcp.TextResourceContents).Text)
+	})
+
+	t.Run("lines over the limit are truncated", func(t *testing.T) {
+		uri := pathToResourceURI(longPath)
+		contents, err := read(uri + "?lines=1-2")
+		require.NoError(t, err)
+		text := contents.(mcp.TextResourceContents)
+		assert.Equal(t, strings.Repeat("é", 50), text.Text)
+		assert.Equal(t, true, text.Meta["truncated"])
+		assert.Equal(t, 1, text.Meta["endLine"])
+		assert.Equal(t, uri+"?lines=2-2", text.Meta["next"])
+
+		contents, err = read(uri + "?lines=2")
+		require.NoError(t, err)
+		text = contents.(mcp.TextResourceContents)
+		assert.Equal(t, "short\n", text.Text)
+		assert.NotContains(t, text.Meta, "truncated")
+	})
+
+	t.Run("invalid ranges", func(t *testing.T) {
+		for _, query := range []string{"?lines=0", "?lines=5-2", "?offset=-1", "?length=0", "?offset=1&lines=2", "?page=2", "?offset=1000"} {
+			_, err := read(textURI + query)
+			assert.Error(t, err, query)
+		}
+		_, err := read(binURI + "?lines=1-2")
+		assert.Error(t, err)
+		_, err = read(pathToResourceURI(dir) + "?offset=0")
+		assert.Error(t, err)
+	})
+}
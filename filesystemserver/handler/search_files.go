@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
 
 	"github.com/gobwas/glob"
@@ -56,7 +55,7 @@ func (fs *FilesystemHandler) HandleSearchFiles(
 	}
 
 	// Check if it's a directory
-	info, err := os.Stat(validPath)
+	info, err := fs.backend.Stat(validPath)
 	if err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
@@ -112,7 +111,7 @@ func (fs *FilesystemHandler) HandleSearchFiles(
 
 	for _, result := range results {
 		resourceURI := pathToResourceURI(result)
-		info, err := os.Stat(result)
+		info, err := fs.backend.Stat(result)
 		if err == nil {
 			if info.IsDir() {
 				formattedResults.WriteString(fmt.Sprintf("[DIR]  %s (%s)\n", result, resourceURI))
@@ -139,7 +138,7 @@ func searchFiles(rootPath, pattern string, fs *FilesystemHandler) ([]string, err
 	var results []string
 	globPattern := glob.MustCompile(pattern)
 
-	err := filepath.Walk(
+	err := walk(fs.backend,
 		rootPath,
 		func(path string, info os.FileInfo, err error) error {
 			if err != nil {
@@ -161,4 +160,4 @@ func searchFiles(rootPath, pattern string, fs *FilesystemHandler) ([]string, err
 		return nil, err
 	}
 	return results, nil
-}
\ No newline at end of file
+}
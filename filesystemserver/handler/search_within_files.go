@@ -54,7 +54,7 @@ func (fs *FilesystemHandler) HandleSearchWithinFiles(
 	}
 
 	// Extract optional max_results parameter
-	maxResults := MAX_SEARCH_RESULTS // default limit
+	maxResults := fs.limits.MaxSearchResults // default limit
 	if maxResultsArg, err := request.RequireFloat("max_results"); err == nil {
 		maxResults = int(maxResultsArg)
 		if maxResults <= 0 {
@@ -88,6 +88,23 @@ func (fs *FilesystemHandler) HandleSearchWithinFiles(
 		path = cwd
 	}
 
+	// Paths like bundle.zip!/dir point into an archive
+	if archivePath, name, ok := splitArchivePath(path); ok {
+		results, err := fs.searchArchive(archivePath, name, substring, maxDepth, maxResults)
+		if err != nil {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					mcp.TextContent{
+						Type: "text",
+						Text: fmt.Sprintf("Error searching within files: %v", err),
+					},
+				},
+				IsError: true,
+			}, nil
+		}
+		return searchWithinFilesResult(results, substring, path, maxResults), nil
+	}
+
 	validPath, err := fs.validatePath(path)
 	if err != nil {
 		return &mcp.CallToolResult{
@@ -102,7 +119,7 @@ func (fs *FilesystemHandler) HandleSearchWithinFiles(
 	}
 
 	// Check if the path is a directory
-	info, err := os.Stat(validPath)
+	info, err := fs.backend.Stat(validPath)
 	if err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
@@ -141,6 +158,11 @@ func (fs *FilesystemHandler) HandleSearchWithinFiles(
 		}, nil
 	}
 
+	return searchWithinFilesResult(results, substring, path, maxResults), nil
+}
+
+// searchWithinFilesResult formats the matches found under path
+func searchWithinFilesResult(results []SearchResult, substring, path string, maxResults int) *mcp.CallToolResult {
 	if len(results) == 0 {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
@@ -149,7 +171,7 @@ func (fs *FilesystemHandler) HandleSearchWithinFiles(
 					Text: fmt.Sprintf("No occurrences of '%s' found in files under %s", substring, path),
 				},
 			},
-		}, nil
+		}
 	}
 
 	// Format search results
@@ -206,7 +228,7 @@ func (fs *FilesystemHandler) HandleSearchWithinFiles(
 				Text: formattedResults.String(),
 			},
 		},
-	}, nil
+	}
 }
 
 // searchWithinFiles searches for a substring within file contents
@@ -218,7 +240,7 @@ func searchWithinFiles(
 	currentDepth := 0
 
 	// Walk the directory tree
-	err := filepath.Walk(
+	err := walk(fs.backend,
 		rootPath,
 		func(path string, info os.FileInfo, err error) error {
 			if err != nil {
@@ -259,18 +281,18 @@ func searchWithinFiles(
 			}
 
 			// Skip files that are too large
-			if info.Size() > MAX_SEARCHABLE_SIZE {
+			if info.Size() > fs.limits.MaxSearchableSize {
 				return nil
 			}
 
 			// Determine MIME type and skip non-text files
-			mimeType := detectMimeType(validPath)
+			mimeType := fs.detectMimeType(validPath)
 			if !isTextFile(mimeType) {
 				return nil
 			}
 
 			// Open the file and search for the substring
-			file, err := os.Open(validPath)
+			file, err := fs.backend.Open(validPath)
 			if err != nil {
 				return nil // Skip files that can't be opened
 			}
@@ -0,0 +1,297 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// Name of the metadata file stored alongside each trashed item
+	trashMetaFile = "meta.json"
+	// Name under which the trashed file or directory itself is stored
+	trashItemName = "item"
+)
+
+// trash is a server-managed area that deleted items are moved into so that
+// they can be restored later. Each entry lives in its own subdirectory
+// containing the item and a metadata file.
+type trash struct {
+	dir       string
+	retention time.Duration
+	mu        sync.Mutex
+}
+
+// EnableTrash switches delete_file into soft-delete mode. Deleted items are
+// moved into dir and kept for retention (zero keeps them until empty_trash).
+func (fs *FilesystemHandler) EnableTrash(dir string, retention time.Duration) error {
+	if !fs.localDisk() {
+		return fmt.Errorf("the trash requires the local disk backend")
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve trash directory %s: %w", dir, err)
+	}
+	if err := os.MkdirAll(abs, 0700); err != nil {
+		return fmt.Errorf("failed to create trash directory %s: %w", abs, err)
+	}
+	// Resolve symlinks so the trash can be compared against validated paths
+	realDir, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return fmt.Errorf("failed to resolve trash directory %s: %w", abs, err)
+	}
+
+	fs.trash = &trash{
+		dir:       filepath.Clean(realDir),
+		retention: retention,
+	}
+	return nil
+}
+
+// contains reports whether path is the trash directory or inside it
+func (t *trash) contains(path string) bool {
+	return path == t.dir || strings.HasPrefix(path, t.dir+string(filepath.Separator))
+}
+
+// put moves the item at path into the trash and returns its entry
+func (t *trash) put(path string) (*TrashEntry, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := newTrashID()
+	if err != nil {
+		return nil, err
+	}
+
+	entryDir := filepath.Join(t.dir, id)
+	if err := os.Mkdir(entryDir, 0700); err != nil {
+		return nil, err
+	}
+
+	entry := &TrashEntry{
+		ID:           id,
+		OriginalPath: path,
+		DeletedAt:    time.Now().UTC(),
+		IsDirectory:  info.IsDir(),
+		Size:         info.Size(),
+	}
+	if err := writeTrashMeta(entryDir, entry); err != nil {
+		os.RemoveAll(entryDir)
+		return nil, err
+	}
+
+	if err := movePath(path, filepath.Join(entryDir, trashItemName)); err != nil {
+		os.RemoveAll(entryDir)
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// list returns all trash entries, newest first
+func (t *trash) list() ([]*TrashEntry, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.entries()
+}
+
+// entries reads the metadata of every entry; the caller must hold t.mu
+func (t *trash) entries() ([]*TrashEntry, error) {
+	dirEntries, err := os.ReadDir(t.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*TrashEntry, 0, len(dirEntries))
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() {
+			continue
+		}
+		entry, err := readTrashMeta(filepath.Join(t.dir, dirEntry.Name()))
+		if err != nil {
+			// Skip entries with missing or corrupt metadata
+			continue
+		}
+		result = append(result, entry)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].DeletedAt.After(result[j].DeletedAt)
+	})
+	return result, nil
+}
+
+// get returns the entry with the given ID
+func (t *trash) get(id string) (*TrashEntry, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entryDir, err := t.entryDir(id)
+	if err != nil {
+		return nil, err
+	}
+	return readTrashMeta(entryDir)
+}
+
+// restore moves the entry with the given ID back to dest and removes it from the trash
+func (t *trash) restore(id, dest string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entryDir, err := t.entryDir(id)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Lstat(dest); err == nil {
+		return fmt.Errorf("destination already exists: %s", dest)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	if err := movePath(filepath.Join(entryDir, trashItemName), dest); err != nil {
+		return err
+	}
+
+	return os.RemoveAll(entryDir)
+}
+
+// remove permanently deletes the entry with the given ID
+func (t *trash) remove(id string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entryDir, err := t.entryDir(id)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(entryDir)
+}
+
+// purge permanently deletes entries deleted before cutoff (all entries if
+// cutoff is zero) and returns how many were removed
+func (t *trash) purge(cutoff time.Time) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entries, err := t.entries()
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		if !cutoff.IsZero() && !entry.DeletedAt.Before(cutoff) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(t.dir, entry.ID)); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// purgeExpired removes entries older than the configured retention
+func (t *trash) purgeExpired() (int, error) {
+	if t.retention <= 0 {
+		return 0, nil
+	}
+	return t.purge(time.Now().Add(-t.retention))
+}
+
+// entryDir returns the directory of the entry with the given ID
+func (t *trash) entryDir(id string) (string, error) {
+	// IDs are generated by newTrashID; reject anything that could escape the trash
+	if id == "" || id != filepath.Base(id) || strings.HasPrefix(id, ".") {
+		return "", fmt.Errorf("invalid trash id: %s", id)
+	}
+
+	entryDir := filepath.Join(t.dir, id)
+	if _, err := os.Stat(filepath.Join(entryDir, trashMetaFile)); err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("trash entry not found: %s", id)
+		}
+		return "", err
+	}
+	return entryDir, nil
+}
+
+// newTrashID generates a sortable, unique identifier for a trash entry
+func newTrashID() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return time.Now().UTC().Format("20060102T150405") + "-" + hex.EncodeToString(b), nil
+}
+
+func writeTrashMeta(entryDir string, entry *TrashEntry) error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(entryDir, trashMetaFile), data, 0600)
+}
+
+func readTrashMeta(entryDir string) (*TrashEntry, error) {
+	data, err := os.ReadFile(filepath.Join(entryDir, trashMetaFile))
+	if err != nil {
+		return nil, err
+	}
+	var entry TrashEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// movePath renames src to dst, falling back to copy and delete when they are
+// on different filesystems
+func movePath(src, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil {
+		return nil
+	}
+
+	// Windows reports ERROR_NOT_SAME_DEVICE instead of EXDEV
+	crossDevice := errors.Is(err, syscall.EXDEV) ||
+		(runtime.GOOS == "windows" && errors.Is(err, syscall.Errno(17)))
+	if !crossDevice {
+		return err
+	}
+
+	info, statErr := os.Lstat(src)
+	if statErr != nil {
+		return err
+	}
+	if info.IsDir() {
+		err = copyDir(OSBackend{}, src, dst)
+	} else {
+		err = copyFile(OSBackend{}, src, dst)
+	}
+	if err != nil {
+		os.RemoveAll(dst)
+		return err
+	}
+	return os.RemoveAll(src)
+}
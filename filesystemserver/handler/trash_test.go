@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrash(t *testing.T) {
+	// Setup a temporary directory for the test and a separate trash directory
+	tmpDir := t.TempDir()
+	trashDir := t.TempDir()
+
+	allowedDirs := resolveAllowedDirs(t, tmpDir)
+	fsHandler, err := NewFilesystemHandler(allowedDirs)
+	require.NoError(t, err)
+	require.NoError(t, fsHandler.EnableTrash(trashDir, 0))
+
+	ctx := context.Background()
+
+	deleteToTrash := func(t *testing.T, path string, recursive bool) string {
+		t.Helper()
+		req := mcp.CallToolRequest{
+			Params: mcp.CallToolParams{
+				Arguments: map[string]interface{}{
+					"path":      path,
+					"recursive": recursive,
+				},
+			},
+		}
+		res, err := fsHandler.HandleDeleteFile(ctx, req)
+		require.NoError(t, err)
+		require.False(t, res.IsError)
+
+		entries, err := fsHandler.trash.list()
+		require.NoError(t, err)
+		require.NotEmpty(t, entries)
+		return entries[0].ID
+	}
+
+	t.Run("delete moves a file to the trash and restore brings it back", func(t *testing.T) {
+		filePath := filepath.Join(tmpDir, "trashed.txt")
+		require.NoError(t, os.WriteFile(filePath, []byte("keep me"), 0644))
+
+		id := deleteToTrash(t, filePath, false)
+
+		_, err := os.Stat(filePath)
+		assert.True(t, os.IsNotExist(err))
+
+		req := mcp.CallToolRequest{
+			Params: mcp.CallToolParams{
+				Arguments: map[string]interface{}{
+					"id": id,
+				},
+			},
+		}
+		res, err := fsHandler.HandleRestoreFromTrash(ctx, req)
+		require.NoError(t, err)
+		require.False(t, res.IsError)
+
+		content, err := os.ReadFile(filePath)
+		require.NoError(t, err)
+		assert.Equal(t, "keep me", string(content))
+
+		// The entry is gone once restored
+		_, err = fsHandler.trash.get(id)
+		assert.Error(t, err)
+	})
+
+	t.Run("directories are restored with their contents", func(t *testing.T) {
+		dirPath := filepath.Join(tmpDir, "trashed_dir")
+		require.NoError(t, os.MkdirAll(filepath.Join(dirPath, "sub"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dirPath, "sub", "file.txt"), []byte("nested"), 0644))
+
+		id := deleteToTrash(t, dirPath, true)
+
+		restored := filepath.Join(tmpDir, "restored_dir")
+		req := mcp.CallToolRequest{
+			Params: mcp.CallToolParams{
+				Arguments: map[string]interface{}{
+					"id":          id,
+					"destination": restored,
+				},
+			},
+		}
+		res, err := fsHandler.HandleRestoreFromTrash(ctx, req)
+		require.NoError(t, err)
+		require.False(t, res.IsError)
+
+		content, err := os.ReadFile(filepath.Join(restored, "sub", "file.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "nested", string(content))
+	})
+
+	t.Run("restore refuses to overwrite an existing path", func(t *testing.T) {
+		filePath := filepath.Join(tmpDir, "conflict.txt")
+		require.NoError(t, os.WriteFile(filePath, []byte("old"), 0644))
+
+		id := deleteToTrash(t, filePath, false)
+		require.NoError(t, os.WriteFile(filePath, []byte("new"), 0644))
+
+		req := mcp.CallToolRequest{
+			Params: mcp.CallToolParams{
+				Arguments: map[string]interface{}{
+					"id": id,
+				},
+			},
+		}
+		res, err := fsHandler.HandleRestoreFromTrash(ctx, req)
+		require.NoError(t, err)
+		require.True(t, res.IsError)
+
+		content, err := os.ReadFile(filePath)
+		require.NoError(t, err)
+		assert.Equal(t, "new", string(content))
+	})
+
+	t.Run("restore rejects destinations outside allowed directories", func(t *testing.T) {
+		filePath := filepath.Join(tmpDir, "escape.txt")
+		require.NoError(t, os.WriteFile(filePath, []byte("data"), 0644))
+
+		id := deleteToTrash(t, filePath, false)
+
+		req := mcp.CallToolRequest{
+			Params: mcp.CallToolParams{
+				Arguments: map[string]interface{}{
+					"id":          id,
+					"destination": filepath.Join(t.TempDir(), "escape.txt"),
+				},
+			},
+		}
+		res, err := fsHandler.HandleRestoreFromTrash(ctx, req)
+		require.NoError(t, err)
+		require.True(t, res.IsError)
+	})
+
+	t.Run("restore rejects ids that escape the trash", func(t *testing.T) {
+		req := mcp.CallToolRequest{
+			Params: mcp.CallToolParams{
+				Arguments: map[string]interface{}{
+					"id": "../outside",
+				},
+			},
+		}
+		res, err := fsHandler.HandleRestoreFromTrash(ctx, req)
+		require.NoError(t, err)
+		require.True(t, res.IsError)
+	})
+
+	t.Run("list and empty trash", func(t *testing.T) {
+		filePath := filepath.Join(tmpDir, "listed.txt")
+		require.NoError(t, os.WriteFile(filePath, []byte("data"), 0644))
+		deleteToTrash(t, filePath, false)
+
+		res, err := fsHandler.HandleListTrash(ctx, mcp.CallToolRequest{})
+		require.NoError(t, err)
+		require.False(t, res.IsError)
+		assert.Contains(t, res.Content[0].(mcp.TextContent).Text, filePath)
+
+		res, err = fsHandler.HandleEmptyTrash(ctx, mcp.CallToolRequest{})
+		require.NoError(t, err)
+		require.False(t, res.IsError)
+
+		entries, err := fsHandler.trash.list()
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+}
+
+func TestTrashRetention(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	allowedDirs := resolveAllowedDirs(t, tmpDir)
+	fsHandler, err := NewFilesystemHandler(allowedDirs)
+	require.NoError(t, err)
+	require.NoError(t, fsHandler.EnableTrash(t.TempDir(), time.Hour))
+
+	filePath := filepath.Join(tmpDir, "old.txt")
+	require.NoError(t, os.WriteFile(filePath, []byte("data"), 0644))
+
+	entry, err := fsHandler.trash.put(filePath)
+	require.NoError(t, err)
+
+	// Backdate the entry past the retention period
+	entry.DeletedAt = time.Now().Add(-2 * time.Hour)
+	require.NoError(t, writeTrashMeta(filepath.Join(fsHandler.trash.dir, entry.ID), entry))
+
+	removed, err := fsHandler.trash.purgeExpired()
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+}
+
+func TestTrashDisabled(t *testing.T) {
+	fsHandler, err := NewFilesystemHandler(resolveAllowedDirs(t, t.TempDir()))
+	require.NoError(t, err)
+
+	res, err := fsHandler.HandleListTrash(context.Background(), mcp.CallToolRequest{})
+	require.NoError(t, err)
+	assert.True(t, res.IsError)
+}
+
+func TestMovePathKeepsFailedRenames(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+	require.NoError(t, os.MkdirAll(src, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "a.txt"), []byte("a"), 0644))
+	require.NoError(t, os.MkdirAll(dst, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dst, "b.txt"), []byte("b"), 0644))
+
+	// Renaming onto a non-empty directory fails without copying or deleting
+	assert.Error(t, movePath(src, dst))
+	_, err := os.Stat(filepath.Join(src, "a.txt"))
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(dst, "b.txt"))
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(dst, "a.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	err = movePath(filepath.Join(dir, "missing"), filepath.Join(dir, "moved"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -49,6 +49,23 @@ func (fs *FilesystemHandler) HandleTree(
 		followSymlinks = followParam
 	}
 
+	// Paths like bundle.zip!/dir point into an archive
+	if archivePath, name, ok := splitArchivePath(path); ok {
+		tree, err := fs.archiveTree(archivePath, name, depth)
+		if err != nil {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					mcp.TextContent{
+						Type: "text",
+						Text: fmt.Sprintf("Error: %v", err),
+					},
+				},
+				IsError: true,
+			}, nil
+		}
+		return treeResult(tree, tree.Path, depth)
+	}
+
 	// Validate the path is within allowed directories
 	validPath, err := fs.validatePath(path)
 	if err != nil {
@@ -64,7 +81,7 @@ func (fs *FilesystemHandler) HandleTree(
 	}
 
 	// Check if it's a directory
-	info, err := os.Stat(validPath)
+	info, err := fs.backend.Stat(validPath)
 	if err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
@@ -103,6 +120,11 @@ func (fs *FilesystemHandler) HandleTree(
 		}, nil
 	}
 
+	return treeResult(tree, validPath, depth)
+}
+
+// treeResult renders the tree rooted at dir as JSON
+func treeResult(tree *FileNode, dir string, depth int) (*mcp.CallToolResult, error) {
 	// Convert to JSON
 	jsonData, err := json.MarshalIndent(tree, "", "  ")
 	if err != nil {
@@ -118,14 +140,14 @@ func (fs *FilesystemHandler) HandleTree(
 	}
 
 	// Create resource URI for the directory
-	resourceURI := pathToResourceURI(validPath)
+	resourceURI := pathToResourceURI(dir)
 
 	// Return the result
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{
 			mcp.TextContent{
 				Type: "text",
-				Text: fmt.Sprintf("Directory tree for %s (max depth: %d):\n\n%s", validPath, depth, string(jsonData)),
+				Text: fmt.Sprintf("Directory tree for %s (max depth: %d):\n\n%s", dir, depth, string(jsonData)),
 			},
 			mcp.EmbeddedResource{
 				Type: "resource",
@@ -148,7 +170,7 @@ func (fs *FilesystemHandler) buildTree(path string, maxDepth int, currentDepth i
 	}
 
 	// Get file info
-	info, err := os.Stat(validPath)
+	info, err := fs.backend.Stat(validPath)
 	if err != nil {
 		return nil, err
 	}
@@ -167,7 +189,7 @@ func (fs *FilesystemHandler) buildTree(path string, maxDepth int, currentDepth i
 		// If we haven't reached the max depth, process children
 		if currentDepth < maxDepth {
 			// Read directory entries
-			entries, err := os.ReadDir(validPath)
+			entries, err := fs.backend.ReadDir(validPath)
 			if err != nil {
 				return nil, err
 			}
@@ -184,7 +206,7 @@ func (fs *FilesystemHandler) buildTree(path string, maxDepth int, currentDepth i
 					}
 
 					// Resolve symlink
-					linkDest, err := filepath.EvalSymlinks(entryPath)
+					linkDest, err := evalSymlinks(fs.backend, entryPath)
 					if err != nil {
 						// Skip invalid symlinks
 						continue
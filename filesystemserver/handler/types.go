@@ -11,8 +11,52 @@ const (
 	MAX_SEARCH_RESULTS = 1000
 	// Maximum file size in bytes to search within (10MB)
 	MAX_SEARCHABLE_SIZE = 10 * 1024 * 1024
+	// Default time deleted items are kept in the trash (7 days)
+	DEFAULT_TRASH_RETENTION = 7 * 24 * time.Hour
+	// Default number of operations kept in the undo journal
+	DEFAULT_JOURNAL_ENTRIES = 100
+	// Maximum number of operations in a single batch
+	MAX_BATCH_OPERATIONS = 100
+	// Maximum total size in bytes of the files extract_archive writes (1GB)
+	MAX_EXTRACT_SIZE = 1024 * 1024 * 1024
+	// Maximum number of entries in an archive extract_archive accepts
+	MAX_EXTRACT_ENTRIES = 10000
+	// Number of files listed per page of resources/list
+	RESOURCE_PAGE_SIZE = 100
 )
 
+// Limits bounds the size of content and results handled by the tools.
+// The constants above are the defaults.
+type Limits struct {
+	// MaxInlineSize is the largest file whose content is returned inline
+	MaxInlineSize int64 `json:"max_inline_size,omitempty"`
+	// MaxBase64Size is the largest binary file returned base64 encoded
+	MaxBase64Size int64 `json:"max_base64_size,omitempty"`
+	// MaxSearchResults is the default number of search_within_files matches
+	MaxSearchResults int `json:"max_search_results,omitempty"`
+	// MaxSearchableSize is the largest file search_within_files looks into
+	MaxSearchableSize int64 `json:"max_searchable_size,omitempty"`
+	// MaxBatchOperations is the largest number of operations in a batch
+	MaxBatchOperations int `json:"max_batch_operations,omitempty"`
+	// MaxExtractSize is the largest total size extract_archive unpacks
+	MaxExtractSize int64 `json:"max_extract_size,omitempty"`
+	// MaxExtractEntries is the largest number of entries extract_archive unpacks
+	MaxExtractEntries int `json:"max_extract_entries,omitempty"`
+}
+
+// DefaultLimits returns the limits used unless configured otherwise
+func DefaultLimits() Limits {
+	return Limits{
+		MaxInlineSize:      MAX_INLINE_SIZE,
+		MaxBase64Size:      MAX_BASE64_SIZE,
+		MaxSearchResults:   MAX_SEARCH_RESULTS,
+		MaxSearchableSize:  MAX_SEARCHABLE_SIZE,
+		MaxBatchOperations: MAX_BATCH_OPERATIONS,
+		MaxExtractSize:     MAX_EXTRACT_SIZE,
+		MaxExtractEntries:  MAX_EXTRACT_ENTRIES,
+	}
+}
+
 type FileInfo struct {
 	Size        int64     `json:"size"`
 	Created     time.Time `json:"created"`
@@ -40,3 +84,44 @@ type SearchResult struct {
 	LineContent string
 	ResourceURI string
 }
+
+// TrashEntry describes an item that was moved to the trash by delete_file
+type TrashEntry struct {
+	ID           string    `json:"id"`
+	OriginalPath string    `json:"originalPath"`
+	DeletedAt    time.Time `json:"deletedAt"`
+	IsDirectory  bool      `json:"isDirectory"`
+	Size         int64     `json:"size"`
+}
+
+// JournalEntry describes a mutating operation recorded in the undo journal
+type JournalEntry struct {
+	ID      int       `json:"id"`
+	Tool    string    `json:"tool"`
+	Summary string    `json:"summary"`
+	Time    time.Time `json:"time"`
+}
+
+// Checkpoint describes a named snapshot of a directory tree
+type Checkpoint struct {
+	Name    string    `json:"name"`
+	Root    string    `json:"root"`
+	Created time.Time `json:"created"`
+	Files   int       `json:"files"`
+	Size    int64     `json:"size"`
+}
+
+// AllowedDirectory describes a directory the handler gives access to
+type AllowedDirectory struct {
+	Path   string `json:"path"`
+	Access string `json:"access"`
+	Origin string `json:"origin"`
+}
+
+// OverlayChange is a difference between a copy-on-write overlay and the
+// layer below it
+type OverlayChange struct {
+	Path   string `json:"path"`
+	Status string `json:"status"` // "added", "removed" or "modified"
+	Type   string `json:"type"`   // "file", "directory" or "symlink"
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func (fs *FilesystemHandler) HandleUndoHistory(
+	ctx context.Context,
+	request mcp.CallToolRequest,
+) (*mcp.CallToolResult, error) {
+	if fs.journal == nil {
+		return journalDisabledResult(), nil
+	}
+
+	entries, err := fs.journal.list()
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error reading undo journal: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	if len(entries) == 0 {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: "Undo journal is empty",
+				},
+			},
+		}, nil
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Undo journal contains %d operation(s), newest first:\n\n", len(entries)))
+	for _, entry := range entries {
+		result.WriteString(fmt.Sprintf("#%d %s: %s (%s)\n",
+			entry.ID, entry.Tool, entry.Summary, entry.Time.Format(time.RFC3339)))
+	}
+	result.WriteString("\nUse undo_to with a checkpoint number to undo every operation after it.")
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: result.String(),
+			},
+		},
+	}, nil
+}
+
+// journalDisabledResult is returned by the undo tools when the journal is off
+func journalDisabledResult() *mcp.CallToolResult {
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: "Error: Undo journal is not enabled on this server",
+			},
+		},
+		IsError: true,
+	}
+}
+
+// undoResult reports which journal entries were undone and any error that
+// stopped the rollback
+func undoResult(undone []*journalEntry, err error) *mcp.CallToolResult {
+	var result strings.Builder
+	for _, entry := range undone {
+		result.WriteString(fmt.Sprintf("Undid #%d %s: %s\n", entry.ID, entry.Tool, entry.Summary))
+	}
+
+	if err != nil {
+		result.WriteString(fmt.Sprintf("Error: %v", err))
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: result.String(),
+				},
+			},
+			IsError: true,
+		}
+	}
+
+	if len(undone) == 0 {
+		result.WriteString("Nothing to undo")
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: result.String(),
+			},
+		},
+	}
+}
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func (fs *FilesystemHandler) HandleUndoLast(
+	ctx context.Context,
+	request mcp.CallToolRequest,
+) (*mcp.CallToolResult, error) {
+	if fs.journal == nil {
+		return journalDisabledResult(), nil
+	}
+
+	// Extract count parameter (optional, default: 1)
+	count := 1
+	if countParam, err := request.RequireFloat("count"); err == nil {
+		count = int(countParam)
+		if count <= 0 {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					mcp.TextContent{
+						Type: "text",
+						Text: "Error: count must be positive",
+					},
+				},
+				IsError: true,
+			}, nil
+		}
+	}
+
+	undone := 0
+	entries, err := fs.journal.undo(fs, func(*journalEntry) bool {
+		undone++
+		return undone > count
+	})
+	return undoResult(entries, err), nil
+}
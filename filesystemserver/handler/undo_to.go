@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func (fs *FilesystemHandler) HandleUndoTo(
+	ctx context.Context,
+	request mcp.CallToolRequest,
+) (*mcp.CallToolResult, error) {
+	checkpointParam, err := request.RequireFloat("checkpoint")
+	if err != nil {
+		return nil, err
+	}
+
+	if fs.journal == nil {
+		return journalDisabledResult(), nil
+	}
+
+	// Undo every operation recorded after the checkpoint
+	checkpoint := int(checkpointParam)
+	entries, err := fs.journal.undo(fs, func(entry *journalEntry) bool {
+		return entry.ID <= checkpoint
+	})
+	return undoResult(entries, err), nil
+}
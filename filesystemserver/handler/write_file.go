@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/mark3labs/mcp-go/mcp"
 )
@@ -54,7 +55,7 @@ func (fs *FilesystemHandler) HandleWriteFile(
 	}
 
 	// Check if it's a directory
-	if info, err := os.Stat(validPath); err == nil && info.IsDir() {
+	if info, err := fs.backend.Stat(validPath); err == nil && info.IsDir() {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				mcp.TextContent{
@@ -66,9 +67,52 @@ func (fs *FilesystemHandler) HandleWriteFile(
 		}, nil
 	}
 
+	// Existing files keep their encoding, BOM and line endings unless the
+	// request sets them. The content decides whether the file ends with a
+	// line break.
+	current := textEncoding{name: EncodingUTF8}
+	var endings *lineEndings
+	if existing, err := readFile(fs.backend, validPath); err == nil {
+		if enc, ok := detectEncoding(existing); ok {
+			current = enc
+			if text, err := decodeText(existing, enc); err == nil {
+				if e, ok := detectLineEndings(text); ok {
+					e.finalNewline = strings.HasSuffix(content, "\n")
+					endings = &e
+				}
+			}
+		}
+	}
+	enc, err := requestEncoding(request, current)
+	var data []byte
+	if err == nil {
+		content, err = applyLineEndings(request, content, endings)
+	}
+	if err == nil {
+		data, err = encodeText(content, enc)
+	}
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error: %v", err),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	// Record the previous content so the write can be undone
+	entry, err := fs.journalSnapshot(ctx, "write_file", fmt.Sprintf("write %s", validPath), validPath)
+	if err != nil {
+		return journalErrorResult(err), nil
+	}
+
 	// Create parent directories if they don't exist
 	parentDir := filepath.Dir(validPath)
-	if err := os.MkdirAll(parentDir, 0755); err != nil {
+	if err := mkdirAll(fs.backend, parentDir, 0755); err != nil {
+		entry.discard()
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				mcp.TextContent{
@@ -80,7 +124,8 @@ func (fs *FilesystemHandler) HandleWriteFile(
 		}, nil
 	}
 
-	if err := os.WriteFile(validPath, []byte(content), 0644); err != nil {
+	if err := writeFile(fs.backend, validPath, data, 0644); err != nil {
+		entry.discard()
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				mcp.TextContent{
@@ -92,8 +137,10 @@ func (fs *FilesystemHandler) HandleWriteFile(
 		}, nil
 	}
 
+	fs.journalCommit(entry)
+
 	// Get file info for the response
-	info, err := os.Stat(validPath)
+	info, err := fs.backend.Stat(validPath)
 	if err != nil {
 		// File was written but we couldn't get info
 		return &mcp.CallToolResult{
@@ -106,12 +153,16 @@ func (fs *FilesystemHandler) HandleWriteFile(
 		}, nil
 	}
 
+	written := fmt.Sprintf("Successfully wrote %d bytes to %s", info.Size(), path)
+	if !enc.plain() {
+		written += fmt.Sprintf(" (%s)", enc)
+	}
 	resourceURI := pathToResourceURI(validPath)
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{
 			mcp.TextContent{
 				Type: "text",
-				Text: fmt.Sprintf("Successfully wrote %d bytes to %s", info.Size(), path),
+				Text: written,
 			},
 			mcp.EmbeddedResource{
 				Type: "resource",
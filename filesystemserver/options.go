@@ -0,0 +1,190 @@
+package filesystemserver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/mark3labs/mcp-filesystem-server/filesystemserver/handler"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// DefaultServerName is the name the server reports to clients
+const DefaultServerName = "secure-filesystem-server"
+
+// Option configures a server created by NewFilesystemServer.
+type Option func(*options)
+
+type options struct {
+	config   Config
+	name     string
+	enabled  []string
+	disabled []string
+	logger   *slog.Logger
+	policy   Policy
+	backend  handler.Backend
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{name: DefaultServerName, backend: handler.OSBackend{}}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithConfig enables the optional features described by cfg.
+func WithConfig(cfg Config) Option {
+	return func(o *options) {
+		o.config = cfg
+	}
+}
+
+// WithLimits overrides the default size and result limits. Zero fields keep
+// their defaults.
+func WithLimits(limits handler.Limits) Option {
+	return func(o *options) {
+		o.config.Limits = limits
+	}
+}
+
+// WithReadOnly registers only the tools that do not change files. Like
+// WithTools and WithoutTools, it also applies to Tools of a NewHandler.
+func WithReadOnly() Option {
+	return func(o *options) {
+		o.config.ReadOnly = true
+	}
+}
+
+// WithTools registers only the named tools.
+func WithTools(names ...string) Option {
+	return func(o *options) {
+		o.enabled = append(o.enabled, names...)
+	}
+}
+
+// WithoutTools leaves the named tools out.
+func WithoutTools(names ...string) Option {
+	return func(o *options) {
+		o.disabled = append(o.disabled, names...)
+	}
+}
+
+// WithBackend serves the allowed directories from backend instead of the
+// local disk. The trash, undo journal and checkpoints need the local disk.
+func WithBackend(backend handler.Backend) Option {
+	return func(o *options) {
+		o.backend = backend
+	}
+}
+
+// WithLogger logs every tool call at debug level and failed or rejected
+// calls at warn level.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithPolicy checks every tool call against policy before it runs.
+func WithPolicy(policy Policy) Option {
+	return func(o *options) {
+		o.policy = policy
+	}
+}
+
+// WithServerName changes the name the server reports to clients.
+func WithServerName(name string) Option {
+	return func(o *options) {
+		o.name = name
+	}
+}
+
+// Policy decides whether a tool call may run. An error rejects the call and
+// is reported to the client. The operations of a batch are checked as a
+// single call to the batch tool.
+type Policy interface {
+	Allow(ctx context.Context, tool string, arguments map[string]any) error
+}
+
+// PolicyFunc adapts a function to the Policy interface.
+type PolicyFunc func(ctx context.Context, tool string, arguments map[string]any) error
+
+// Allow calls f.
+func (f PolicyFunc) Allow(ctx context.Context, tool string, arguments map[string]any) error {
+	return f(ctx, tool, arguments)
+}
+
+// checkToolNames returns an error if the enabled or disabled options name a
+// tool that is not among tools
+func (o *options) checkToolNames(tools []server.ServerTool) error {
+	known := make(map[string]bool, len(tools))
+	for _, tool := range tools {
+		known[tool.Tool.Name] = true
+	}
+	for _, name := range append(slices.Clone(o.enabled), o.disabled...) {
+		if !known[name] {
+			return fmt.Errorf("unknown tool %q", name)
+		}
+	}
+	return nil
+}
+
+// selectTools applies the tool selection and read-only mode of h to tools
+func selectTools(h *handler.FilesystemHandler, tools []server.ServerTool) []server.ServerTool {
+	enabled, disabled := h.ToolSelection()
+	var selected []server.ServerTool
+	for _, tool := range tools {
+		name := tool.Tool.Name
+		switch {
+		case len(enabled) > 0 && !slices.Contains(enabled, name),
+			slices.Contains(disabled, name),
+			h.ReadOnly() && slices.Contains(mutatingTools, name):
+			continue
+		}
+		selected = append(selected, tool)
+	}
+	return selected
+}
+
+// middleware applies the policy and logger to a tool handler
+func (o *options) middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		tool := request.Params.Name
+
+		if o.policy != nil {
+			if err := o.policy.Allow(ctx, tool, request.GetArguments()); err != nil {
+				if o.logger != nil {
+					o.logger.Warn("Tool call rejected by policy", "tool", tool, "error", err)
+				}
+				return &mcp.CallToolResult{
+					Content: []mcp.Content{
+						mcp.TextContent{
+							Type: "text",
+							Text: fmt.Sprintf("Error: %v", err),
+						},
+					},
+					IsError: true,
+				}, nil
+			}
+		}
+
+		start := time.Now()
+		result, err := next(ctx, request)
+		if o.logger != nil {
+			elapsed := time.Since(start)
+			switch {
+			case err != nil:
+				o.logger.Warn("Tool call failed", "tool", tool, "duration", elapsed, "error", err)
+			case result != nil && result.IsError:
+				o.logger.Warn("Tool call returned an error", "tool", tool, "duration", elapsed)
+			default:
+				o.logger.Debug("Tool call", "tool", tool, "duration", elapsed)
+			}
+		}
+		return result, err
+	}
+}
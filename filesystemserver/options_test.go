@@ -0,0 +1,171 @@
+package filesystemserver_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-filesystem-server/filesystemserver"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// toolNames lists the names of the tools the server registers
+func toolNames(t *testing.T, mcpClient client.MCPClient) []string {
+	t.Helper()
+	result, err := mcpClient.ListTools(context.Background(), mcp.ListToolsRequest{})
+	require.NoError(t, err)
+	var names []string
+	for _, tool := range result.Tools {
+		names = append(names, tool.Name)
+	}
+	return names
+}
+
+func TestServerOptions(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("read only", func(t *testing.T) {
+		fss, err := filesystemserver.NewFilesystemServer([]string{dir}, filesystemserver.WithReadOnly())
+		require.NoError(t, err)
+		names := toolNames(t, startTestClient(t, fss))
+		assert.Contains(t, names, "read_file")
+		assert.NotContains(t, names, "write_file")
+		assert.NotContains(t, names, "batch")
+	})
+
+	t.Run("enabled and disabled tools", func(t *testing.T) {
+		fss, err := filesystemserver.NewFilesystemServer([]string{dir},
+			filesystemserver.WithTools("read_file", "list_directory", "tree"),
+			filesystemserver.WithoutTools("tree"),
+		)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"read_file", "list_directory"}, toolNames(t, startTestClient(t, fss)))
+
+		_, err = filesystemserver.NewFilesystemServer([]string{dir}, filesystemserver.WithoutTools("rm_rf"))
+		assert.Error(t, err)
+	})
+
+	t.Run("directory admin", func(t *testing.T) {
+		fss, err := filesystemserver.NewFilesystemServer([]string{dir})
+		require.NoError(t, err)
+		assert.NotContains(t, toolNames(t, startTestClient(t, fss)), "add_allowed_directory")
+
+		fss, err = filesystemserver.NewFilesystemServer([]string{dir},
+			filesystemserver.WithConfig(filesystemserver.Config{DirectoryAdmin: true}))
+		require.NoError(t, err)
+		names := toolNames(t, startTestClient(t, fss))
+		assert.Contains(t, names, "add_allowed_directory")
+		assert.Contains(t, names, "remove_allowed_directory")
+	})
+
+	t.Run("server name", func(t *testing.T) {
+		fss, err := filesystemserver.NewFilesystemServer([]string{dir}, filesystemserver.WithServerName("team-fs"))
+		require.NoError(t, err)
+
+		mcpClient, err := client.NewInProcessClient(fss)
+		require.NoError(t, err)
+		defer mcpClient.Close()
+		initRequest := mcp.InitializeRequest{}
+		initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+		result, err := mcpClient.Initialize(context.Background(), initRequest)
+		require.NoError(t, err)
+		assert.Equal(t, "team-fs", result.ServerInfo.Name)
+	})
+
+	t.Run("policy and logger", func(t *testing.T) {
+		var logs bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		policy := filesystemserver.PolicyFunc(func(_ context.Context, tool string, arguments map[string]any) error {
+			if path, _ := arguments["path"].(string); filepath.Ext(path) == ".env" {
+				return errors.New("access to .env files is not allowed")
+			}
+			return nil
+		})
+
+		fss, err := filesystemserver.NewFilesystemServer([]string{dir},
+			filesystemserver.WithPolicy(policy),
+			filesystemserver.WithLogger(logger),
+		)
+		require.NoError(t, err)
+		mcpClient := startTestClient(t, fss)
+
+		call := func(path string) *mcp.CallToolResult {
+			request := mcp.CallToolRequest{}
+			request.Params.Name = "write_file"
+			request.Params.Arguments = map[string]any{"path": path, "content": "x"}
+			result, err := mcpClient.CallTool(context.Background(), request)
+			require.NoError(t, err)
+			return result
+		}
+
+		result := call(filepath.Join(dir, ".env"))
+		assert.True(t, result.IsError)
+		assert.Contains(t, result.Content[0].(mcp.TextContent).Text, "not allowed")
+		_, err = os.Stat(filepath.Join(dir, ".env"))
+		assert.True(t, os.IsNotExist(err))
+
+		result = call(filepath.Join(dir, "notes.txt"))
+		assert.False(t, result.IsError)
+
+		assert.Contains(t, logs.String(), "Tool call rejected by policy")
+		assert.Contains(t, logs.String(), "tool=write_file")
+	})
+}
+
+func TestRegisterToolSubset(t *testing.T) {
+	dir := t.TempDir()
+	h, err := filesystemserver.NewHandler([]string{dir})
+	require.NoError(t, err)
+
+	// A team's own server exposing only part of the filesystem tools
+	s := server.NewMCPServer("team-server", "1.0.0")
+	s.AddTools(filesystemserver.Tools(h, "read_file", "list_directory")...)
+	s.AddResourceTemplates(filesystemserver.ResourceTemplates(h)...)
+
+	mcpClient, err := client.NewInProcessClient(s)
+	require.NoError(t, err)
+	defer mcpClient.Close()
+	initRequest := mcp.InitializeRequest{}
+	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	_, err = mcpClient.Initialize(context.Background(), initRequest)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"read_file", "list_directory"}, toolNames(t, mcpClient))
+}
+
+func TestToolsApplyHandlerOptions(t *testing.T) {
+	dir := t.TempDir()
+	names := func(tools []server.ServerTool) []string {
+		var names []string
+		for _, tool := range tools {
+			names = append(names, tool.Tool.Name)
+		}
+		return names
+	}
+
+	h, err := filesystemserver.NewHandler([]string{dir}, filesystemserver.WithReadOnly())
+	require.NoError(t, err)
+	tools := names(filesystemserver.Tools(h))
+	assert.Contains(t, tools, "read_file")
+	for _, name := range []string{"write_file", "delete_file", "move_file", "batch"} {
+		assert.NotContains(t, tools, name)
+	}
+	assert.Empty(t, filesystemserver.Tools(h, "write_file"))
+
+	h, err = filesystemserver.NewHandler([]string{dir},
+		filesystemserver.WithTools("read_file", "write_file", "list_directory"),
+		filesystemserver.WithoutTools("write_file"))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"read_file", "list_directory"}, names(filesystemserver.Tools(h)))
+
+	_, err = filesystemserver.NewHandler([]string{dir}, filesystemserver.WithTools("no_such_tool"))
+	assert.ErrorContains(t, err, "unknown tool")
+}
@@ -0,0 +1,151 @@
+package filesystemserver
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/mark3labs/mcp-filesystem-server/filesystemserver/handler"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Client roots modes for Config.ClientRoots
+const (
+	// ClientRootsOff ignores the roots of the client
+	ClientRootsOff = "off"
+	// ClientRootsIntersect limits the allowed directories to the parts that
+	// are also inside a client root
+	ClientRootsIntersect = "intersect"
+	// ClientRootsReplace serves the client roots instead of the allowed
+	// directories. The roots apply to every session of the server, so it is
+	// meant for servers with a single client, such as on stdio.
+	ClientRootsReplace = "replace"
+)
+
+// rootsTimeout bounds how long the server waits for roots/list
+const rootsTimeout = 30 * time.Second
+
+// clientRoots updates the allowed directories of a handler from the roots of
+// the client. With several sessions the roots reported last apply.
+type clientRoots struct {
+	server  *server.MCPServer
+	handler *handler.FilesystemHandler
+	dirs    []string
+	mode    string
+	options *options
+}
+
+func validClientRootsMode(mode string) error {
+	switch mode {
+	case "", ClientRootsOff, ClientRootsIntersect, ClientRootsReplace:
+		return nil
+	}
+	return fmt.Errorf("invalid client roots mode %q (expected off, intersect or replace)", mode)
+}
+
+// register asks for the roots once the client is initialized and whenever
+// they change
+func (cr *clientRoots) register() {
+	cr.server.AddNotificationHandler("notifications/initialized", cr.handleNotification)
+	cr.server.AddNotificationHandler(mcp.MethodNotificationRootsListChanged, cr.handleNotification)
+}
+
+func (cr *clientRoots) handleNotification(ctx context.Context, _ mcp.JSONRPCNotification) {
+	session, ok := server.ClientSessionFromContext(ctx).(server.SessionWithClientInfo)
+	if !ok || session.GetClientCapabilities().Roots == nil {
+		return
+	}
+
+	// The stdio transport handles notifications on its read loop, so the
+	// roots/list response can only arrive once this handler has returned
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rootsTimeout)
+		defer cancel()
+		result, err := cr.server.RequestRoots(ctx, mcp.ListRootsRequest{})
+		if err != nil {
+			cr.warn("Failed to list client roots", "error", err)
+			return
+		}
+		if err := cr.apply(result.Roots); err != nil {
+			cr.warn("Failed to apply client roots", "error", err)
+		}
+	}()
+}
+
+// apply sets the allowed directories for roots
+func (cr *clientRoots) apply(roots []mcp.Root) error {
+	var dirs []string
+	for _, root := range roots {
+		dir, err := rootPath(root.URI)
+		if err != nil {
+			cr.warn("Ignoring client root", "uri", root.URI, "error", err)
+			continue
+		}
+		info, err := cr.handler.Backend().Stat(dir)
+		if err != nil || !info.IsDir() {
+			cr.warn("Ignoring client root that is not a directory", "uri", root.URI)
+			continue
+		}
+		dirs = append(dirs, dir)
+	}
+
+	if cr.mode == ClientRootsIntersect {
+		dirs = intersectDirs(dirs, cr.dirs, len(roots) == 0)
+	}
+	if err := cr.handler.SetAllowedDirectories(dirs, handler.DirOriginClient); err != nil {
+		return err
+	}
+	cr.server.SendNotificationToAllClients(mcp.MethodNotificationResourcesListChanged, nil)
+	if cr.options.logger != nil {
+		cr.options.logger.Info("Updated allowed directories from client roots", "directories", dirs)
+	}
+	return nil
+}
+
+func (cr *clientRoots) warn(msg string, args ...any) {
+	if cr.options.logger != nil {
+		cr.options.logger.Warn(msg, args...)
+	}
+}
+
+// intersectDirs returns the parts of roots that are inside dirs. Without
+// roots, when the client does not limit the server, dirs are returned.
+func intersectDirs(roots, dirs []string, unlimited bool) []string {
+	if unlimited {
+		return dirs
+	}
+	var result []string
+	seen := make(map[string]bool)
+	add := func(dir string) {
+		if !seen[dir] {
+			seen[dir] = true
+			result = append(result, dir)
+		}
+	}
+	for _, root := range roots {
+		if isWithinDirs(root, dirs) {
+			add(root)
+			continue
+		}
+		for _, dir := range dirs {
+			if isWithinDirs(dir, []string{root}) {
+				add(dir)
+			}
+		}
+	}
+	return result
+}
+
+// rootPath converts a file:// root URI to a local path
+func rootPath(uri string) (string, error) {
+	path, err := handler.ResourceURIToPath(uri)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("not an absolute path")
+	}
+	return filepath.Clean(path), nil
+}
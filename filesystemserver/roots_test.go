@@ -0,0 +1,160 @@
+package filesystemserver_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-filesystem-server/filesystemserver"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testRoots is a client roots handler whose roots can be changed
+type testRoots struct {
+	mu   sync.Mutex
+	dirs []string
+}
+
+func (r *testRoots) set(dirs ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.dirs = dirs
+}
+
+func (r *testRoots) ListRoots(ctx context.Context, request mcp.ListRootsRequest) (*mcp.ListRootsResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result := &mcp.ListRootsResult{}
+	for _, dir := range r.dirs {
+		path := filepath.ToSlash(dir)
+		if !strings.HasPrefix(path, "/") {
+			// Windows paths such as C:/src
+			path = "/" + path
+		}
+		result.Roots = append(result.Roots, mcp.Root{URI: "file://" + path, Name: filepath.Base(dir)})
+	}
+	return result, nil
+}
+
+// startRootsClient connects a client that advertises roots to fss. The
+// returned context carries the client session, which the in-process
+// transport does not add to notifications itself.
+func startRootsClient(t *testing.T, fss *server.MCPServer, roots *testRoots) (*client.Client, context.Context) {
+	t.Helper()
+
+	session := server.NewInProcessSessionWithHandlers(fss.GenerateInProcessSessionID(), nil, nil, roots)
+	require.NoError(t, fss.RegisterSession(context.Background(), session))
+	ctx := fss.WithContext(context.Background(), session)
+
+	mcpClient := client.NewClient(transport.NewInProcessTransport(fss), client.WithRootsHandler(roots))
+	t.Cleanup(func() {
+		mcpClient.Close()
+		fss.UnregisterSession(context.Background(), session.SessionID())
+	})
+	require.NoError(t, mcpClient.Start(ctx))
+
+	initRequest := mcp.InitializeRequest{}
+	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initRequest.Params.ClientInfo = mcp.Implementation{
+		Name:    "test-client",
+		Version: "1.0.0",
+	}
+	_, err := mcpClient.Initialize(ctx, initRequest)
+	require.NoError(t, err)
+	return mcpClient, ctx
+}
+
+// allowedDirectories lists the allowed directories through the tool
+func allowedDirectories(t *testing.T, mcpClient *client.Client, ctx context.Context) string {
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "list_allowed_directories"
+	result, err := mcpClient.CallTool(ctx, request)
+	require.NoError(t, err)
+	return result.Content[0].(mcp.TextContent).Text
+}
+
+func TestClientRoots(t *testing.T) {
+	base, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	project := filepath.Join(base, "project")
+	other := filepath.Join(base, "other")
+	for _, dir := range []string{project, other} {
+		require.NoError(t, os.Mkdir(dir, 0755))
+	}
+	outside, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+
+	waitFor := func(t *testing.T, mcpClient *client.Client, ctx context.Context, check func(dirs string) bool) {
+		assert.Eventually(t, func() bool {
+			return check(allowedDirectories(t, mcpClient, ctx))
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+
+	t.Run("intersect keeps client roots inside the allowed directories", func(t *testing.T) {
+		fss, err := filesystemserver.NewFilesystemServer([]string{base},
+			filesystemserver.WithConfig(filesystemserver.Config{ClientRoots: filesystemserver.ClientRootsIntersect}))
+		require.NoError(t, err)
+
+		roots := &testRoots{}
+		roots.set(project, outside)
+		mcpClient, ctx := startRootsClient(t, fss, roots)
+		waitFor(t, mcpClient, ctx, func(dirs string) bool {
+			return strings.Contains(dirs, project) && !strings.Contains(dirs, outside) && !strings.Contains(dirs, other)
+		})
+
+		// A root containing the allowed directory is limited to it
+		roots.set(filepath.Dir(base))
+		require.NoError(t, mcpClient.RootListChanges(ctx))
+		waitFor(t, mcpClient, ctx, func(dirs string) bool {
+			return strings.Contains(dirs, base) && !strings.Contains(dirs, project)
+		})
+	})
+
+	t.Run("replace serves the client roots", func(t *testing.T) {
+		fss, err := filesystemserver.NewFilesystemServer(nil,
+			filesystemserver.WithConfig(filesystemserver.Config{ClientRoots: filesystemserver.ClientRootsReplace}))
+		require.NoError(t, err)
+
+		roots := &testRoots{}
+		roots.set(outside)
+		mcpClient, ctx := startRootsClient(t, fss, roots)
+		waitFor(t, mcpClient, ctx, func(dirs string) bool {
+			return strings.Contains(dirs, outside)
+		})
+
+		roots.set(other, filepath.Join(base, "missing"))
+		require.NoError(t, mcpClient.RootListChanges(ctx))
+		waitFor(t, mcpClient, ctx, func(dirs string) bool {
+			return strings.Contains(dirs, other) && !strings.Contains(dirs, outside)
+		})
+	})
+
+	t.Run("off ignores the client roots", func(t *testing.T) {
+		fss, err := filesystemserver.NewFilesystemServer([]string{base})
+		require.NoError(t, err)
+
+		roots := &testRoots{}
+		roots.set(project)
+		mcpClient, ctx := startRootsClient(t, fss, roots)
+		require.NoError(t, mcpClient.RootListChanges(ctx))
+		time.Sleep(50 * time.Millisecond)
+		dirs := allowedDirectories(t, mcpClient, ctx)
+		assert.Contains(t, dirs, base)
+		assert.NotContains(t, dirs, project)
+	})
+
+	t.Run("invalid mode", func(t *testing.T) {
+		_, err := filesystemserver.NewFilesystemServer([]string{base},
+			filesystemserver.WithConfig(filesystemserver.Config{ClientRoots: "all"}))
+		assert.Error(t, err)
+	})
+}
@@ -1,6 +1,10 @@
 package filesystemserver
 
 import (
+	"fmt"
+	"slices"
+	"time"
+
 	"github.com/mark3labs/mcp-filesystem-server/filesystemserver/handler"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -8,38 +12,334 @@ import (
 
 var Version = "dev"
 
-func NewFilesystemServer(allowedDirs []string) (*server.MCPServer, error) {
+// Config holds optional features of the filesystem server.
+// The zero value serves the allowed directories with default behavior.
+type Config struct {
+	// TrashDir enables soft delete: delete_file moves items into this
+	// directory and the trash tools are registered.
+	TrashDir string
+	// TrashRetention is how long trashed items are kept before they are
+	// purged. Zero keeps them until empty_trash is called.
+	TrashRetention time.Duration
+	// JournalDir enables the undo journal: every mutating tool records how
+	// to reverse itself in this directory and the undo tools are registered.
+	// It must lie outside the allowed directories.
+	JournalDir string
+	// JournalEntries is the number of operations kept in the journal.
+	// Zero keeps all of them.
+	JournalEntries int
+	// CheckpointDir enables named workspace checkpoints stored in this
+	// directory and registers the checkpoint tools. It must lie outside the
+	// allowed directories.
+	CheckpointDir string
+	// OverlayDir enables copy-on-write mode: the allowed directories are not
+	// modified and all changes are kept in this directory until they are
+	// committed or discarded with the overlay tools. It cannot be combined
+	// with the trash, undo journal or checkpoints.
+	OverlayDir string
+	// ClientRoots updates the allowed directories from the roots of clients
+	// that support them: ClientRootsIntersect keeps the parts of the allowed
+	// directories inside a client root and ClientRootsReplace serves the
+	// client roots instead. The default, ClientRootsOff, ignores them. The
+	// allowed directories are shared by all sessions, so with several
+	// clients the roots reported last apply to all of them.
+	ClientRoots string
+	// DirectoryAdmin registers add_allowed_directory and
+	// remove_allowed_directory, which let clients change the allowed
+	// directories at runtime. Only enable it for trusted clients.
+	DirectoryAdmin bool
+	// Confirm asks the user of the client through an elicitation before
+	// dangerous tool calls: ConfirmDestructive before destructive calls that
+	// would overwrite or remove data, ConfirmAll before every change. Calls
+	// are aborted when the client cannot ask. The default, ConfirmOff, never
+	// asks.
+	Confirm string
+	// ReadOnly registers only the tools that do not change files.
+	ReadOnly bool
+	// Limits overrides the default size and result limits. Zero fields keep
+	// their defaults.
+	Limits handler.Limits
+}
+
+// mutatingTools are the tools that change files or the state of the trash,
+// journal and checkpoint store. They are not registered on read-only servers.
+var mutatingTools = []string{
+	"write_file",
+	"create_directory",
+	"copy_file",
+	"move_file",
+	"delete_file",
+	"modify_file",
+	"edit_lines",
+	"batch",
+	"create_archive",
+	"extract_archive",
+	"restore_from_trash",
+	"empty_trash",
+	"undo_last",
+	"undo_to",
+	"checkpoint_create",
+	"checkpoint_restore",
+	"overlay_commit",
+	"overlay_discard",
+}
+
+// NewFilesystemServer creates a filesystem server for allowedDirs. Without
+// options it registers every core tool with the default limits.
+func NewFilesystemServer(allowedDirs []string, opts ...Option) (*server.MCPServer, error) {
+	return newServer(allowedDirs, newOptions(opts))
+}
 
-	h, err := handler.NewFilesystemHandler(allowedDirs)
+// NewFilesystemServerWithConfig creates a filesystem server with the optional
+// features described by cfg enabled.
+func NewFilesystemServerWithConfig(allowedDirs []string, cfg Config) (*server.MCPServer, error) {
+	return NewFilesystemServer(allowedDirs, WithConfig(cfg))
+}
+
+// NewHandler creates a handler for allowedDirs with the limits and optional
+// features of opts applied. Register it on an existing MCPServer with Tools
+// and ResourceTemplates; to list files as resources and complete paths, add
+// h.HandleListResources as an AfterListResources hook and pass h to
+// server.WithResourceCompletionProvider.
+func NewHandler(allowedDirs []string, opts ...Option) (*handler.FilesystemHandler, error) {
+	return newHandler(allowedDirs, newOptions(opts))
+}
+
+func newHandler(allowedDirs []string, o *options) (*handler.FilesystemHandler, error) {
+	cfg := o.config
+	h, err := handler.NewFilesystemHandlerWithBackend(allowedDirs, o.backend)
 	if err != nil {
 		return nil, err
 	}
+	h.SetLimits(cfg.Limits)
+	h.SetReadOnly(cfg.ReadOnly)
+	if cfg.DirectoryAdmin {
+		h.EnableDirectoryAdmin()
+	}
 
-	s := server.NewMCPServer(
-		"secure-filesystem-server",
-		Version,
+	if cfg.TrashDir != "" {
+		if err := h.EnableTrash(cfg.TrashDir, cfg.TrashRetention); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.CheckpointDir != "" {
+		if err := h.EnableCheckpoints(cfg.CheckpointDir); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.JournalDir != "" {
+		if err := h.EnableJournal(cfg.JournalDir, cfg.JournalEntries); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.OverlayDir != "" {
+		if err := h.EnableOverlay(cfg.OverlayDir); err != nil {
+			return nil, err
+		}
+	}
+
+	// Tools applies the selection, so that it holds for embedders too
+	if err := o.checkToolNames(allTools(h)); err != nil {
+		return nil, err
+	}
+	h.SetToolSelection(o.enabled, o.disabled)
+	return h, nil
+}
+
+func newServer(allowedDirs []string, o *options) (*server.MCPServer, error) {
+	if err := validClientRootsMode(o.config.ClientRoots); err != nil {
+		return nil, err
+	}
+	if err := validConfirmMode(o.config.Confirm); err != nil {
+		return nil, err
+	}
+	h, err := newHandler(allowedDirs, o)
+	if err != nil {
+		return nil, err
+	}
+
+	tools := Tools(h)
+
+	// The files under the allowed directories are listed by a hook since
+	// they are not registered as static resources
+	hooks := &server.Hooks{}
+	hooks.AddAfterListResources(h.HandleListResources)
+	serverOpts := []server.ServerOption{
 		server.WithResourceCapabilities(true, true),
-	)
+		server.WithHooks(hooks),
+		server.WithCompletions(),
+		server.WithResourceCompletionProvider(h),
+		server.WithPromptCompletionProvider(h),
+	}
+	if o.policy != nil || o.logger != nil {
+		serverOpts = append(serverOpts, server.WithToolHandlerMiddleware(o.middleware))
+	}
+	// Confirmation runs after the policy so that rejected calls are not asked about
+	if mode := o.config.Confirm; mode != "" && mode != ConfirmOff {
+		serverOpts = append(serverOpts,
+			server.WithElicitation(),
+			server.WithToolHandlerMiddleware(confirmMiddleware(h, mode)),
+		)
+	}
+	s := server.NewMCPServer(o.name, Version, serverOpts...)
+
+	s.AddResourceTemplates(ResourceTemplates(h)...)
+	s.AddPrompts(Prompts(h)...)
+	s.AddTools(tools...)
+
+	if mode := o.config.ClientRoots; mode != "" && mode != ClientRootsOff {
+		roots := &clientRoots{server: s, handler: h, dirs: allowedDirs, mode: mode, options: o}
+		roots.register()
+	}
+	return s, nil
+}
+
+// ResourceTemplates returns the resource templates served by h. Files are
+// addressed as file:///{+path}; directories return a listing.
+func ResourceTemplates(h *handler.FilesystemHandler) []server.ServerResourceTemplate {
+	return []server.ServerResourceTemplate{
+		{
+			Template: mcp.NewResourceTemplate(
+				"file:///{+path}",
+				"File System",
+				mcp.WithTemplateDescription("Files and directories in the allowed directories"),
+			),
+			Handler: h.HandleReadResource,
+		},
+	}
+}
+
+// Prompts returns the prompts of h for common workflows. review_changes is
+// only included when checkpoints are enabled.
+func Prompts(h *handler.FilesystemHandler) []server.ServerPrompt {
+	prompts := []server.ServerPrompt{
+		{
+			Prompt: mcp.NewPrompt(
+				"summarize_directory",
+				mcp.WithPromptDescription("Summarize what a directory contains and how it is organized, "+
+					"based on its tree and files like README or go.mod."),
+				mcp.WithArgument("path",
+					mcp.ArgumentDescription("Path of the directory to summarize"),
+					mcp.RequiredArgument(),
+				),
+				mcp.WithArgument("depth",
+					mcp.ArgumentDescription("Maximum depth of the tree (default: 2)"),
+				),
+			),
+			Handler: h.HandleSummarizeDirectoryPrompt,
+		},
+		{
+			Prompt: mcp.NewPrompt(
+				"find_usages",
+				mcp.WithPromptDescription("Find and explain the usages of a symbol or text in a directory, "+
+					"with the matching lines and files."),
+				mcp.WithArgument("symbol",
+					mcp.ArgumentDescription("Symbol or text to look for"),
+					mcp.RequiredArgument(),
+				),
+				mcp.WithArgument("path",
+					mcp.ArgumentDescription("Path of the directory to search"),
+					mcp.RequiredArgument(),
+				),
+			),
+			Handler: h.HandleFindUsagesPrompt,
+		},
+	}
 
-	// Register resource handlers
-	s.AddResource(mcp.NewResource(
-		"file://",
-		"File System",
-		mcp.WithResourceDescription("Access to files and directories on the local file system"),
-	), h.HandleReadResource)
+	if h.CheckpointsEnabled() {
+		prompts = append(prompts, server.ServerPrompt{
+			Prompt: mcp.NewPrompt(
+				"review_changes",
+				mcp.WithPromptDescription("Review the changes made in a directory since a checkpoint, "+
+					"with their diff and the changed files."),
+				mcp.WithArgument("checkpoint",
+					mcp.ArgumentDescription("Name of the checkpoint to compare with"),
+					mcp.RequiredArgument(),
+				),
+			),
+			Handler: h.HandleReviewChangesPrompt,
+		})
+	}
+	return prompts
+}
 
+// readOnlyTool annotates a tool that does not change anything. All tools
+// work on the local file system only, so none of them has an open world.
+func readOnlyTool() mcp.ToolOption {
+	return mcp.WithToolAnnotation(mcp.ToolAnnotation{
+		ReadOnlyHint:    mcp.ToBoolPtr(true),
+		DestructiveHint: mcp.ToBoolPtr(false),
+		IdempotentHint:  mcp.ToBoolPtr(true),
+		OpenWorldHint:   mcp.ToBoolPtr(false),
+	})
+}
+
+// additiveTool annotates a tool that makes changes without overwriting or
+// removing anything
+func additiveTool(idempotent bool) mcp.ToolOption {
+	return mcp.WithToolAnnotation(mcp.ToolAnnotation{
+		ReadOnlyHint:    mcp.ToBoolPtr(false),
+		DestructiveHint: mcp.ToBoolPtr(false),
+		IdempotentHint:  mcp.ToBoolPtr(idempotent),
+		OpenWorldHint:   mcp.ToBoolPtr(false),
+	})
+}
+
+// destructiveTool annotates a tool that may overwrite or remove files
+func destructiveTool(idempotent bool) mcp.ToolOption {
+	return mcp.WithToolAnnotation(mcp.ToolAnnotation{
+		ReadOnlyHint:    mcp.ToBoolPtr(false),
+		DestructiveHint: mcp.ToBoolPtr(true),
+		IdempotentHint:  mcp.ToBoolPtr(idempotent),
+		OpenWorldHint:   mcp.ToBoolPtr(false),
+	})
+}
+
+// toolSet collects tool definitions in registration order
+type toolSet []server.ServerTool
+
+func (ts *toolSet) add(tool mcp.Tool, handler server.ToolHandlerFunc) {
+	*ts = append(*ts, server.ServerTool{Tool: tool, Handler: handler})
+}
+
+// Tools returns the tools of h, including those of the optional features
+// enabled on it, that its read-only mode and tool selection allow. With
+// names, only the named tools are returned so that a subset can be
+// registered on another MCPServer.
+func Tools(h *handler.FilesystemHandler, names ...string) []server.ServerTool {
+	tools := selectTools(h, allTools(h))
+	if len(names) == 0 {
+		return tools
+	}
+	var selected []server.ServerTool
+	for _, tool := range tools {
+		if slices.Contains(names, tool.Tool.Name) {
+			selected = append(selected, tool)
+		}
+	}
+	return selected
+}
+
+// allTools returns every tool of h, whether selected or not
+func allTools(h *handler.FilesystemHandler) []server.ServerTool {
+	var tools toolSet
 	// Register tool handlers
-	s.AddTool(mcp.NewTool(
+	tools.add(mcp.NewTool(
 		"read_file",
-		mcp.WithDescription("Read the complete contents of a file from the file system."),
+		readOnlyTool(),
+		mcp.WithDescription("Read the complete contents of a file from the file system. Text in UTF-16, Latin-1 or Windows-1252 is transcoded to UTF-8 and its encoding reported. Files inside zip, jar and tar archives can be read with paths like bundle.zip!/dir/file.txt."),
 		mcp.WithString("path",
 			mcp.Description("Path to the file to read"),
 			mcp.Required(),
 		),
 	), h.HandleReadFile)
 
-	s.AddTool(mcp.NewTool(
+	tools.add(mcp.NewTool(
 		"write_file",
+		destructiveTool(true),
 		mcp.WithDescription("Create a new file or overwrite an existing file with new content."),
 		mcp.WithString("path",
 			mcp.Description("Path where to write the file"),
@@ -49,19 +349,35 @@ func NewFilesystemServer(allowedDirs []string) (*server.MCPServer, error) {
 			mcp.Description("Content to write to the file"),
 			mcp.Required(),
 		),
+		mcp.WithString("encoding",
+			mcp.Description("Encoding to write the file in (default: the encoding of the existing file, utf-8 for new files)"),
+			mcp.Enum(handler.EncodingUTF8, handler.EncodingUTF16LE, handler.EncodingUTF16BE, handler.EncodingLatin1, handler.EncodingWindows1252),
+		),
+		mcp.WithBoolean("bom",
+			mcp.Description("Whether to start the file with a byte order mark (default: as the file does)"),
+		),
+		mcp.WithString("line_endings",
+			mcp.Description("Convert all line endings to lf or crlf (default: keep those of the file)"),
+			mcp.Enum(handler.LineEndingLF, handler.LineEndingCRLF),
+		),
+		mcp.WithBoolean("final_newline",
+			mcp.Description("Whether the file ends with a line break (default: as the content does)"),
+		),
 	), h.HandleWriteFile)
 
-	s.AddTool(mcp.NewTool(
+	tools.add(mcp.NewTool(
 		"list_directory",
-		mcp.WithDescription("Get a detailed listing of all files and directories in a specified path."),
+		readOnlyTool(),
+		mcp.WithDescription("Get a detailed listing of all files and directories in a specified path. Archives can be listed like directories with paths like bundle.zip!/ or bundle.zip!/dir."),
 		mcp.WithString("path",
 			mcp.Description("Path of the directory to list"),
 			mcp.Required(),
 		),
 	), h.HandleListDirectory)
 
-	s.AddTool(mcp.NewTool(
+	tools.add(mcp.NewTool(
 		"create_directory",
+		additiveTool(true),
 		mcp.WithDescription("Create a new directory or ensure a directory exists."),
 		mcp.WithString("path",
 			mcp.Description("Path of the directory to create"),
@@ -69,8 +385,9 @@ func NewFilesystemServer(allowedDirs []string) (*server.MCPServer, error) {
 		),
 	), h.HandleCreateDirectory)
 
-	s.AddTool(mcp.NewTool(
+	tools.add(mcp.NewTool(
 		"copy_file",
+		destructiveTool(true),
 		mcp.WithDescription("Copy files and directories."),
 		mcp.WithString("source",
 			mcp.Description("Source path of the file or directory"),
@@ -82,8 +399,9 @@ func NewFilesystemServer(allowedDirs []string) (*server.MCPServer, error) {
 		),
 	), h.HandleCopyFile)
 
-	s.AddTool(mcp.NewTool(
+	tools.add(mcp.NewTool(
 		"move_file",
+		destructiveTool(false),
 		mcp.WithDescription("Move or rename files and directories."),
 		mcp.WithString("source",
 			mcp.Description("Source path of the file or directory"),
@@ -95,8 +413,9 @@ func NewFilesystemServer(allowedDirs []string) (*server.MCPServer, error) {
 		),
 	), h.HandleMoveFile)
 
-	s.AddTool(mcp.NewTool(
+	tools.add(mcp.NewTool(
 		"search_files",
+		readOnlyTool(),
 		mcp.WithDescription("Recursively search for files and directories matching a pattern."),
 		mcp.WithString("path",
 			mcp.Description("Starting path for the search"),
@@ -108,8 +427,9 @@ func NewFilesystemServer(allowedDirs []string) (*server.MCPServer, error) {
 		),
 	), h.HandleSearchFiles)
 
-	s.AddTool(mcp.NewTool(
+	tools.add(mcp.NewTool(
 		"get_file_info",
+		readOnlyTool(),
 		mcp.WithDescription("Retrieve detailed metadata about a file or directory."),
 		mcp.WithString("path",
 			mcp.Description("Path to the file or directory"),
@@ -117,13 +437,15 @@ func NewFilesystemServer(allowedDirs []string) (*server.MCPServer, error) {
 		),
 	), h.HandleGetFileInfo)
 
-	s.AddTool(mcp.NewTool(
+	tools.add(mcp.NewTool(
 		"list_allowed_directories",
-		mcp.WithDescription("Returns the list of directories that this server is allowed to access."),
+		readOnlyTool(),
+		mcp.WithDescription("Returns the list of directories that this server is allowed to access, with the access level and origin of each."),
 	), h.HandleListAllowedDirectories)
 
-	s.AddTool(mcp.NewTool(
+	tools.add(mcp.NewTool(
 		"read_multiple_files",
+		readOnlyTool(),
 		mcp.WithDescription("Read the contents of multiple files in a single operation."),
 		mcp.WithArray("paths",
 			mcp.Description("List of file paths to read"),
@@ -132,9 +454,10 @@ func NewFilesystemServer(allowedDirs []string) (*server.MCPServer, error) {
 		),
 	), h.HandleReadMultipleFiles)
 
-	s.AddTool(mcp.NewTool(
+	tools.add(mcp.NewTool(
 		"tree",
-		mcp.WithDescription("Returns a hierarchical JSON representation of a directory structure."),
+		readOnlyTool(),
+		mcp.WithDescription("Returns a hierarchical JSON representation of a directory structure. Archives can be traversed with paths like bundle.zip!/."),
 		mcp.WithString("path",
 			mcp.Description("Path of the directory to traverse"),
 			mcp.Required(),
@@ -147,8 +470,9 @@ func NewFilesystemServer(allowedDirs []string) (*server.MCPServer, error) {
 		),
 	), h.HandleTree)
 
-	s.AddTool(mcp.NewTool(
+	tools.add(mcp.NewTool(
 		"delete_file",
+		destructiveTool(true),
 		mcp.WithDescription("Delete a file or directory from the file system."),
 		mcp.WithString("path",
 			mcp.Description("Path to the file or directory to delete"),
@@ -159,8 +483,9 @@ func NewFilesystemServer(allowedDirs []string) (*server.MCPServer, error) {
 		),
 	), h.HandleDeleteFile)
 
-	s.AddTool(mcp.NewTool(
+	tools.add(mcp.NewTool(
 		"modify_file",
+		destructiveTool(false),
 		mcp.WithDescription("Update file by finding and replacing text. Provides a simple pattern matching interface without needing exact character positions."),
 		mcp.WithString("path",
 			mcp.Description("Path to the file to modify"),
@@ -180,11 +505,56 @@ func NewFilesystemServer(allowedDirs []string) (*server.MCPServer, error) {
 		mcp.WithBoolean("regex",
 			mcp.Description("Treat the find pattern as a regular expression (default: false)"),
 		),
+		mcp.WithString("encoding",
+			mcp.Description("Encoding to write the file in (default: the encoding of the file)"),
+			mcp.Enum(handler.EncodingUTF8, handler.EncodingUTF16LE, handler.EncodingUTF16BE, handler.EncodingLatin1, handler.EncodingWindows1252),
+		),
+		mcp.WithBoolean("bom",
+			mcp.Description("Whether to start the file with a byte order mark (default: as the file does)"),
+		),
+		mcp.WithString("line_endings",
+			mcp.Description("Convert all line endings to lf or crlf (default: keep those of the file)"),
+			mcp.Enum(handler.LineEndingLF, handler.LineEndingCRLF),
+		),
+		mcp.WithBoolean("final_newline",
+			mcp.Description("Whether the file ends with a line break (default: as the file does)"),
+		),
 	), h.HandleModifyFile)
 
-	s.AddTool(mcp.NewTool(
+	tools.add(mcp.NewTool(
+		"edit_lines",
+		destructiveTool(false),
+		mcp.WithDescription("Edit a file by line numbers, as reported by search_within_files or a range read: insert text after a line, replace a range of lines or delete it. The file keeps its encoding and line endings. Set expected to the current text of the target lines to refuse the edit if the file has changed."),
+		mcp.WithString("path",
+			mcp.Description("Path to the file to edit"),
+			mcp.Required(),
+		),
+		mcp.WithString("operation",
+			mcp.Description("insert content after line, replace lines start to end with content, or delete lines start to end"),
+			mcp.Enum(handler.EditLinesInsert, handler.EditLinesReplace, handler.EditLinesDelete),
+			mcp.Required(),
+		),
+		mcp.WithNumber("line",
+			mcp.Description("Line after which to insert, 0 for the start of the file (insert)"),
+		),
+		mcp.WithNumber("start",
+			mcp.Description("First line to replace or delete, counting from 1 (replace, delete)"),
+		),
+		mcp.WithNumber("end",
+			mcp.Description("Last line to replace or delete, inclusive (default: start)"),
+		),
+		mcp.WithString("content",
+			mcp.Description("Lines to insert or to replace the range with (insert, replace)"),
+		),
+		mcp.WithString("expected",
+			mcp.Description("Current text of the lines start to end, or of line for insert, which must match for the edit to be made"),
+		),
+	), h.HandleEditLines)
+
+	tools.add(mcp.NewTool(
 		"search_within_files",
-		mcp.WithDescription("Search for text within file contents. Unlike search_files which only searches file names, this tool scans the actual contents of text files for matching substrings. Binary files are automatically excluded from the search. Reports file paths and line numbers where matches are found."),
+		readOnlyTool(),
+		mcp.WithDescription("Search for text within file contents. Unlike search_files which only searches file names, this tool scans the actual contents of text files for matching substrings. Binary files are automatically excluded from the search. Reports file paths and line numbers where matches are found. Archives can be searched with paths like bundle.zip!/ or bundle.zip!/dir."),
 		mcp.WithString("path",
 			mcp.Description("Starting path for the search (must be a directory)"),
 			mcp.Required(),
@@ -197,9 +567,264 @@ func NewFilesystemServer(allowedDirs []string) (*server.MCPServer, error) {
 			mcp.Description("Maximum directory depth to search (default: unlimited)"),
 		),
 		mcp.WithNumber("max_results",
-			mcp.Description("Maximum number of results to return (default: 1000)"),
+			mcp.Description(fmt.Sprintf("Maximum number of results to return (default: %d)", h.Limits().MaxSearchResults)),
 		),
 	), h.HandleSearchWithinFiles)
 
-	return s, nil
+	tools.add(mcp.NewTool(
+		"batch",
+		destructiveTool(false),
+		mcp.WithDescription("Apply an ordered list of write, modify, move, copy, delete and mkdir operations as a single transaction. All paths are validated before anything runs, and if any operation fails every completed operation is rolled back."),
+		mcp.WithArray("operations",
+			mcp.Description("Operations to run in order. Each takes the same arguments as the corresponding tool: write (path, content), modify (path, find, replace, all_occurrences, regex), move and copy (source, destination), delete (path, recursive), mkdir (path)."),
+			mcp.Required(),
+			mcp.Items(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"op": map[string]any{
+						"type": "string",
+						"enum": []string{"write", "modify", "move", "copy", "delete", "mkdir"},
+					},
+					"path":            map[string]any{"type": "string"},
+					"content":         map[string]any{"type": "string"},
+					"find":            map[string]any{"type": "string"},
+					"replace":         map[string]any{"type": "string"},
+					"all_occurrences": map[string]any{"type": "boolean"},
+					"regex":           map[string]any{"type": "boolean"},
+					"source":          map[string]any{"type": "string"},
+					"destination":     map[string]any{"type": "string"},
+					"recursive":       map[string]any{"type": "boolean"},
+				},
+				"required": []string{"op"},
+			}),
+		),
+	), h.HandleBatch)
+
+	tools.add(mcp.NewTool(
+		"create_archive",
+		additiveTool(false),
+		mcp.WithDescription("Pack files and directories into a new zip, tar, tar.gz or tar.zst archive, preserving file modes and modification times. Symbolic links are stored as links. Each path is packed under its base name."),
+		mcp.WithArray("paths",
+			mcp.Description("Files and directories to pack"),
+			mcp.Required(),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithString("destination",
+			mcp.Description("Path of the archive to create; it must not exist yet"),
+			mcp.Required(),
+		),
+		mcp.WithString("format",
+			mcp.Description("Archive format (default: implied by the destination's extension)"),
+			mcp.Enum("zip", "tar", "tar.gz", "tar.zst"),
+		),
+		mcp.WithArray("include",
+			mcp.Description("Only pack files matching one of these globs. Patterns without a slash match file names anywhere, patterns with one match the path in the archive, e.g. build/**.js"),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithArray("exclude",
+			mcp.Description("Leave out files and directories matching one of these globs, e.g. *.log or node_modules"),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+	), h.HandleCreateArchive)
+
+	tools.add(mcp.NewTool(
+		"extract_archive",
+		additiveTool(false),
+		mcp.WithDescription("Safely unpack a zip, tar, tar.gz or tar.zst archive into a directory and list every extracted file. The whole archive is checked first: entries with absolute or escaping paths, symlinks pointing outside the destination, existing files and archives over the size and entry limits are refused without writing anything."),
+		mcp.WithString("path",
+			mcp.Description("Path of the archive to unpack"),
+			mcp.Required(),
+		),
+		mcp.WithString("destination",
+			mcp.Description("Directory to unpack into; it is created if missing"),
+			mcp.Required(),
+		),
+		mcp.WithString("format",
+			mcp.Description("Archive format (default: implied by the archive's extension)"),
+			mcp.Enum("zip", "tar", "tar.gz", "tar.zst"),
+		),
+	), h.HandleExtractArchive)
+
+	if h.TrashEnabled() {
+		addTrashTools(&tools, h)
+	}
+
+	if h.JournalEnabled() {
+		addUndoTools(&tools, h)
+	}
+
+	if h.CheckpointsEnabled() {
+		addCheckpointTools(&tools, h)
+	}
+
+	if h.OverlayEnabled() {
+		addOverlayTools(&tools, h)
+	}
+
+	if h.DirectoryAdminEnabled() {
+		addDirectoryAdminTools(&tools, h)
+	}
+	return tools
+}
+
+// addTrashTools adds the tools for inspecting and restoring soft-deleted items
+func addTrashTools(tools *toolSet, h *handler.FilesystemHandler) {
+	tools.add(mcp.NewTool(
+		"list_trash",
+		readOnlyTool(),
+		mcp.WithDescription("List items that were deleted with delete_file and can still be restored."),
+	), h.HandleListTrash)
+
+	tools.add(mcp.NewTool(
+		"restore_from_trash",
+		additiveTool(false),
+		mcp.WithDescription("Restore a deleted file or directory from the trash to its original location or a new destination."),
+		mcp.WithString("id",
+			mcp.Description("ID of the trash entry, as reported by delete_file or list_trash"),
+			mcp.Required(),
+		),
+		mcp.WithString("destination",
+			mcp.Description("Path to restore to (default: the original path)"),
+		),
+	), h.HandleRestoreFromTrash)
+
+	tools.add(mcp.NewTool(
+		"empty_trash",
+		destructiveTool(true),
+		mcp.WithDescription("Permanently delete items from the trash. This cannot be undone."),
+		mcp.WithString("id",
+			mcp.Description("ID of a single trash entry to delete (default: all entries)"),
+		),
+		mcp.WithNumber("older_than_hours",
+			mcp.Description("Only delete entries that were trashed more than this many hours ago"),
+		),
+	), h.HandleEmptyTrash)
+}
+
+// addUndoTools adds the tools for rolling back journaled operations
+func addUndoTools(tools *toolSet, h *handler.FilesystemHandler) {
+	tools.add(mcp.NewTool(
+		"undo_history",
+		readOnlyTool(),
+		mcp.WithDescription("List the operations recorded in the undo journal, newest first, with their checkpoint numbers."),
+	), h.HandleUndoHistory)
+
+	tools.add(mcp.NewTool(
+		"undo_last",
+		destructiveTool(false),
+		mcp.WithDescription("Undo the most recent write, modify, move, copy, create directory or delete operations."),
+		mcp.WithNumber("count",
+			mcp.Description("Number of operations to undo (default: 1)"),
+		),
+	), h.HandleUndoLast)
+
+	tools.add(mcp.NewTool(
+		"undo_to",
+		destructiveTool(false),
+		mcp.WithDescription("Undo every operation recorded after the given checkpoint, restoring the files to their state at that point."),
+		mcp.WithNumber("checkpoint",
+			mcp.Description("Checkpoint number from undo_history; operations with a higher number are undone (use 0 to undo everything)"),
+			mcp.Required(),
+		),
+	), h.HandleUndoTo)
+}
+
+// addCheckpointTools adds the tools for snapshotting and restoring directories
+func addCheckpointTools(tools *toolSet, h *handler.FilesystemHandler) {
+	tools.add(mcp.NewTool(
+		"checkpoint_create",
+		additiveTool(true),
+		mcp.WithDescription("Snapshot a directory under a name so that it can be compared with or restored later. Unchanged file contents are stored only once."),
+		mcp.WithString("name",
+			mcp.Description("Name of the checkpoint (letters, digits, '.', '_' and '-')"),
+			mcp.Required(),
+		),
+		mcp.WithString("path",
+			mcp.Description("Path of the directory to snapshot"),
+			mcp.Required(),
+		),
+	), h.HandleCheckpointCreate)
+
+	tools.add(mcp.NewTool(
+		"checkpoint_list",
+		readOnlyTool(),
+		mcp.WithDescription("List all checkpoints with the directory they snapshot."),
+	), h.HandleCheckpointList)
+
+	tools.add(mcp.NewTool(
+		"checkpoint_diff",
+		readOnlyTool(),
+		mcp.WithDescription("Show files that were added, modified or deleted in a directory since a checkpoint was taken."),
+		mcp.WithString("name",
+			mcp.Description("Name of the checkpoint"),
+			mcp.Required(),
+		),
+	), h.HandleCheckpointDiff)
+
+	tools.add(mcp.NewTool(
+		"checkpoint_restore",
+		destructiveTool(true),
+		mcp.WithDescription("Restore a directory to the state of a checkpoint. Files added since the checkpoint are deleted and changed or deleted files are written back."),
+		mcp.WithString("name",
+			mcp.Description("Name of the checkpoint"),
+			mcp.Required(),
+		),
+	), h.HandleCheckpointRestore)
+}
+
+// addOverlayTools adds the tools for reviewing and applying the changes kept
+// in the copy-on-write overlay
+func addOverlayTools(tools *toolSet, h *handler.FilesystemHandler) {
+	tools.add(mcp.NewTool(
+		"overlay_diff",
+		readOnlyTool(),
+		mcp.WithDescription("List the files and directories that were added, modified or deleted in the overlay and not yet committed to the allowed directories."),
+		mcp.WithString("path",
+			mcp.Description("Only list changes in this directory (default: all changes)"),
+		),
+	), h.HandleOverlayDiff)
+
+	tools.add(mcp.NewTool(
+		"overlay_export",
+		readOnlyTool(),
+		mcp.WithDescription("Export the changes in the overlay as a unified diff that applies with 'patch -p1' from the file system root."),
+		mcp.WithString("path",
+			mcp.Description("Only export changes in this directory (default: all changes)"),
+		),
+	), h.HandleOverlayExport)
+
+	tools.add(mcp.NewTool(
+		"overlay_commit",
+		destructiveTool(true),
+		mcp.WithDescription("Apply all changes in the overlay to the allowed directories and empty the overlay."),
+	), h.HandleOverlayCommit)
+
+	tools.add(mcp.NewTool(
+		"overlay_discard",
+		destructiveTool(true),
+		mcp.WithDescription("Throw away all changes in the overlay, leaving the allowed directories as they are. This cannot be undone."),
+	), h.HandleOverlayDiscard)
+}
+
+// addDirectoryAdminTools adds the tools for changing the allowed directories
+func addDirectoryAdminTools(tools *toolSet, h *handler.FilesystemHandler) {
+	tools.add(mcp.NewTool(
+		"add_allowed_directory",
+		additiveTool(true),
+		mcp.WithDescription("Give access to another directory. Directories holding the server's trash, journal, checkpoints or overlay cannot be added."),
+		mcp.WithString("path",
+			mcp.Description("Absolute path of the directory to allow"),
+			mcp.Required(),
+		),
+	), h.HandleAddAllowedDirectory)
+
+	tools.add(mcp.NewTool(
+		"remove_allowed_directory",
+		additiveTool(true),
+		mcp.WithDescription("Stop giving access to one of the allowed directories. Files in it are not changed."),
+		mcp.WithString("path",
+			mcp.Description("Path of the allowed directory to remove, as shown by list_allowed_directories"),
+			mcp.Required(),
+		),
+	), h.HandleRemoveAllowedDirectory)
 }
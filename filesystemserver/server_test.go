@@ -1,9 +1,14 @@
 package filesystemserver_test
 
 import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/mark3labs/mcp-filesystem-server/filesystemserver"
+	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -26,3 +31,129 @@ func TestReadMultipleFilesSchema(t *testing.T) {
 	_, ok = pathsMap["items"]
 	assert.True(t, ok)
 }
+
+func TestResources(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	path := filepath.Join(dir, "read me.txt")
+	require.NoError(t, os.WriteFile(path, []byte("hello"), 0644))
+
+	fsserver, err := filesystemserver.NewFilesystemServer([]string{dir})
+	require.NoError(t, err)
+	mcpClient := startTestClient(t, fsserver)
+	ctx := context.Background()
+
+	templates, err := mcpClient.ListResourceTemplates(ctx, mcp.ListResourceTemplatesRequest{})
+	require.NoError(t, err)
+	require.Len(t, templates.ResourceTemplates, 1)
+	assert.Equal(t, "file:///{+path}", templates.ResourceTemplates[0].URITemplate.Raw())
+
+	resources, err := mcpClient.ListResources(ctx, mcp.ListResourcesRequest{})
+	require.NoError(t, err)
+	require.Len(t, resources.Resources, 1)
+	assert.Equal(t, path, resources.Resources[0].Name)
+
+	request := mcp.ReadResourceRequest{}
+	request.Params.URI = resources.Resources[0].URI
+	contents, err := mcpClient.ReadResource(ctx, request)
+	require.NoError(t, err)
+	require.Len(t, contents.Contents, 1)
+	assert.Equal(t, "hello", contents.Contents[0].(mcp.TextResourceContents).Text)
+
+	complete := mcp.CompleteRequest{}
+	complete.Params.Ref = mcp.ResourceReference{Type: "ref/resource", URI: "file:///{+path}"}
+	complete.Params.Argument = mcp.CompleteArgument{Name: "path", Value: strings.TrimPrefix(filepath.ToSlash(dir), "/") + "/re"}
+	completion, err := mcpClient.Complete(ctx, complete)
+	require.NoError(t, err)
+	assert.Equal(t, []string{strings.TrimPrefix(filepath.ToSlash(path), "/")}, completion.Completion.Values)
+
+	// Tool arguments are completed through a prompt reference named after the tool
+	complete.Params.Ref = mcp.PromptReference{Type: "ref/prompt", Name: "read_file"}
+	complete.Params.Argument = mcp.CompleteArgument{Name: "path", Value: filepath.Join(dir, "re")}
+	completion, err = mcpClient.Complete(ctx, complete)
+	require.NoError(t, err)
+	assert.Equal(t, []string{path}, completion.Completion.Values)
+}
+
+func TestPrompts(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("# Project"), 0644))
+
+	promptNames := func(config filesystemserver.Config) []string {
+		fsserver, err := filesystemserver.NewFilesystemServer([]string{dir}, filesystemserver.WithConfig(config))
+		require.NoError(t, err)
+		prompts, err := startTestClient(t, fsserver).ListPrompts(context.Background(), mcp.ListPromptsRequest{})
+		require.NoError(t, err)
+		var names []string
+		for _, prompt := range prompts.Prompts {
+			names = append(names, prompt.Name)
+		}
+		return names
+	}
+	assert.ElementsMatch(t, []string{"summarize_directory", "find_usages"}, promptNames(filesystemserver.Config{}))
+	assert.ElementsMatch(t, []string{"summarize_directory", "find_usages", "review_changes"},
+		promptNames(filesystemserver.Config{CheckpointDir: t.TempDir()}))
+
+	fsserver, err := filesystemserver.NewFilesystemServer([]string{dir})
+	require.NoError(t, err)
+	request := mcp.GetPromptRequest{}
+	request.Params.Name = "summarize_directory"
+	request.Params.Arguments = map[string]string{"path": dir}
+	result, err := startTestClient(t, fsserver).GetPrompt(context.Background(), request)
+	require.NoError(t, err)
+	require.Len(t, result.Messages, 3)
+	readme := result.Messages[2].Content.(mcp.EmbeddedResource).Resource.(mcp.TextResourceContents)
+	assert.Equal(t, "# Project", readme.Text)
+}
+
+func TestToolAnnotations(t *testing.T) {
+	dir := t.TempDir()
+	fsserver, err := filesystemserver.NewFilesystemServer([]string{dir}, filesystemserver.WithConfig(filesystemserver.Config{
+		TrashDir:       filepath.Join(t.TempDir(), "trash"),
+		JournalDir:     filepath.Join(t.TempDir(), "journal"),
+		CheckpointDir:  filepath.Join(t.TempDir(), "checkpoints"),
+		DirectoryAdmin: true,
+	}))
+	require.NoError(t, err)
+	result, err := startTestClient(t, fsserver).ListTools(context.Background(), mcp.ListToolsRequest{})
+	require.NoError(t, err)
+
+	readOnly, err := filesystemserver.NewFilesystemServer([]string{dir}, filesystemserver.WithConfig(filesystemserver.Config{
+		TrashDir:      filepath.Join(t.TempDir(), "trash"),
+		JournalDir:    filepath.Join(t.TempDir(), "journal"),
+		CheckpointDir: filepath.Join(t.TempDir(), "checkpoints"),
+		ReadOnly:      true,
+	}))
+	require.NoError(t, err)
+	readOnlyTools := toolNames(t, startTestClient(t, readOnly))
+
+	for _, tool := range result.Tools {
+		annotations := tool.Annotations
+		require.NotNil(t, annotations.ReadOnlyHint, tool.Name)
+		require.NotNil(t, annotations.DestructiveHint, tool.Name)
+		require.NotNil(t, annotations.IdempotentHint, tool.Name)
+		require.NotNil(t, annotations.OpenWorldHint, tool.Name)
+		assert.False(t, *annotations.OpenWorldHint, tool.Name)
+		if *annotations.ReadOnlyHint {
+			assert.False(t, *annotations.DestructiveHint, tool.Name)
+			assert.Contains(t, readOnlyTools, tool.Name)
+		}
+	}
+
+	hints := func(name string) mcp.ToolAnnotation {
+		for _, tool := range result.Tools {
+			if tool.Name == name {
+				return tool.Annotations
+			}
+		}
+		require.Fail(t, "Tool not found", name)
+		return mcp.ToolAnnotation{}
+	}
+	assert.True(t, *hints("read_file").ReadOnlyHint)
+	assert.True(t, *hints("tree").ReadOnlyHint)
+	assert.True(t, *hints("delete_file").DestructiveHint)
+	assert.True(t, *hints("move_file").DestructiveHint)
+	assert.False(t, *hints("move_file").IdempotentHint)
+	assert.False(t, *hints("create_directory").DestructiveHint)
+}
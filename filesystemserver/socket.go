@@ -0,0 +1,122 @@
+package filesystemserver
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UnixAddrPrefix marks a listen address as the path of a Unix domain socket
+const UnixAddrPrefix = "unix:"
+
+// DefaultSocketMode lets the owner and group of the socket connect
+const DefaultSocketMode os.FileMode = 0660
+
+// listen opens the listener for the HTTP transports. Addresses starting with
+// UnixAddrPrefix create a Unix domain socket; anything else is a TCP address.
+func listen(addr string, opts ServeOptions) (net.Listener, error) {
+	path, ok := strings.CutPrefix(addr, UnixAddrPrefix)
+	if !ok {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+		}
+		return ln, nil
+	}
+
+	if path == "" {
+		return nil, errors.New("missing socket path after unix:")
+	}
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+
+	ln, err := listenUnix(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+
+	// The socket starts out private and is widened to the configured mode
+	mode := opts.SocketMode
+	if mode == 0 {
+		mode = DefaultSocketMode
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("failed to set socket mode: %w", err)
+	}
+
+	if opts.SocketOwner != "" {
+		uid, gid, err := lookupOwner(opts.SocketOwner)
+		if err == nil {
+			err = os.Chown(path, uid, gid)
+		}
+		if err != nil {
+			ln.Close()
+			return nil, fmt.Errorf("failed to set socket owner: %w", err)
+		}
+	}
+	return ln, nil
+}
+
+// removeStaleSocket removes a socket left behind by a server that is no
+// longer running. It refuses to replace other files or a live socket.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("socket %s is already in use", path)
+	}
+	return os.Remove(path)
+}
+
+// lookupOwner resolves an owner given as user[:group] to numeric IDs. Names
+// and numeric IDs are both accepted; -1 leaves the group unchanged.
+func lookupOwner(owner string) (int, int, error) {
+	userName, groupName, hasGroup := strings.Cut(owner, ":")
+
+	uid := -1
+	if userName != "" {
+		u, err := user.Lookup(userName)
+		if err != nil {
+			u, err = user.LookupId(userName)
+		}
+		if err != nil {
+			return 0, 0, fmt.Errorf("unknown user %q", userName)
+		}
+		if uid, err = strconv.Atoi(u.Uid); err != nil {
+			return 0, 0, fmt.Errorf("user %q has no numeric ID", userName)
+		}
+	}
+
+	gid := -1
+	if hasGroup && groupName != "" {
+		g, err := user.LookupGroup(groupName)
+		if err != nil {
+			g, err = user.LookupGroupId(groupName)
+		}
+		if err != nil {
+			return 0, 0, fmt.Errorf("unknown group %q", groupName)
+		}
+		if gid, err = strconv.Atoi(g.Gid); err != nil {
+			return 0, 0, fmt.Errorf("group %q has no numeric ID", groupName)
+		}
+	}
+	return uid, gid, nil
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package filesystemserver
+
+import "net"
+
+// listenUnix creates the socket at path. Without a umask the file
+// permissions of the socket are left to the operating system.
+func listenUnix(path string) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package filesystemserver
+
+import (
+	"net"
+	"syscall"
+)
+
+// listenUnix creates the socket at path accessible to its owner only, so
+// that no one else can connect before the configured mode is applied. The
+// umask is process-wide, so files created concurrently get it as well.
+func listenUnix(path string) (net.Listener, error) {
+	old := syscall.Umask(0177)
+	defer syscall.Umask(old)
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,172 @@
+package filesystemserver
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Transports supported by Serve
+const (
+	// TransportStdio serves a single client over standard input and output
+	TransportStdio = "stdio"
+	// TransportSSE serves clients over HTTP with server-sent events
+	TransportSSE = "sse"
+	// TransportHTTP serves clients over the streamable HTTP transport
+	TransportHTTP = "http"
+)
+
+// Default settings for the HTTP transports
+const (
+	DefaultListenAddr      = "localhost:8080"
+	DefaultHTTPBasePath    = "/mcp"
+	DefaultShutdownTimeout = 10 * time.Second
+)
+
+// ServeOptions selects and configures the transport used by Serve.
+type ServeOptions struct {
+	// Transport is one of TransportStdio (the default), TransportSSE or TransportHTTP.
+	Transport string
+	// Addr is the TCP address the HTTP transports listen on, or the path of
+	// a Unix domain socket prefixed with UnixAddrPrefix ("unix:/run/mcp.sock").
+	Addr string
+	// SocketMode is the file mode of a Unix domain socket (default 0660).
+	SocketMode os.FileMode
+	// SocketOwner changes the owner of a Unix domain socket, given as
+	// user[:group] names or numeric IDs.
+	SocketOwner string
+	// BasePath is the URL path the HTTP transports are served under.
+	// SSE serves BasePath/sse and BasePath/message; streamable HTTP serves
+	// BasePath itself (default /mcp).
+	BasePath string
+	// ShutdownTimeout bounds how long open sessions are given to finish
+	// once the context is cancelled.
+	ShutdownTimeout time.Duration
+	// TLS serves the HTTP transports over HTTPS. When ClientCAs is set,
+	// clients may authenticate with a certificate signed by one of them.
+	TLS *tls.Config
+	// Auth requires every HTTP request to carry a bearer token or a client
+	// certificate. Without it anyone who can reach the listener is served,
+	// unless TLS requires a client certificate.
+	Auth *Auth
+}
+
+// Serve runs s on the configured transport until ctx is cancelled, then shuts
+// down gracefully. A cancelled context is not reported as an error.
+func Serve(ctx context.Context, s *server.MCPServer, opts ServeOptions) error {
+	switch opts.Transport {
+	case "", TransportStdio:
+		err := server.NewStdioServer(s).Listen(ctx, os.Stdin, os.Stdout)
+		if errors.Is(err, context.Canceled) {
+			return nil
+		}
+		return err
+	case TransportSSE, TransportHTTP:
+		addr := opts.Addr
+		if addr == "" {
+			addr = DefaultListenAddr
+		}
+		ln, err := listen(addr, opts)
+		if err != nil {
+			return err
+		}
+		return ServeListener(ctx, s, ln, opts)
+	default:
+		return fmt.Errorf("unknown transport %q (expected %s, %s or %s)",
+			opts.Transport, TransportStdio, TransportSSE, TransportHTTP)
+	}
+}
+
+// ServeListener runs s on one of the HTTP transports using an existing
+// listener until ctx is cancelled. The listener is closed on return.
+func ServeListener(ctx context.Context, s *server.MCPServer, ln net.Listener, opts ServeOptions) error {
+	handler, shutdown, err := newTransportHandler(s, opts)
+	if err == nil && opts.Auth != nil {
+		handler, shutdown, err = opts.Auth.wrap(handler, shutdown, opts)
+	}
+	if err != nil {
+		ln.Close()
+		return err
+	}
+
+	if opts.TLS != nil {
+		cfg := opts.TLS.Clone()
+		if cfg.ClientCAs != nil && cfg.ClientAuth == tls.NoClientCert {
+			cfg.ClientAuth = tls.RequireAndVerifyClientCert
+			if opts.Auth != nil {
+				// Auth accepts bearer tokens in place of a certificate
+				cfg.ClientAuth = tls.VerifyClientCertIfGiven
+			}
+		}
+		ln = tls.NewListener(ln, cfg)
+	}
+
+	srv := &http.Server{Handler: handler}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	timeout := opts.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = DefaultShutdownTimeout
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// Close open sessions first so that the server can wait for in-flight requests
+	if err := shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to close sessions: %w", err)
+	}
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down: %w", err)
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// newTransportHandler returns the HTTP handler serving s on the configured
+// transport and a function that closes its open sessions
+func newTransportHandler(s *server.MCPServer, opts ServeOptions) (http.Handler, func(context.Context) error, error) {
+	mux := http.NewServeMux()
+	switch opts.Transport {
+	case TransportSSE:
+		// The SSE server only closes its sessions when it owns an http.Server,
+		// so give it one that is never started
+		sse := server.NewSSEServer(s,
+			server.WithStaticBasePath(strings.TrimSuffix(opts.BasePath, "/")),
+			server.WithHTTPServer(&http.Server{}),
+		)
+		mux.Handle(sse.CompleteSsePath(), sse)
+		mux.Handle(sse.CompleteMessagePath(), sse)
+		return mux, sse.Shutdown, nil
+	case TransportHTTP:
+		basePath := opts.BasePath
+		if basePath == "" {
+			basePath = DefaultHTTPBasePath
+		}
+		streamable := server.NewStreamableHTTPServer(s,
+			server.WithEndpointPath(basePath),
+		)
+		mux.Handle(basePath, streamable)
+		return mux, streamable.Shutdown, nil
+	default:
+		return nil, nil, fmt.Errorf("transport %q cannot be served over HTTP", opts.Transport)
+	}
+}
@@ -0,0 +1,174 @@
+package filesystemserver_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-filesystem-server/filesystemserver"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeHTTPTransports(t *testing.T) {
+	tests := []struct {
+		name      string
+		transport string
+		basePath  string
+		newClient func(baseURL string) (*client.Client, error)
+	}{
+		{
+			name:      "streamable http",
+			transport: filesystemserver.TransportHTTP,
+			newClient: func(baseURL string) (*client.Client, error) {
+				return client.NewStreamableHttpClient(baseURL + filesystemserver.DefaultHTTPBasePath)
+			},
+		},
+		{
+			name:      "sse with base path",
+			transport: filesystemserver.TransportSSE,
+			basePath:  "/fs",
+			newClient: func(baseURL string) (*client.Client, error) {
+				return client.NewSSEMCPClient(baseURL + "/fs/sse")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fss, err := filesystemserver.NewFilesystemServer([]string{t.TempDir()})
+			require.NoError(t, err)
+
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			done := make(chan error, 1)
+			go func() {
+				done <- filesystemserver.ServeListener(ctx, fss, ln, filesystemserver.ServeOptions{
+					Transport:       tt.transport,
+					BasePath:        tt.basePath,
+					ShutdownTimeout: time.Second,
+				})
+			}()
+
+			mcpClient, err := tt.newClient("http://" + ln.Addr().String())
+			require.NoError(t, err)
+			require.NoError(t, mcpClient.Start(context.Background()))
+
+			initRequest := mcp.InitializeRequest{}
+			initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+			initRequest.Params.ClientInfo = mcp.Implementation{
+				Name:    "test-client",
+				Version: "1.0.0",
+			}
+			_, err = mcpClient.Initialize(context.Background(), initRequest)
+			require.NoError(t, err)
+
+			tool := getTool(t, mcpClient, "read_file")
+			assert.NotNil(t, tool)
+			mcpClient.Close()
+
+			// Cancelling the context shuts the server down cleanly
+			cancel()
+			select {
+			case err := <-done:
+				assert.NoError(t, err)
+			case <-time.After(5 * time.Second):
+				t.Fatal("server did not shut down")
+			}
+		})
+	}
+}
+
+func TestServeUnknownTransport(t *testing.T) {
+	fss, err := filesystemserver.NewFilesystemServer([]string{t.TempDir()})
+	require.NoError(t, err)
+
+	err = filesystemserver.Serve(context.Background(), fss, filesystemserver.ServeOptions{Transport: "carrier-pigeon"})
+	assert.Error(t, err)
+}
+
+func TestServeUnixSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("socket file modes and owners are not supported on Windows")
+	}
+
+	// Socket paths are limited to about 100 bytes, so avoid the long t.TempDir
+	socketDir, err := os.MkdirTemp("", "mcpfs")
+	require.NoError(t, err)
+	defer os.RemoveAll(socketDir)
+	socketPath := filepath.Join(socketDir, "fs.sock")
+
+	// A socket left behind by a previous run is replaced
+	stale, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	fss, err := filesystemserver.NewFilesystemServer([]string{t.TempDir()})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- filesystemserver.Serve(ctx, fss, filesystemserver.ServeOptions{
+			Transport:       filesystemserver.TransportHTTP,
+			Addr:            filesystemserver.UnixAddrPrefix + socketPath,
+			SocketMode:      0600,
+			SocketOwner:     strconv.Itoa(os.Getuid()),
+			ShutdownTimeout: time.Second,
+		})
+	}()
+
+	require.Eventually(t, func() bool {
+		info, err := os.Stat(socketPath)
+		return err == nil && info.Mode()&os.ModeSocket != 0 && info.Mode().Perm() == 0600
+	}, 5*time.Second, 10*time.Millisecond)
+
+	httpClient := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socketPath)
+		},
+	}}
+	mcpClient, err := client.NewStreamableHttpClient("http://localhost"+filesystemserver.DefaultHTTPBasePath,
+		transport.WithHTTPBasicClient(httpClient))
+	require.NoError(t, err)
+
+	initRequest := mcp.InitializeRequest{}
+	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initRequest.Params.ClientInfo = mcp.Implementation{
+		Name:    "test-client",
+		Version: "1.0.0",
+	}
+	_, err = mcpClient.Initialize(context.Background(), initRequest)
+	require.NoError(t, err)
+	assert.NotNil(t, getTool(t, mcpClient, "read_file"))
+	mcpClient.Close()
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not shut down")
+	}
+
+	// The socket is removed on shutdown
+	_, err = os.Stat(socketPath)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -1,33 +1,232 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
 
 	"github.com/mark3labs/mcp-filesystem-server/filesystemserver"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
 
+// commands maps each subcommand to its implementation
+var commands = map[string]func(s *settings, stdout io.Writer) error{
+	"serve":        serve,
+	"check-config": checkConfig,
+	"list-tools":   listTools,
+}
+
 func main() {
-	// Parse command line arguments
-	if len(os.Args) < 2 {
-		fmt.Fprintf(
-			os.Stderr,
-			"Usage: %s <allowed-directory> [additional-directories...]\n",
-			os.Args[0],
-		)
+	err := run(os.Args[1:], os.Stdout, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+}
+
+// run executes the command line. Without a command, serve is assumed so that
+// directories can be passed directly.
+func run(args []string, stdout, stderr io.Writer) error {
+	command := "serve"
+	if len(args) > 0 {
+		if _, ok := commands[args[0]]; ok {
+			command = args[0]
+			args = args[1:]
+		}
+	}
+
+	s, dirs, err := parseSettings(command, args, stderr)
+	if err != nil {
+		return err
+	}
+	if s.version {
+		fmt.Fprintf(stdout, "mcp-filesystem-server %s\n", filesystemserver.Version)
+		return nil
+	}
+	s.AllowedDirectories = append(s.AllowedDirectories, dirs...)
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s.LogLevel)); err != nil {
+		return fmt.Errorf("invalid log level %q (expected debug, info, warn or error)", s.LogLevel)
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: level})))
+
+	return commands[command](s, stdout)
+}
+
+// build creates the server and transport options described by s
+func build(s *settings) (*server.MCPServer, filesystemserver.ServeOptions, error) {
+	opts := filesystemserver.ServeOptions{
+		Transport:       s.Transport,
+		Addr:            s.Listen,
+		BasePath:        s.BasePath,
+		SocketMode:      os.FileMode(s.SocketMode),
+		SocketOwner:     s.SocketOwner,
+		ShutdownTimeout: s.ShutdownTimeout.duration(),
+	}
+	// With replaced client roots the allowed directories are optional
+	if len(s.AllowedDirectories) == 0 && s.ClientRoots != filesystemserver.ClientRootsReplace {
+		return nil, opts, errors.New("no allowed directories given")
+	}
+	// The roots of one client would replace the directories of all others
+	if s.ClientRoots == filesystemserver.ClientRootsReplace && s.Transport != "" && s.Transport != filesystemserver.TransportStdio {
+		return nil, opts, errors.New("client-roots replace is only supported on the stdio transport")
+	}
 
-	// Create and start the server
-	fss, err := filesystemserver.NewFilesystemServer(os.Args[1:])
+	serverOpts := []filesystemserver.Option{
+		filesystemserver.WithConfig(s.config()),
+		filesystemserver.WithLogger(slog.Default()),
+	}
+	fss, err := filesystemserver.NewFilesystemServer(s.AllowedDirectories, serverOpts...)
 	if err != nil {
-		log.Fatalf("Failed to create server: %v", err)
+		return nil, opts, fmt.Errorf("failed to create server: %w", err)
+	}
+
+	// Set up authentication for the HTTP transports
+	var principals []filesystemserver.Principal
+	for i, token := range s.AuthTokens {
+		principals = append(principals, filesystemserver.Principal{
+			Name:  fmt.Sprintf("token-%d", i+1),
+			Token: token,
+		})
+	}
+	if s.AuthFile != "" {
+		loaded, err := filesystemserver.LoadPrincipals(s.AuthFile)
+		if err != nil {
+			return nil, opts, err
+		}
+		principals = append(principals, loaded...)
 	}
+	if len(principals) > 0 {
+		opts.Auth, err = filesystemserver.NewAuth(s.AllowedDirectories, principals, serverOpts...)
+		if err != nil {
+			return nil, opts, fmt.Errorf("invalid authentication settings: %w", err)
+		}
+	}
+	if s.TLSCert != "" || s.TLSKey != "" {
+		opts.TLS, err = filesystemserver.LoadTLSConfig(s.TLSCert, s.TLSKey, s.TLSClientCA)
+		if err != nil {
+			return nil, opts, fmt.Errorf("invalid TLS settings: %w", err)
+		}
+	} else if s.TLSClientCA != "" {
+		return nil, opts, errors.New("tls-client-ca requires tls-cert and tls-key")
+	}
+	return fss, opts, nil
+}
+
+// serve runs the server until SIGINT or SIGTERM
+func serve(s *settings, _ io.Writer) error {
+	fss, opts, err := build(s)
+	if err != nil {
+		return err
+	}
+	slog.Debug("Configured server",
+		"directories", s.AllowedDirectories,
+		"read_only", s.ReadOnly,
+		"limits", fmt.Sprintf("%+v", s.Limits))
+
+	// Shut down gracefully on SIGINT and SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// Serve requests
-	if err := server.ServeStdio(fss); err != nil {
-		log.Fatalf("Server error: %v", err)
+	if opts.Transport != filesystemserver.TransportStdio {
+		slog.Info("Serving", "transport", opts.Transport, "address", opts.Addr)
+		unixSocket := strings.HasPrefix(opts.Addr, filesystemserver.UnixAddrPrefix)
+		if opts.Auth == nil && s.TLSClientCA == "" && !unixSocket {
+			slog.Warn("Authentication is disabled; anyone who can reach the address can access the allowed directories",
+				"address", opts.Addr)
+		}
+	}
+
+	if err := filesystemserver.Serve(ctx, fss, opts); err != nil {
+		return fmt.Errorf("server error: %w", err)
+	}
+	return nil
+}
+
+// checkConfig validates the configuration without serving
+func checkConfig(s *settings, stdout io.Writer) error {
+	fss, opts, err := build(s)
+	if err != nil {
+		return err
+	}
+	tools, err := serverTools(fss)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "Configuration OK\n")
+	fmt.Fprintf(stdout, "Allowed directories: %s\n", strings.Join(s.AllowedDirectories, ", "))
+	fmt.Fprintf(stdout, "Read only: %t\n", s.ReadOnly)
+	fmt.Fprintf(stdout, "Tools: %d\n", len(tools))
+	switch opts.Transport {
+	case "", filesystemserver.TransportStdio:
+		fmt.Fprintf(stdout, "Transport: stdio\n")
+	default:
+		fmt.Fprintf(stdout, "Transport: %s on %s (authentication: %t, TLS: %t)\n",
+			opts.Transport, opts.Addr, opts.Auth != nil, opts.TLS != nil)
+	}
+	return nil
+}
+
+// listTools prints the tools registered for the configuration. The allowed
+// directories default to the working directory since no tool is called.
+func listTools(s *settings, stdout io.Writer) error {
+	if len(s.AllowedDirectories) == 0 {
+		s.AllowedDirectories = []string{"."}
+	}
+	fss, err := filesystemserver.NewFilesystemServerWithConfig(s.AllowedDirectories, s.config())
+	if err != nil {
+		return fmt.Errorf("failed to create server: %w", err)
+	}
+	tools, err := serverTools(fss)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	for _, tool := range tools {
+		description, _, _ := strings.Cut(tool.Description, ". ")
+		fmt.Fprintf(w, "%s\t%s\n", tool.Name, strings.TrimSuffix(description, "."))
+	}
+	return w.Flush()
+}
+
+// serverTools lists the tools of fss through an in-process client
+func serverTools(fss *server.MCPServer) ([]mcp.Tool, error) {
+	ctx := context.Background()
+	c, err := client.NewInProcessClient(fss)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	initRequest := mcp.InitializeRequest{}
+	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initRequest.Params.ClientInfo = mcp.Implementation{
+		Name:    "mcp-filesystem-server",
+		Version: filesystemserver.Version,
+	}
+	if _, err := c.Initialize(ctx, initRequest); err != nil {
+		return nil, fmt.Errorf("failed to initialize server: %w", err)
+	}
+
+	result, err := c.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tools: %w", err)
 	}
+	return result.Tools, nil
 }